@@ -12,5 +12,5 @@
 func Register(ctx context.Context, wrangler *wrangler.Context) error {
 	feature.Register(ctx, wrangler.Mgmt.Feature())
 	helm.RegisterReposForFollowers(ctx, wrangler.Core.Secret().Cache(), wrangler.Catalog.ClusterRepo())
-	return settings.Register(wrangler.Mgmt.Setting())
+	return settings.Register(wrangler.Mgmt.Setting(), settings.Options{OnLeader: wrangler.OnLeader})
 }
@@ -0,0 +1,48 @@
+package settings
+
+import (
+	"sort"
+
+	managementcontrollers "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SettingDrift describes a setting whose effective value differs from the baseline.
+type SettingDrift struct {
+	Name     string
+	Expected string
+	Actual   string
+	Source   string
+	Missing  bool
+}
+
+// AssertAgainstBaseline compares the effective value of every setting in the baseline with the value in k8s and
+// returns the settings that drifted, sorted by name. Settings configured by an env var are skipped if ignoreEnv is true.
+func AssertAgainstBaseline(client managementcontrollers.SettingClient, baseline map[string]string, ignoreEnv bool) ([]SettingDrift, error) {
+	var drifts []SettingDrift
+
+	for name, expected := range baseline {
+		obj, err := client.Get(name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			drifts = append(drifts, SettingDrift{Name: name, Expected: expected, Missing: true})
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		if ignoreEnv && obj.Source == "env" {
+			continue
+		}
+
+		if actual := EffectiveValue(obj); actual != expected {
+			drifts = append(drifts, SettingDrift{Name: name, Expected: expected, Actual: actual, Source: obj.Source})
+		}
+	}
+
+	sort.Slice(drifts, func(i, j int) bool {
+		return drifts[i].Name < drifts[j].Name
+	})
+
+	return drifts, nil
+}
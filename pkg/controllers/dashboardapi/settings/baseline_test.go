@@ -0,0 +1,58 @@
+package settings
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/wrangler/v2/pkg/generic/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestAssertAgainstBaseline(t *testing.T) {
+	store := map[string]v3.Setting{
+		"matching":         {ObjectMeta: metav1.ObjectMeta{Name: "matching"}, Value: "a"},
+		"matching-default": {ObjectMeta: metav1.ObjectMeta{Name: "matching-default"}, Default: "b"},
+		"drifting":         {ObjectMeta: metav1.ObjectMeta{Name: "drifting"}, Value: "changed", Default: "c"},
+		"env":              {ObjectMeta: metav1.ObjectMeta{Name: "env"}, Value: "from-env", Default: "d", Source: "env"},
+	}
+	client := newStoreBackedClient(t, store)
+
+	baseline := map[string]string{
+		"matching":         "a",
+		"matching-default": "b",
+		"drifting":         "c",
+		"env":              "d",
+		"missing":          "e",
+	}
+
+	drifts, err := AssertAgainstBaseline(client, baseline, false)
+	require.NoError(t, err)
+	assert.Equal(t, []SettingDrift{
+		{Name: "drifting", Expected: "c", Actual: "changed"},
+		{Name: "env", Expected: "d", Actual: "from-env", Source: "env"},
+		{Name: "missing", Expected: "e", Missing: true},
+	}, drifts)
+
+	drifts, err = AssertAgainstBaseline(client, baseline, true)
+	require.NoError(t, err)
+	assert.Equal(t, []SettingDrift{
+		{Name: "drifting", Expected: "c", Actual: "changed"},
+		{Name: "missing", Expected: "e", Missing: true},
+	}, drifts)
+
+	drifts, err = AssertAgainstBaseline(client, map[string]string{"matching": "a"}, false)
+	require.NoError(t, err)
+	assert.Empty(t, drifts)
+}
+
+func TestAssertAgainstBaselineGetError(t *testing.T) {
+	client := fake.NewMockNonNamespacedControllerInterface[*v3.Setting, *v3.SettingList](gomock.NewController(t))
+	client.EXPECT().Get(gomock.Any(), gomock.Any()).Return(nil, apierrors.NewServiceUnavailable("some error"))
+
+	_, err := AssertAgainstBaseline(client, map[string]string{"a": "b"}, false)
+	assert.Error(t, err)
+}
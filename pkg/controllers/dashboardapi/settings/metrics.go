@@ -0,0 +1,84 @@
+package settings
+
+import (
+	"errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// settingsMetrics counts the outcome of reconciling settings in SetAll. A nil *settingsMetrics records nothing.
+type settingsMetrics struct {
+	reconciled      prometheus.Counter
+	reconcileErrors prometheus.Counter
+	unknown         prometheus.Counter
+}
+
+// newSettingsMetrics creates the settings counters and registers them with registerer. Counters that are already
+// registered, e.g. because the provider was registered before, are reused.
+func newSettingsMetrics(registerer prometheus.Registerer) (*settingsMetrics, error) {
+	reconciled, err := registerCounter(registerer, prometheus.CounterOpts{
+		Subsystem: "settings",
+		Name:      "reconciled_total",
+		Help:      "Number of settings reconciled by the settings provider",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	reconcileErrors, err := registerCounter(registerer, prometheus.CounterOpts{
+		Subsystem: "settings",
+		Name:      "reconcile_errors_total",
+		Help:      "Number of errors reconciling settings by the settings provider",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	unknown, err := registerCounter(registerer, prometheus.CounterOpts{
+		Subsystem: "settings",
+		Name:      "unknown_total",
+		Help:      "Number of settings marked as unknown by the settings provider",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &settingsMetrics{
+		reconciled:      reconciled,
+		reconcileErrors: reconcileErrors,
+		unknown:         unknown,
+	}, nil
+}
+
+func registerCounter(registerer prometheus.Registerer, opts prometheus.CounterOpts) (prometheus.Counter, error) {
+	counter := prometheus.NewCounter(opts)
+	if err := registerer.Register(counter); err != nil {
+		var alreadyRegistered prometheus.AlreadyRegisteredError
+		if errors.As(err, &alreadyRegistered) {
+			if existing, ok := alreadyRegistered.ExistingCollector.(prometheus.Counter); ok {
+				return existing, nil
+			}
+		}
+		return nil, err
+	}
+
+	return counter, nil
+}
+
+func (m *settingsMetrics) incReconciled() {
+	if m != nil {
+		m.reconciled.Inc()
+	}
+}
+
+func (m *settingsMetrics) incReconcileErrors() {
+	if m != nil {
+		m.reconcileErrors.Inc()
+	}
+}
+
+func (m *settingsMetrics) incUnknown() {
+	if m != nil {
+		m.unknown.Inc()
+	}
+}
@@ -0,0 +1,88 @@
+package settings
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/rancher/wrangler/v2/pkg/generic/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSetAllMetrics(t *testing.T) {
+	store := map[string]v3.Setting{
+		"known":   {ObjectMeta: metav1.ObjectMeta{Name: "known", ResourceVersion: "1"}, Default: "old-default"},
+		"unknown": {ObjectMeta: metav1.ObjectMeta{Name: "unknown", ResourceVersion: "1"}, Value: "unknown"},
+	}
+	settingMap := map[string]settings.Setting{
+		"known":   settings.NewSetting("known", "new-default"),
+		"missing": settings.NewSetting("missing", "default"),
+		"large":   settings.NewSetting("large", "default"),
+	}
+	t.Setenv(settings.GetEnvKey("large"), "too-large")
+
+	registry := prometheus.NewRegistry()
+	metrics, err := newSettingsMetrics(registry)
+	require.NoError(t, err)
+
+	provider := settingsProvider{
+		settings: newStoreBackedClient(t, store),
+		metrics:  metrics,
+		maxSizes: map[string]int{"large": 4},
+	}
+
+	err = provider.SetAll(settingMap)
+	assert.ErrorContains(t, err, "large")
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(metrics.reconciled))
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.reconcileErrors))
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.unknown))
+
+	// Settings already marked as unknown are not counted again.
+	err = provider.SetAll(settingMap)
+	assert.ErrorContains(t, err, "large")
+
+	assert.Equal(t, float64(4), testutil.ToFloat64(metrics.reconciled))
+	assert.Equal(t, float64(2), testutil.ToFloat64(metrics.reconcileErrors))
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.unknown))
+
+	count, err := testutil.GatherAndCount(registry, "settings_reconciled_total", "settings_reconcile_errors_total", "settings_unknown_total")
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+}
+
+func TestSetAllMetricsListError(t *testing.T) {
+	client := fake.NewMockNonNamespacedControllerInterface[*v3.Setting, *v3.SettingList](gomock.NewController(t))
+	client.EXPECT().List(gomock.Any()).Return(nil, fmt.Errorf("some error"))
+
+	metrics, err := newSettingsMetrics(prometheus.NewRegistry())
+	require.NoError(t, err)
+
+	provider := settingsProvider{
+		settings: client,
+		metrics:  metrics,
+	}
+
+	err = provider.SetAll(map[string]settings.Setting{"a": settings.NewSetting("a", "default")})
+	assert.Error(t, err)
+	assert.Equal(t, float64(0), testutil.ToFloat64(metrics.reconciled))
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.reconcileErrors))
+}
+
+func TestNewSettingsMetricsReusesRegisteredCounters(t *testing.T) {
+	registry := prometheus.NewRegistry()
+
+	first, err := newSettingsMetrics(registry)
+	require.NoError(t, err)
+	second, err := newSettingsMetrics(registry)
+	require.NoError(t, err)
+
+	second.incReconciled()
+	assert.Equal(t, float64(1), testutil.ToFloat64(first.reconciled))
+}
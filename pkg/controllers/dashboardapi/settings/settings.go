@@ -4,35 +4,122 @@
 package settings
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
-
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
 	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
 	managementcontrollers "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
 	"github.com/rancher/rancher/pkg/settings"
 	"github.com/sirupsen/logrus"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/util/retry"
 )
 
-func Register(settingController managementcontrollers.SettingController) error {
+// Options configures the settings provider registered by Register. The zero value keeps the default behavior.
+type Options struct {
+	// UnknownAfterCycles is how many consecutive calls to SetAll a setting must be missing from the known settings
+	// before it is marked as unknown, so that settings registered by a newer Rancher on another replica aren't labeled
+	// transiently during a rolling upgrade. Values of one or less mark it as unknown the first time it is missing.
+	UnknownAfterCycles int
+
+	// OnLeader registers a function to be called once this replica becomes the leader, e.g. the OnLeader method of
+	// the wrangler context. If set, SetAll only updates settings on the leader, and the settings are reconciled again
+	// once this replica becomes the leader. Nil treats every replica as the leader.
+	OnLeader func(func(ctx context.Context) error)
+
+	// ResetValueOnEnvRemoval resets a setting to its default when the env var that configured it is removed, instead
+	// of keeping the value that was set by the env var. The replaced value is kept in an annotation.
+	ResetValueOnEnvRemoval bool
+}
+
+func Register(settingController managementcontrollers.SettingController, opts Options) error {
+	metrics, err := newSettingsMetrics(prometheus.DefaultRegisterer)
+	if err != nil {
+		return fmt.Errorf("error registering settings metrics: %w", err)
+	}
+
+	return settings.SetProvider(newSettingsProvider(settingController, metrics, opts))
+}
+
+// newSettingsProvider returns a settings provider using the setting controller, configured by the options.
+func newSettingsProvider(settingController managementcontrollers.SettingController, metrics *settingsMetrics, opts Options) *settingsProvider {
 	sp := &settingsProvider{
-		settings:     settingController,
-		settingCache: settingController.Cache(),
+		settings:               settingController,
+		settingCache:           settingController.Cache(),
+		unknownAfterCycles:     opts.UnknownAfterCycles,
+		resetValueOnEnvRemoval: opts.ResetValueOnEnvRemoval,
+		metrics:                metrics,
 	}
 
-	return settings.SetProvider(sp)
+	if opts.OnLeader != nil {
+		var leader atomic.Bool
+		sp.isLeader = leader.Load
+		opts.OnLeader(func(ctx context.Context) error {
+			leader.Store(true)
+			sp.reconcileAsLeader()
+			return nil
+		})
+	}
+
+	return sp
 }
 
 type settingsProvider struct {
 	settings     managementcontrollers.SettingClient
 	settingCache managementcontrollers.SettingCache
 	fallback     map[string]string
+	fallbackLock sync.RWMutex
+
+	// unknownAfterCycles is how many consecutive calls to SetAll a setting must be missing from the known settings
+	// before it is marked as unknown. One or less marks it as unknown right away.
+	unknownAfterCycles int
+
+	// resetValueOnEnvRemoval resets a setting to its default when the env var that configured it is removed,
+	// instead of keeping the value that was set by the env var.
+	resetValueOnEnvRemoval bool
+
+	// defaultsFrom maps a setting to the setting whose effective value it defaults to when it has no value.
+	defaultsFrom     map[string]string
+	defaultsFromLock sync.RWMutex
+
+	// maxSizes maps a setting to the maximum size in bytes of a value that SetAll applies to it.
+	maxSizes     map[string]int
+	maxSizesLock sync.RWMutex
+
+	// envKeys maps a setting to the env var configuring it, overriding the key derived by settings.GetEnvKey.
+	envKeys     map[string]string
+	envKeysLock sync.RWMutex
+
+	// metrics counts the settings reconciled by SetAll. Nil disables the metrics.
+	metrics *settingsMetrics
+
+	// isLeader reports whether this replica is the leader. Only the leader writes settings in SetAll; other replicas
+	// only update their fallback values. Nil treats every replica as the leader.
+	isLeader func() bool
+
+	// lastSettingsMap is the settingsMap of the last call to SetAll, which is reconciled again once this replica
+	// becomes the leader. Until then, Get uses its defaults instead of the stored ones, which may be outdated.
+	lastSettingsMap     map[string]settings.Setting
+	lastSettingsMapLock sync.Mutex
+
+	// unavailableBackoff is how SetAll retries API calls that failed because the API server is briefly unavailable,
+	// e.g. while etcd elects a new leader. The zero value uses defaultUnavailableBackoff.
+	unavailableBackoff wait.Backoff
 }
 
 func (s *settingsProvider) Get(name string) string {
-	value := os.Getenv(settings.GetEnvKey(name))
+	value := os.Getenv(s.envKey(name))
 	if value != "" {
 		return value
 	}
@@ -41,20 +128,202 @@ func (s *settingsProvider) Get(name string) string {
 	if err != nil {
 		val, err := s.settings.Get(name, metav1.GetOptions{})
 		if err != nil {
-			return s.fallback[name]
+			return s.getFallback(name)
 		}
 		obj = val
 	}
 
-	if obj.Value == "" {
-		return obj.Default
+	return s.resolveValue(name, obj.Value, s.defaultOf(obj), s.Get)
+}
+
+// resolveValue returns the value of the setting if it is set. Otherwise, it returns the effective value, as returned by
+// lookup, of the setting registered with SetDefaultFrom, or def if that has no effective value either.
+func (s *settingsProvider) resolveValue(name, value, def string, lookup func(name string) string) string {
+	if value != "" {
+		return value
+	}
+
+	if from, ok := s.getDefaultFrom(name); ok {
+		if value := lookup(from); value != "" {
+			return value
+		}
+	}
+
+	return def
+}
+
+// defaultOf returns the default of the stored setting. The stored default is only updated by the leader, so while this
+// replica is not the leader, e.g. during startup before leader election, the default of the last call to SetAll is used.
+func (s *settingsProvider) defaultOf(obj *v3.Setting) string {
+	if s.isLeader != nil && !s.isLeader() {
+		s.lastSettingsMapLock.Lock()
+		setting, ok := s.lastSettingsMap[obj.Name]
+		s.lastSettingsMapLock.Unlock()
+		if ok {
+			return setting.Default
+		}
+	}
+
+	return obj.Default
+}
+
+// SetDefaultFrom makes the setting default to the effective value of the setting from when it has no value and
+// no env var is set for it. The setting's own default is used if from has no effective value either.
+// An error is returned if this would create a cycle of defaults.
+func (s *settingsProvider) SetDefaultFrom(name, from string) error {
+	s.defaultsFromLock.Lock()
+	defer s.defaultsFromLock.Unlock()
+
+	chain := []string{name}
+	for next, ok := from, true; ok; next, ok = s.defaultsFrom[next] {
+		chain = append(chain, next)
+		if next == name {
+			return fmt.Errorf("setting %s can not default to %s: cycle %s", name, from, strings.Join(chain, " -> "))
+		}
+	}
+
+	if s.defaultsFrom == nil {
+		s.defaultsFrom = map[string]string{}
+	}
+	s.defaultsFrom[name] = from
+
+	return nil
+}
+
+// SetMaxSize limits the size of values that SetAll applies to the setting from its env var to maxBytes, so that a
+// runaway value, e.g. a large CA bundle, does not bloat etcd.
+func (s *settingsProvider) SetMaxSize(name string, maxBytes int) {
+	s.maxSizesLock.Lock()
+	defer s.maxSizesLock.Unlock()
+
+	if s.maxSizes == nil {
+		s.maxSizes = map[string]int{}
+	}
+	s.maxSizes[name] = maxBytes
+}
+
+// SetEnvKey makes the setting configurable by the env var envKey instead of the one derived from its name by
+// settings.GetEnvKey, e.g. to keep supporting an env var that was named before the setting. The derived env var is
+// ignored for the setting afterwards.
+func (s *settingsProvider) SetEnvKey(name, envKey string) {
+	s.envKeysLock.Lock()
+	defer s.envKeysLock.Unlock()
+
+	if s.envKeys == nil {
+		s.envKeys = map[string]string{}
+	}
+	s.envKeys[name] = envKey
+}
+
+// envKey returns the env var configuring the setting.
+func (s *settingsProvider) envKey(name string) string {
+	s.envKeysLock.RLock()
+	defer s.envKeysLock.RUnlock()
+
+	if envKey, ok := s.envKeys[name]; ok {
+		return envKey
+	}
+
+	return settings.GetEnvKey(name)
+}
+
+// checkMaxSize returns an error if the value exceeds the maximum size registered for the setting.
+func (s *settingsProvider) checkMaxSize(name, value string) error {
+	s.maxSizesLock.RLock()
+	defer s.maxSizesLock.RUnlock()
+
+	if maxBytes, ok := s.maxSizes[name]; ok && len(value) > maxBytes {
+		return fmt.Errorf("value of setting %s from env var %s is %d bytes, exceeding the limit of %d bytes", name, s.envKey(name), len(value), maxBytes)
 	}
 
-	return obj.Value
+	return nil
+}
+
+// getDefaultFrom returns the setting the given setting defaults to, if any.
+func (s *settingsProvider) getDefaultFrom(name string) (string, bool) {
+	s.defaultsFromLock.RLock()
+	defer s.defaultsFromLock.RUnlock()
+
+	from, ok := s.defaultsFrom[name]
+	return from, ok
+}
+
+// ExplainValue returns a human-readable explanation of how the effective value of the setting is derived.
+func (s *settingsProvider) ExplainValue(name string) (string, error) {
+	obj, err := s.settingCache.Get(name)
+	if err != nil {
+		obj, err = s.settings.Get(name, metav1.GetOptions{})
+		if err != nil {
+			return "", err
+		}
+	}
+
+	envKey := s.envKey(name)
+	if envValue := os.Getenv(envKey); envValue != "" {
+		if obj.Value == "" || obj.Value == envValue {
+			return fmt.Sprintf("value %q from env var %s is used; default is %q", envValue, envKey, obj.Default), nil
+		}
+		return fmt.Sprintf("value %q from env var %s overrides stored value %q; default is %q", envValue, envKey, obj.Value, obj.Default), nil
+	}
+
+	if obj.Value != "" {
+		return fmt.Sprintf("stored value %q is used; default is %q", obj.Value, obj.Default), nil
+	}
+
+	def := s.defaultOf(obj)
+	if from, ok := s.getDefaultFrom(name); ok {
+		if value := s.resolveValue(name, "", "", s.Get); value != "" {
+			return fmt.Sprintf("no value is stored, value %q of setting %s is used; default is %q", value, from, def), nil
+		}
+	}
+
+	return fmt.Sprintf("no value is stored, default %q is used", def), nil
+}
+
+// GetInt returns the effective value of the setting parsed as an int.
+func (s *settingsProvider) GetInt(name string) (int, error) {
+	value := s.Get(name)
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("setting %s has invalid int value %q: %w", name, value, err)
+	}
+
+	return i, nil
+}
+
+// GetBool returns the effective value of the setting parsed as a bool.
+func (s *settingsProvider) GetBool(name string) (bool, error) {
+	value := s.Get(name)
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("setting %s has invalid bool value %q: %w", name, value, err)
+	}
+
+	return b, nil
+}
+
+// GetDuration returns the effective value of the setting parsed as a time.Duration.
+func (s *settingsProvider) GetDuration(name string) (time.Duration, error) {
+	value := s.Get(name)
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("setting %s has invalid duration value %q: %w", name, value, err)
+	}
+
+	return d, nil
+}
+
+// getFallback returns the effective value of the setting as of the last call to SetAll. SetAll resolves the settings
+// registered with SetDefaultFrom when computing the fallback values.
+func (s *settingsProvider) getFallback(name string) string {
+	s.fallbackLock.RLock()
+	defer s.fallbackLock.RUnlock()
+
+	return s.fallback[name]
 }
 
 func (s *settingsProvider) Set(name, value string) error {
-	envValue := os.Getenv(settings.GetEnvKey(name))
+	envValue := os.Getenv(s.envKey(name))
 	if envValue != "" {
 		return fmt.Errorf("setting %s can not be set because it is from environment variable", name)
 	}
@@ -68,6 +337,70 @@ func (s *settingsProvider) Set(name, value string) error {
 	return err
 }
 
+// SetValues sets the values of several settings as a whole. All writes are computed before any is applied, and if a
+// write fails, the settings that were already updated are rolled back to their prior values. The returned error
+// contains both the original error and any errors that occurred while rolling back.
+func (s *settingsProvider) SetValues(values map[string]string) error {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var writes []*v3.Setting
+	previousValues := map[string]string{}
+	for _, name := range names {
+		if os.Getenv(s.envKey(name)) != "" {
+			return fmt.Errorf("setting %s can not be set because it is from environment variable", name)
+		}
+
+		obj, err := s.settings.Get(name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		if obj.Value == values[name] {
+			continue
+		}
+
+		previousValues[name] = obj.Value
+		obj.Value = values[name]
+		writes = append(writes, obj)
+	}
+
+	var applied []string
+	for _, obj := range writes {
+		if _, err := s.settings.Update(obj); err != nil {
+			return errors.Join(fmt.Errorf("error setting %s: %w", obj.Name, err), s.rollbackValues(applied, previousValues))
+		}
+		applied = append(applied, obj.Name)
+	}
+
+	return nil
+}
+
+// rollbackValues restores the given settings to their previous values.
+func (s *settingsProvider) rollbackValues(names []string, previousValues map[string]string) error {
+	var errs []error
+	for _, name := range names {
+		err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			obj, err := s.settings.Get(name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+
+			obj.Value = previousValues[name]
+			_, err = s.settings.Update(obj)
+			return err
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error rolling back setting %s: %w", name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
 func (s *settingsProvider) SetIfUnset(name, value string) error {
 	obj, err := s.settings.Get(name, metav1.GetOptions{})
 	if err != nil {
@@ -83,114 +416,404 @@ func (s *settingsProvider) SetIfUnset(name, value string) error {
 	return err
 }
 
-// SetAll iterates through a map of settings.Setting and updates corresponding settings in k8s
-// to match any values set for them via their respective CATTLE_<setting-name> env var, their
-// source to "env" if configured by an env var, and their default to match the setting in the map.
-// NOTE: All settings not provided in settingsMap will be marked as unknown, and may be removed in the future.
+// SetAll reconciles the settings in k8s with settingsMap. Missing settings are created, and existing ones get the
+// default from the map and the value of their env var, if set, with source "env". Settings not in settingsMap are
+// marked as unknown, and may be removed in the future. Only the leader writes settings; every replica updates the
+// fallback values used by Get when a setting can't be read. Env values exceeding their maximum size are not applied
+// and reported in the returned error once all other settings were reconciled.
 func (s *settingsProvider) SetAll(settingsMap map[string]settings.Setting) error {
+	_, err := s.SetAllWithResult(settingsMap)
+	return err
+}
+
+// SetAllWithResult is SetAll, returning how many settings were created, updated, left unchanged and newly marked as
+// unknown.
+func (s *settingsProvider) SetAllWithResult(settingsMap map[string]settings.Setting) (settings.SetAllResult, error) {
+	s.lastSettingsMapLock.Lock()
+	s.lastSettingsMap = settingsMap
+	s.lastSettingsMapLock.Unlock()
+
+	// All settings are fetched with a single List up front, so that a reconcile in which nothing changed does not
+	// issue an API call per setting. The settings cache is not yet available at this point, thus using the client directly.
+	var list *v3.SettingList
+	err := s.retryOnUnavailable(func() error {
+		var err error
+		list, err = s.settings.List(metav1.ListOptions{})
+		return err
+	})
+	if err != nil {
+		s.metrics.incReconcileErrors()
+		return settings.SetAllResult{}, fmt.Errorf("error listing settings: %w", err)
+	}
+
+	existing := make(map[string]*v3.Setting, len(list.Items))
+	for i := range list.Items {
+		existing[list.Items[i].Name] = &list.Items[i]
+	}
+
+	if s.isLeader != nil && !s.isLeader() {
+		s.setFallbackFromExisting(settingsMap, existing)
+		return settings.SetAllResult{}, nil
+	}
+
+	var result settings.SetAllResult
 	fallback := map[string]string{}
+	var errs []error
 
-	for name, setting := range settingsMap {
-		key := settings.GetEnvKey(name)
+	for _, name := range s.reconcileOrder(settingsMap) {
+		setting := settingsMap[name]
+		key := s.envKey(name)
 		envValue, envOk := os.LookupEnv(key)
 
-		obj, err := s.settings.Get(setting.Name, metav1.GetOptions{})
-		if apierrors.IsNotFound(err) {
-			newSetting := &v3.Setting{
-				ObjectMeta: metav1.ObjectMeta{
-					Name: setting.Name,
-				},
-				Default: setting.Default,
-			}
-			if envOk {
-				newSetting.Source = "env"
-				newSetting.Value = envValue
-			}
-			if newSetting.Value == "" {
-				fallback[newSetting.Name] = newSetting.Default
-			} else {
-				fallback[newSetting.Name] = newSetting.Value
-			}
-			_, err := s.settings.Create(newSetting)
-			// Rancher will race in an HA setup to try and create the settings
-			// so if it exists just move on.
-			if err != nil && !apierrors.IsAlreadyExists(err) {
-				return err
+		var sizeErr error
+		if envOk {
+			if sizeErr = s.checkMaxSize(name, envValue); sizeErr != nil {
+				s.metrics.incReconcileErrors()
+				errs = append(errs, sizeErr)
 			}
-		} else if err != nil {
-			return err
-		} else {
-			update := false
-			if obj.Default != setting.Default {
-				obj.Default = setting.Default
-				update = true
-			}
-			if envOk && obj.Source != "env" {
-				obj.Source = "env"
-				update = true
-			}
-			if !envOk && obj.Source == "env" {
-				obj.Source = ""
-				update = true
-			}
-			if envOk && obj.Value != envValue {
-				obj.Value = envValue
-				update = true
-			}
-			if obj.Value == "" {
-				fallback[obj.Name] = obj.Default
-			} else {
-				fallback[obj.Name] = obj.Value
-			}
-			if update {
-				_, err := s.settings.Update(obj)
-				if err != nil {
+		}
+
+		// SetAll may be called concurrently, so retry when another writer updated the setting in the meantime.
+		obj := existing[setting.Name]
+		isFirstAttempt := true
+		var synced *v3.Setting
+		var action syncAction
+		syncOnce := func() error {
+			defer func() { isFirstAttempt = false }()
+
+			if !isFirstAttempt { // Refetch only if the first attempt to update failed.
+				var err error
+				obj, err = s.settings.Get(setting.Name, metav1.GetOptions{})
+				if apierrors.IsNotFound(err) {
+					obj = nil
+				} else if err != nil {
 					return err
 				}
 			}
+
+			syncEnvValue, syncEnvOk := envValue, envOk
+			if sizeErr != nil {
+				// Keep the prior value and source of the setting by syncing it against them instead of the env var.
+				syncEnvValue, syncEnvOk = "", false
+				if obj != nil && obj.Source == "env" {
+					syncEnvValue, syncEnvOk = obj.Value, true
+				}
+			}
+
+			var err error
+			synced, action, err = s.syncSetting(setting, obj.DeepCopy(), syncEnvValue, syncEnvOk)
+			return err
+		}
+		err := s.retryOnUnavailable(func() error {
+			return retry.RetryOnConflict(retry.DefaultRetry, syncOnce)
+		})
+		if err != nil {
+			s.metrics.incReconcileErrors()
+			return result, err
+		}
+		switch action {
+		case syncCreated:
+			result.Created++
+		case syncUpdated:
+			result.Updated++
+		default:
+			result.Unchanged++
 		}
+		if sizeErr == nil {
+			s.metrics.incReconciled()
+		}
+		fallback[setting.Name] = s.resolveValue(name, synced.Value, synced.Default, lookupIn(fallback))
+	}
+
+	if len(settingsMap) > 0 {
+		s.fallbackLock.Lock()
+		s.fallback = fallback
+		s.fallbackLock.Unlock()
+	}
+
+	result.LabeledUnknown = s.cleanupUnknownSettings(settingsMap, list.Items)
+
+	return result, errors.Join(errs...)
+}
+
+// reconcileAsLeader reconciles the settings of the last call to SetAll again, as SetAll didn't update them while this
+// replica wasn't the leader. Errors are only logged, as SetAll already applied all settings it could.
+func (s *settingsProvider) reconcileAsLeader() {
+	s.lastSettingsMapLock.Lock()
+	settingsMap := s.lastSettingsMap
+	s.lastSettingsMapLock.Unlock()
+
+	// Without a prior call to SetAll there is nothing to reconcile, and a nil map would mark all settings as unknown.
+	if settingsMap == nil {
+		return
+	}
+
+	if err := s.SetAll(settingsMap); err != nil {
+		logrus.Errorf("Error reconciling settings after becoming the leader: %v", err)
+	}
+}
+
+// setFallbackFromExisting sets the fallback values to the effective values the leader reconciles the settings to,
+// without writing any setting.
+func (s *settingsProvider) setFallbackFromExisting(settingsMap map[string]settings.Setting, existing map[string]*v3.Setting) {
+	if len(settingsMap) == 0 {
+		return
+	}
+
+	fallback := make(map[string]string, len(settingsMap))
+	for _, name := range s.reconcileOrder(settingsMap) {
+		setting := settingsMap[name]
+		expected := &v3.Setting{Default: setting.Default}
+		obj := existing[setting.Name]
+		envValue, envOk := os.LookupEnv(s.envKey(name))
+		switch {
+		case envOk && s.checkMaxSize(name, envValue) == nil:
+			expected.Value = envValue
+		case obj == nil:
+		case !envOk && obj.Source == "env" && s.resetValueOnEnvRemoval:
+		default:
+			expected.Value = obj.Value
+		}
+		fallback[setting.Name] = s.resolveValue(name, expected.Value, expected.Default, lookupIn(fallback))
 	}
 
+	s.fallbackLock.Lock()
 	s.fallback = fallback
+	s.fallbackLock.Unlock()
+}
 
-	if err := s.cleanupUnknownSettings(settingsMap); err != nil {
-		logrus.Errorf("Error cleaning up unknown settings: %v", err)
+// lookupIn returns a lookup for resolveValue reading the fallback values computed so far. Settings are reconciled in
+// reconcileOrder, so the setting a setting defaults to is already computed if it is part of the same call to SetAll.
+func lookupIn(fallback map[string]string) func(name string) string {
+	return func(name string) string {
+		return fallback[name]
 	}
+}
 
-	return nil
+// reconcileOrder returns the names of the settings sorted by name, with every setting moved after the setting it
+// defaults to, if that is part of settingsMap as well.
+func (s *settingsProvider) reconcileOrder(settingsMap map[string]settings.Setting) []string {
+	names := make([]string, 0, len(settingsMap))
+	for name := range settingsMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	s.defaultsFromLock.RLock()
+	defer s.defaultsFromLock.RUnlock()
+
+	order := make([]string, 0, len(names))
+	visited := map[string]bool{}
+	var visit func(name string)
+	visit = func(name string) {
+		if visited[name] {
+			return
+		}
+		visited[name] = true
+
+		// SetDefaultFrom rejects cycles, so following the dependencies terminates.
+		if from, ok := s.defaultsFrom[name]; ok {
+			if _, known := settingsMap[from]; known {
+				visit(from)
+			}
+		}
+		order = append(order, name)
+	}
+	for _, name := range names {
+		visit(name)
+	}
+
+	return order
 }
 
-const unknownSettingLabelKey = "cattle.io/unknown"
+// syncAction is what syncSetting did to a setting.
+type syncAction int
 
-// cleanupUnknownSettings lists all settings in the cluster and cleans up all unknown (e.g. deprecated) settings.
-// Such settings are marked as unknown with a label so that they can be easily identified and may be removed in the future.
-func (s *settingsProvider) cleanupUnknownSettings(settingsMap map[string]settings.Setting) error {
-	// The settings cache is not yet available at this point, thus using the client directly.
-	list, err := s.settings.List(metav1.ListOptions{})
-	if err != nil {
-		return err
+const (
+	syncUnchanged syncAction = iota
+	syncCreated
+	syncUpdated
+)
+
+// syncSetting creates the setting in k8s if obj is nil, or updates obj if it does not match the given setting and
+// env var, and returns the synced setting and how it was synced. No API call is made if obj is already up to date.
+// Only the fields managed by the provider are changed on obj, so labels and annotations added by an admin are kept.
+// A stored setting with an empty default, e.g. one created by hand, gets the default from the code without losing
+// its value.
+func (s *settingsProvider) syncSetting(setting settings.Setting, obj *v3.Setting, envValue string, envOk bool) (*v3.Setting, syncAction, error) {
+	if obj == nil {
+		newSetting := &v3.Setting{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: setting.Name,
+			},
+			Default: setting.Default,
+		}
+		if envOk {
+			newSetting.Source = "env"
+			newSetting.Value = envValue
+		}
+		_, err := s.settings.Create(newSetting)
+		// Rancher will race in an HA setup to try and create the settings
+		// so if it exists just move on.
+		if apierrors.IsAlreadyExists(err) {
+			return newSetting, syncUnchanged, nil
+		}
+		if err != nil {
+			return nil, syncUnchanged, err
+		}
+		return newSetting, syncCreated, nil
+	}
+
+	update := false
+	if obj.Default != setting.Default {
+		obj.Default = setting.Default
+		update = true
+	}
+	if envOk && obj.Source != "env" {
+		obj.Source = "env"
+		update = true
+	}
+	if !envOk && obj.Source == "env" {
+		// The env var that configured the setting was removed. Keep its value unless configured to reset to the default.
+		obj.Source = ""
+		if s.resetValueOnEnvRemoval && obj.Value != "" {
+			recordPreviousValue(obj)
+			obj.Value = ""
+		}
+		update = true
+	}
+	if envOk && obj.Value != envValue {
+		recordPreviousValue(obj)
+		obj.Value = envValue
+		update = true
+	}
+	if !update {
+		return obj, syncUnchanged, nil
+	}
+	if _, err := s.settings.Update(obj); err != nil {
+		return nil, syncUnchanged, err
 	}
 
-	for _, setting := range list.Items {
+	return obj, syncUpdated, nil
+}
+
+// EffectiveValue returns the stored value of the setting, or its default if the value is empty. Unlike Get, it does not
+// resolve settings registered with SetDefaultFrom, so it can be used on settings read from any client.
+func EffectiveValue(setting *v3.Setting) string {
+	if setting.Value == "" {
+		return setting.Default
+	}
+	return setting.Value
+}
+
+const (
+	unknownSettingLabelKey     = "cattle.io/unknown"
+	missingCyclesAnnotationKey = "settings.cattle.io/missing-cycles"
+	previousValueAnnotationKey = "settings.cattle.io/previous-value"
+)
+
+// defaultUnavailableBackoff retries for about 15 seconds, long enough to ride out an etcd leader election.
+var defaultUnavailableBackoff = wait.Backoff{
+	Steps:    6,
+	Duration: 500 * time.Millisecond,
+	Factor:   2,
+	Jitter:   0.1,
+}
+
+// recordPreviousValue stores the current value of the setting in an annotation before it gets overwritten,
+// so that an admin can recover it. Only one level of history is kept to bound the size of the object.
+func recordPreviousValue(setting *v3.Setting) {
+	if setting.Annotations == nil {
+		setting.Annotations = map[string]string{}
+	}
+	setting.Annotations[previousValueAnnotationKey] = setting.Value
+}
+
+// cleanupUnknownSettings goes through the existing settings in the cluster and cleans up all unknown (e.g. deprecated) settings.
+// Such settings are marked as unknown with a label so that they can be easily identified and may be removed in the future.
+// If unknownAfterCycles is greater than one, the number of consecutive calls a setting has been missing from the known
+// settings is counted in an annotation, and the setting is only marked as unknown once it reaches unknownAfterCycles.
+// If the provider isn't permitted to label settings, the cleanup is skipped with a warning and doesn't fail SetAll.
+func (s *settingsProvider) cleanupUnknownSettings(settingsMap map[string]settings.Setting, existing []v3.Setting) int {
+	labeled := 0
+	for _, setting := range existing {
 		if _, ok := settingsMap[setting.Name]; ok {
+			if _, ok := setting.Annotations[missingCyclesAnnotationKey]; ok {
+				if err := s.updateWithRetry(&setting, clearMissingCycles); err != nil {
+					logrus.Errorf("Error removing annotation %s from setting %s: %v", missingCyclesAnnotationKey, setting.Name, err)
+				}
+			}
 			continue
 		}
 
+		if setting.Labels[unknownSettingLabelKey] == "true" {
+			// Already marked as unknown by a previous, possibly partially failed, cleanup.
+			continue
+		}
+
+		if s.unknownAfterCycles > 1 {
+			// A missing or malformed annotation counts as not missing before.
+			cycles, _ := strconv.Atoi(setting.Annotations[missingCyclesAnnotationKey])
+			cycles++
+			if cycles < s.unknownAfterCycles {
+				if err := s.updateWithRetry(&setting, setMissingCycles(cycles)); err != nil {
+					logrus.Errorf("Error adding annotation %s to setting %s: %v", missingCyclesAnnotationKey, setting.Name, err)
+				}
+				continue
+			}
+		}
+
 		if err := s.markSettingAsUnknown(&setting); err != nil {
+			if apierrors.IsForbidden(err) {
+				// Labeling the remaining settings would be forbidden as well, e.g. when running with a restricted
+				// service account. The known settings are reconciled already, so only the cleanup is skipped.
+				logrus.Warnf("Not permitted to add label %s to setting %s, skipping the cleanup of unknown settings: %v", unknownSettingLabelKey, setting.Name, err)
+				return labeled
+			}
 			logrus.Errorf("Error adding label %s to setting %s: %v", unknownSettingLabelKey, setting.Name, err)
 			continue
 		}
+		s.metrics.incUnknown()
+		labeled++
 	}
 
-	return nil
+	return labeled
 }
 
-// markSettingAsUnknown adds a label to the setting to mark it as unknown.
+// markSettingAsUnknown adds a label to the setting to mark it as unknown. The count of cycles it has been missing is
+// no longer needed once it is labeled, so it is removed.
 func (s *settingsProvider) markSettingAsUnknown(setting *v3.Setting) error {
 	logrus.Warnf("Unknown setting %s", setting.Name)
 
+	return s.updateWithRetry(setting, func(setting *v3.Setting) {
+		if setting.Labels == nil {
+			setting.Labels = map[string]string{}
+		}
+		setting.Labels[unknownSettingLabelKey] = "true"
+		clearMissingCycles(setting)
+	})
+}
+
+// setMissingCycles returns a mutation recording that the setting has been missing from the known settings for the
+// given number of consecutive cycles.
+func setMissingCycles(cycles int) func(setting *v3.Setting) {
+	return func(setting *v3.Setting) {
+		if setting.Annotations == nil {
+			setting.Annotations = map[string]string{}
+		}
+		setting.Annotations[missingCyclesAnnotationKey] = strconv.Itoa(cycles)
+	}
+}
+
+// clearMissingCycles removes the annotation counting the cycles the setting has been missing.
+func clearMissingCycles(setting *v3.Setting) {
+	delete(setting.Annotations, missingCyclesAnnotationKey)
+}
+
+// updateWithRetry applies mutate to the setting and updates it, refetching the setting and retrying on conflict.
+func (s *settingsProvider) updateWithRetry(setting *v3.Setting, mutate func(*v3.Setting)) error {
 	isFirstAttempt := true
-	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+	updateOnce := func() error {
 		defer func() { isFirstAttempt = false }()
 
 		var err error
@@ -205,14 +828,24 @@ func (s *settingsProvider) markSettingAsUnknown(setting *v3.Setting) error {
 			}
 		}
 
-		if setting.Labels == nil {
-			setting.Labels = map[string]string{}
-		}
-		setting.Labels[unknownSettingLabelKey] = "true"
+		mutate(setting)
 
 		_, err = s.settings.Update(setting)
 		return err
+	}
+
+	return s.retryOnUnavailable(func() error {
+		return retry.RetryOnConflict(retry.DefaultRetry, updateOnce)
 	})
+}
 
-	return err
+// retryOnUnavailable calls fn until it does not fail with ServiceUnavailable, backing off between attempts. Conflicts
+// are not retried here, as they require refetching the setting, which the callers handle.
+func (s *settingsProvider) retryOnUnavailable(fn func() error) error {
+	backoff := s.unavailableBackoff
+	if backoff.Steps == 0 {
+		backoff = defaultUnavailableBackoff
+	}
+
+	return retry.OnError(backoff, apierrors.IsServiceUnavailable, fn)
 }
@@ -1,9 +1,14 @@
 package settings
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"strconv"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	management "github.com/rancher/rancher/pkg/apis/management.cattle.io"
@@ -11,9 +16,11 @@
 	"github.com/rancher/rancher/pkg/settings"
 	"github.com/rancher/wrangler/v2/pkg/generic/fake"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/utils/pointer"
 )
 
@@ -313,3 +320,1309 @@ func TestSettingUnknownLabelIsRetried(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, "true", store["unknown"].Labels[unknownSettingLabelKey])
 }
+
+// newStoreBackedClient returns a mock setting client that reads from and writes to the given store. The client is safe
+// for concurrent use and rejects updates based on a stale resource version with a conflict, like the API server does.
+func newStoreBackedClient(t testing.TB, store map[string]v3.Setting) *fake.MockNonNamespacedControllerInterface[*v3.Setting, *v3.SettingList] {
+	client := fake.NewMockNonNamespacedControllerInterface[*v3.Setting, *v3.SettingList](gomock.NewController(t))
+	var mu sync.Mutex
+
+	client.EXPECT().Get(gomock.Any(), gomock.Any()).DoAndReturn(func(name string, options metav1.GetOptions) (*v3.Setting, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		val, ok := store[name]
+		if !ok {
+			return nil, apierrors.NewNotFound(schema.GroupResource{}, name)
+		}
+
+		return val.DeepCopy(), nil
+	}).AnyTimes()
+	client.EXPECT().Create(gomock.Any()).DoAndReturn(func(setting *v3.Setting) (*v3.Setting, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if _, ok := store[setting.Name]; ok {
+			return nil, apierrors.NewAlreadyExists(schema.GroupResource{}, setting.Name)
+		}
+
+		setting = setting.DeepCopy()
+		setting.ResourceVersion = "1"
+		store[setting.Name] = *setting
+		return setting, nil
+	}).AnyTimes()
+	client.EXPECT().Update(gomock.Any()).DoAndReturn(func(setting *v3.Setting) (*v3.Setting, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		current, ok := store[setting.Name]
+		if !ok {
+			return nil, apierrors.NewNotFound(schema.GroupResource{}, setting.Name)
+		}
+		if current.ResourceVersion != setting.ResourceVersion {
+			return nil, apierrors.NewConflict(schema.GroupResource{}, setting.Name, fmt.Errorf("stale resource version"))
+		}
+
+		setting = setting.DeepCopy()
+		version, _ := strconv.Atoi(setting.ResourceVersion)
+		setting.ResourceVersion = strconv.Itoa(version + 1)
+		store[setting.Name] = *setting
+		return setting, nil
+	}).AnyTimes()
+	client.EXPECT().List(gomock.Any()).DoAndReturn(func(opts metav1.ListOptions) (*v3.SettingList, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		var items []v3.Setting
+		for _, setting := range store {
+			items = append(items, *setting.DeepCopy())
+		}
+
+		return &v3.SettingList{Items: items}, nil
+	}).AnyTimes()
+	client.EXPECT().Cache().Return(nil).AnyTimes()
+
+	return client
+}
+
+func TestSetAllRecordsPreviousValue(t *testing.T) {
+	store := map[string]v3.Setting{
+		"overridden": {
+			ObjectMeta: metav1.ObjectMeta{Name: "overridden"},
+			Value:      "admin-value",
+			Default:    "default",
+		},
+		"unchanged": {
+			ObjectMeta: metav1.ObjectMeta{Name: "unchanged"},
+			Value:      "admin-value",
+			Default:    "default",
+		},
+	}
+
+	provider := settingsProvider{
+		settings: newStoreBackedClient(t, store),
+	}
+
+	t.Setenv(settings.GetEnvKey("overridden"), "env-value")
+
+	settingMap := map[string]settings.Setting{
+		"overridden": settings.NewSetting("overridden", "default"),
+		"unchanged":  settings.NewSetting("unchanged", "default"),
+	}
+
+	err := provider.SetAll(settingMap)
+	assert.Nil(t, err)
+
+	overridden := store["overridden"]
+	assert.Equal(t, "env-value", overridden.Value)
+	assert.Equal(t, "admin-value", overridden.Annotations[previousValueAnnotationKey])
+
+	unchanged := store["unchanged"]
+	assert.Equal(t, "admin-value", unchanged.Value)
+	assert.NotContains(t, unchanged.Annotations, previousValueAnnotationKey)
+
+	// Only one level of history is kept.
+	t.Setenv(settings.GetEnvKey("overridden"), "newer-env-value")
+
+	err = provider.SetAll(settingMap)
+	assert.Nil(t, err)
+
+	overridden = store["overridden"]
+	assert.Equal(t, "newer-env-value", overridden.Value)
+	assert.Equal(t, "env-value", overridden.Annotations[previousValueAnnotationKey])
+	assert.Len(t, overridden.Annotations, 1)
+}
+
+// newStoreBackedCache returns a mock setting cache that reads from the given store.
+func newStoreBackedCache(t *testing.T, store map[string]v3.Setting) *fake.MockNonNamespacedCacheInterface[*v3.Setting] {
+	cache := fake.NewMockNonNamespacedCacheInterface[*v3.Setting](gomock.NewController(t))
+
+	cache.EXPECT().Get(gomock.Any()).DoAndReturn(func(name string) (*v3.Setting, error) {
+		val, ok := store[name]
+		if !ok {
+			return nil, apierrors.NewNotFound(schema.GroupResource{}, name)
+		}
+
+		return val.DeepCopy(), nil
+	}).AnyTimes()
+
+	return cache
+}
+
+func TestTypedGetters(t *testing.T) {
+	store := map[string]v3.Setting{
+		"int":              {ObjectMeta: metav1.ObjectMeta{Name: "int"}, Value: "42", Default: "1"},
+		"int-default":      {ObjectMeta: metav1.ObjectMeta{Name: "int-default"}, Default: "10"},
+		"int-malformed":    {ObjectMeta: metav1.ObjectMeta{Name: "int-malformed"}, Value: "abc"},
+		"bool":             {ObjectMeta: metav1.ObjectMeta{Name: "bool"}, Value: "true", Default: "false"},
+		"bool-default":     {ObjectMeta: metav1.ObjectMeta{Name: "bool-default"}, Default: "false"},
+		"bool-malformed":   {ObjectMeta: metav1.ObjectMeta{Name: "bool-malformed"}, Value: "yes please"},
+		"duration":         {ObjectMeta: metav1.ObjectMeta{Name: "duration"}, Value: "5m", Default: "1s"},
+		"duration-default": {ObjectMeta: metav1.ObjectMeta{Name: "duration-default"}, Default: "30s"},
+		"duration-bad":     {ObjectMeta: metav1.ObjectMeta{Name: "duration-bad"}, Value: "5"},
+	}
+
+	provider := settingsProvider{
+		settings:     newStoreBackedClient(t, store),
+		settingCache: newStoreBackedCache(t, store),
+		fallback: map[string]string{
+			"int-fallback": "7",
+		},
+	}
+
+	i, err := provider.GetInt("int")
+	assert.Nil(t, err)
+	assert.Equal(t, 42, i)
+
+	i, err = provider.GetInt("int-default")
+	assert.Nil(t, err)
+	assert.Equal(t, 10, i)
+
+	i, err = provider.GetInt("int-fallback")
+	assert.Nil(t, err)
+	assert.Equal(t, 7, i)
+
+	_, err = provider.GetInt("int-malformed")
+	assert.ErrorContains(t, err, "int-malformed")
+
+	b, err := provider.GetBool("bool")
+	assert.Nil(t, err)
+	assert.True(t, b)
+
+	b, err = provider.GetBool("bool-default")
+	assert.Nil(t, err)
+	assert.False(t, b)
+
+	_, err = provider.GetBool("bool-malformed")
+	assert.ErrorContains(t, err, "bool-malformed")
+
+	d, err := provider.GetDuration("duration")
+	assert.Nil(t, err)
+	assert.Equal(t, 5*time.Minute, d)
+
+	d, err = provider.GetDuration("duration-default")
+	assert.Nil(t, err)
+	assert.Equal(t, 30*time.Second, d)
+
+	_, err = provider.GetDuration("duration-bad")
+	assert.ErrorContains(t, err, "duration-bad")
+}
+
+// StressSetAll calls SetAll on the provider from the given number of goroutines at once and returns the joined errors.
+func StressSetAll(provider *settingsProvider, settingMap map[string]settings.Setting, concurrency int) error {
+	var wg sync.WaitGroup
+	errs := make([]error, concurrency)
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = provider.SetAll(settingMap)
+		}(i)
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+func TestSetAllConcurrent(t *testing.T) {
+	store := map[string]v3.Setting{
+		"existing": {
+			ObjectMeta: metav1.ObjectMeta{Name: "existing", ResourceVersion: "1"},
+			Value:      "admin-value",
+			Default:    "old-default",
+		},
+		"from-env": {
+			ObjectMeta: metav1.ObjectMeta{Name: "from-env", ResourceVersion: "1"},
+			Value:      "admin-value",
+			Default:    "default",
+		},
+	}
+
+	provider := &settingsProvider{
+		settings:     newStoreBackedClient(t, store),
+		settingCache: newStoreBackedCache(t, map[string]v3.Setting{}),
+	}
+
+	t.Setenv(settings.GetEnvKey("from-env"), "env-value")
+
+	settingMap := map[string]settings.Setting{
+		"existing": settings.NewSetting("existing", "new-default"),
+		"from-env": settings.NewSetting("from-env", "default"),
+	}
+	for i := 0; i < 20; i++ {
+		name := fmt.Sprintf("new%d", i)
+		settingMap[name] = settings.NewSetting(name, "default")
+	}
+
+	// Read the fallback values while SetAll is running to catch data races.
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				provider.Get("new0")
+			}
+		}
+	}()
+
+	err := StressSetAll(provider, settingMap, 8)
+	close(done)
+	assert.Nil(t, err)
+
+	assert.Len(t, store, len(settingMap))
+
+	existing := store["existing"]
+	assert.Equal(t, "new-default", existing.Default)
+	assert.Equal(t, "admin-value", existing.Value)
+	assert.Equal(t, "admin-value", provider.getFallback("existing"))
+
+	fromEnv := store["from-env"]
+	assert.Equal(t, "env-value", fromEnv.Value)
+	assert.Equal(t, "env", fromEnv.Source)
+	assert.Equal(t, "admin-value", fromEnv.Annotations[previousValueAnnotationKey])
+	assert.Equal(t, "env-value", provider.getFallback("from-env"))
+
+	for i := 0; i < 20; i++ {
+		name := fmt.Sprintf("new%d", i)
+		assert.Equal(t, "default", store[name].Default)
+		assert.Equal(t, "default", provider.getFallback(name))
+	}
+}
+
+func TestSetValues(t *testing.T) {
+	store := map[string]v3.Setting{
+		"a": {ObjectMeta: metav1.ObjectMeta{Name: "a"}, Value: "old-a"},
+		"b": {ObjectMeta: metav1.ObjectMeta{Name: "b"}, Value: "old-b"},
+		"c": {ObjectMeta: metav1.ObjectMeta{Name: "c"}, Value: "c"},
+	}
+
+	provider := settingsProvider{
+		settings: newStoreBackedClient(t, store),
+	}
+
+	err := provider.SetValues(map[string]string{"a": "new-a", "b": "new-b", "c": "c"})
+	assert.Nil(t, err)
+	assert.Equal(t, "new-a", store["a"].Value)
+	assert.Equal(t, "new-b", store["b"].Value)
+	assert.Equal(t, "c", store["c"].Value)
+}
+
+func TestSetValuesRollsBackOnFailure(t *testing.T) {
+	store := map[string]v3.Setting{
+		"a": {ObjectMeta: metav1.ObjectMeta{Name: "a"}, Value: "old-a"},
+		"b": {ObjectMeta: metav1.ObjectMeta{Name: "b"}, Value: "old-b"},
+	}
+
+	client := fake.NewMockNonNamespacedControllerInterface[*v3.Setting, *v3.SettingList](gomock.NewController(t))
+	client.EXPECT().Get(gomock.Any(), gomock.Any()).DoAndReturn(func(name string, options metav1.GetOptions) (*v3.Setting, error) {
+		val := store[name]
+		return val.DeepCopy(), nil
+	}).AnyTimes()
+	client.EXPECT().Update(gomock.Any()).DoAndReturn(func(setting *v3.Setting) (*v3.Setting, error) {
+		// Fail the second write.
+		if setting.Name == "b" {
+			return nil, apierrors.NewServiceUnavailable("some error")
+		}
+		store[setting.Name] = *setting.DeepCopy()
+		return setting, nil
+	}).AnyTimes()
+
+	provider := settingsProvider{
+		settings: client,
+	}
+
+	err := provider.SetValues(map[string]string{"a": "new-a", "b": "new-b"})
+	assert.ErrorContains(t, err, "error setting b")
+	assert.Equal(t, "old-a", store["a"].Value, "the first write should be rolled back")
+	assert.Equal(t, "old-b", store["b"].Value)
+}
+
+func TestSetValuesReportsRollbackFailure(t *testing.T) {
+	store := map[string]v3.Setting{
+		"a": {ObjectMeta: metav1.ObjectMeta{Name: "a"}, Value: "old-a"},
+		"b": {ObjectMeta: metav1.ObjectMeta{Name: "b"}, Value: "old-b"},
+	}
+
+	client := fake.NewMockNonNamespacedControllerInterface[*v3.Setting, *v3.SettingList](gomock.NewController(t))
+	client.EXPECT().Get(gomock.Any(), gomock.Any()).DoAndReturn(func(name string, options metav1.GetOptions) (*v3.Setting, error) {
+		val := store[name]
+		return val.DeepCopy(), nil
+	}).AnyTimes()
+	client.EXPECT().Update(gomock.Any()).DoAndReturn(func(setting *v3.Setting) (*v3.Setting, error) {
+		// Only the first write succeeds, everything after fails including the rollback.
+		if setting.Name == "b" || store["a"].Value == "new-a" {
+			return nil, apierrors.NewServiceUnavailable("some error")
+		}
+		store[setting.Name] = *setting.DeepCopy()
+		return setting, nil
+	}).AnyTimes()
+
+	provider := settingsProvider{
+		settings: client,
+	}
+
+	err := provider.SetValues(map[string]string{"a": "new-a", "b": "new-b"})
+	assert.ErrorContains(t, err, "error setting b")
+	assert.ErrorContains(t, err, "error rolling back setting a")
+}
+
+func TestSetValuesFromEnv(t *testing.T) {
+	store := map[string]v3.Setting{
+		"a": {ObjectMeta: metav1.ObjectMeta{Name: "a"}, Value: "old-a"},
+		"b": {ObjectMeta: metav1.ObjectMeta{Name: "b"}, Value: "old-b"},
+	}
+
+	provider := settingsProvider{
+		settings: newStoreBackedClient(t, store),
+	}
+
+	t.Setenv(settings.GetEnvKey("b"), "env")
+
+	err := provider.SetValues(map[string]string{"a": "new-a", "b": "new-b"})
+	assert.Error(t, err)
+	assert.Equal(t, "old-a", store["a"].Value, "nothing should be written if any setting can not be set")
+}
+
+func TestExplainValue(t *testing.T) {
+	store := map[string]v3.Setting{
+		"env":     {ObjectMeta: metav1.ObjectMeta{Name: "env"}, Value: "stored", Default: "default", Source: "env"},
+		"stored":  {ObjectMeta: metav1.ObjectMeta{Name: "stored"}, Value: "stored", Default: "default"},
+		"default": {ObjectMeta: metav1.ObjectMeta{Name: "default"}, Default: "default"},
+	}
+
+	provider := settingsProvider{
+		settings:     newStoreBackedClient(t, store),
+		settingCache: newStoreBackedCache(t, store),
+	}
+
+	t.Setenv(settings.GetEnvKey("env"), "from-env")
+
+	explanation, err := provider.ExplainValue("env")
+	assert.Nil(t, err)
+	assert.Equal(t, `value "from-env" from env var CATTLE_ENV overrides stored value "stored"; default is "default"`, explanation)
+
+	explanation, err = provider.ExplainValue("stored")
+	assert.Nil(t, err)
+	assert.Equal(t, `stored value "stored" is used; default is "default"`, explanation)
+
+	explanation, err = provider.ExplainValue("default")
+	assert.Nil(t, err)
+	assert.Equal(t, `no value is stored, default "default" is used`, explanation)
+
+	_, err = provider.ExplainValue("missing")
+	assert.True(t, apierrors.IsNotFound(err))
+
+	assert.Nil(t, provider.SetDefaultFrom("default", "stored"))
+	explanation, err = provider.ExplainValue("default")
+	assert.Nil(t, err)
+	assert.Equal(t, `no value is stored, value "stored" of setting stored is used; default is "default"`, explanation)
+}
+
+func TestSetAllDefaultFromFallback(t *testing.T) {
+	store := map[string]v3.Setting{
+		"server-url": {ObjectMeta: metav1.ObjectMeta{Name: "server-url", ResourceVersion: "1"}, Value: "https://rancher.example.com"},
+	}
+	settingMap := map[string]settings.Setting{
+		"server-url": settings.NewSetting("server-url", ""),
+		"ui-url":     settings.NewSetting("ui-url", "default"),
+		"help-url":   settings.NewSetting("help-url", "default"),
+		"docs-url":   settings.NewSetting("docs-url", "docs-default"),
+	}
+
+	for _, isLeader := range []bool{true, false} {
+		t.Run(fmt.Sprintf("leader=%t", isLeader), func(t *testing.T) {
+			provider := settingsProvider{
+				settings: newStoreBackedClient(t, store),
+				isLeader: func() bool { return isLeader },
+			}
+			assert.Nil(t, provider.SetDefaultFrom("help-url", "ui-url"))
+			assert.Nil(t, provider.SetDefaultFrom("ui-url", "server-url"))
+			assert.Nil(t, provider.SetDefaultFrom("docs-url", "missing-url"))
+
+			err := provider.SetAll(settingMap)
+			assert.Nil(t, err)
+
+			assert.Equal(t, "https://rancher.example.com", provider.getFallback("ui-url"))
+			assert.Equal(t, "https://rancher.example.com", provider.getFallback("help-url"))
+			assert.Equal(t, "docs-default", provider.getFallback("docs-url"))
+		})
+	}
+}
+
+func TestSetAllUnknownAfterCycles(t *testing.T) {
+	store := map[string]v3.Setting{
+		"known":   {ObjectMeta: metav1.ObjectMeta{Name: "known"}, Default: "default"},
+		"unknown": {ObjectMeta: metav1.ObjectMeta{Name: "unknown"}, Value: "unknown"},
+	}
+
+	provider := settingsProvider{
+		settings:           newStoreBackedClient(t, store),
+		unknownAfterCycles: 3,
+	}
+
+	settingMap := map[string]settings.Setting{
+		"known": settings.NewSetting("known", "default"),
+	}
+
+	for cycle := 1; cycle < 3; cycle++ {
+		err := provider.SetAll(settingMap)
+		assert.Nil(t, err)
+		assert.NotContains(t, store["unknown"].Labels, unknownSettingLabelKey, "cycle %d", cycle)
+		assert.Equal(t, strconv.Itoa(cycle), store["unknown"].Annotations[missingCyclesAnnotationKey])
+	}
+
+	result, err := provider.SetAllWithResult(settingMap)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, result.LabeledUnknown)
+	assert.Equal(t, "true", store["unknown"].Labels[unknownSettingLabelKey])
+	assert.NotContains(t, store["unknown"].Annotations, missingCyclesAnnotationKey)
+	assert.NotContains(t, store["known"].Labels, unknownSettingLabelKey)
+	assert.NotContains(t, store["known"].Annotations, missingCyclesAnnotationKey)
+}
+
+func TestSetAllUnknownImmediatelyByDefault(t *testing.T) {
+	store := map[string]v3.Setting{
+		"unknown": {ObjectMeta: metav1.ObjectMeta{Name: "unknown"}, Value: "unknown"},
+	}
+
+	provider := newSettingsProvider(newStoreBackedClient(t, store), nil, Options{})
+
+	result, err := provider.SetAllWithResult(map[string]settings.Setting{
+		"known": settings.NewSetting("known", "default"),
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, result.LabeledUnknown)
+	assert.Equal(t, "true", store["unknown"].Labels[unknownSettingLabelKey])
+	assert.NotContains(t, store["unknown"].Annotations, missingCyclesAnnotationKey)
+}
+
+func TestSetAllUnknownAfterCyclesResetsWhenKnownAgain(t *testing.T) {
+	store := map[string]v3.Setting{
+		"flapping": {
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "flapping",
+				Annotations: map[string]string{missingCyclesAnnotationKey: "2"},
+			},
+		},
+	}
+
+	provider := newSettingsProvider(newStoreBackedClient(t, store), nil, Options{UnknownAfterCycles: 3})
+
+	err := provider.SetAll(map[string]settings.Setting{
+		"flapping": settings.NewSetting("flapping", "default"),
+	})
+	assert.Nil(t, err)
+	assert.NotContains(t, store["flapping"].Annotations, missingCyclesAnnotationKey)
+	assert.NotContains(t, store["flapping"].Labels, unknownSettingLabelKey)
+}
+
+func TestSetAllEnvRemoved(t *testing.T) {
+	newStore := func() map[string]v3.Setting {
+		return map[string]v3.Setting{
+			"from-env": {ObjectMeta: metav1.ObjectMeta{Name: "from-env"}, Value: "env-value", Default: "default", Source: "env"},
+		}
+	}
+	settingMap := map[string]settings.Setting{
+		"from-env": settings.NewSetting("from-env", "default"),
+	}
+
+	// By default the value set by the env var is kept.
+	store := newStore()
+	provider := settingsProvider{
+		settings: newStoreBackedClient(t, store),
+	}
+
+	err := provider.SetAll(settingMap)
+	assert.Nil(t, err)
+	assert.Equal(t, "", store["from-env"].Source)
+	assert.Equal(t, "env-value", store["from-env"].Value)
+	assert.Equal(t, "env-value", provider.getFallback("from-env"))
+
+	// The value is reset to the default if configured.
+	store = newStore()
+	resettingProvider := newSettingsProvider(newStoreBackedClient(t, store), nil, Options{ResetValueOnEnvRemoval: true})
+
+	err = resettingProvider.SetAll(settingMap)
+	assert.Nil(t, err)
+	assert.Equal(t, "", store["from-env"].Source)
+	assert.Equal(t, "", store["from-env"].Value)
+	assert.Equal(t, "env-value", store["from-env"].Annotations[previousValueAnnotationKey])
+	assert.Equal(t, "default", resettingProvider.getFallback("from-env"))
+}
+
+func TestSetDefaultFrom(t *testing.T) {
+	store := map[string]v3.Setting{
+		"server-url": {ObjectMeta: metav1.ObjectMeta{Name: "server-url"}, Value: "https://rancher.example.com"},
+		"ui-url":     {ObjectMeta: metav1.ObjectMeta{Name: "ui-url"}},
+		"help-url":   {ObjectMeta: metav1.ObjectMeta{Name: "help-url"}},
+		"docs-url":   {ObjectMeta: metav1.ObjectMeta{Name: "docs-url"}, Default: "https://docs.example.com"},
+		"set-url":    {ObjectMeta: metav1.ObjectMeta{Name: "set-url"}, Value: "https://set.example.com"},
+	}
+
+	// SetProvider passes the configuration registered on the settings to the provider.
+	var provider settings.ConfigurableProvider = &settingsProvider{
+		settings:     newStoreBackedClient(t, store),
+		settingCache: newStoreBackedCache(t, store),
+	}
+
+	assert.Nil(t, provider.SetDefaultFrom("ui-url", "server-url"))
+	assert.Nil(t, provider.SetDefaultFrom("help-url", "ui-url"))
+	assert.Nil(t, provider.SetDefaultFrom("docs-url", "missing-url"))
+	assert.Nil(t, provider.SetDefaultFrom("set-url", "server-url"))
+
+	assert.Equal(t, "https://rancher.example.com", provider.Get("ui-url"))
+	assert.Equal(t, "https://rancher.example.com", provider.Get("help-url"))
+	assert.Equal(t, "https://docs.example.com", provider.Get("docs-url"), "a missing reference should fall back to the setting's own default")
+	assert.Equal(t, "https://set.example.com", provider.Get("set-url"), "a stored value should take precedence over the chain")
+
+	t.Setenv(settings.GetEnvKey("ui-url"), "https://ui.example.com")
+	assert.Equal(t, "https://ui.example.com", provider.Get("ui-url"))
+	assert.Equal(t, "https://ui.example.com", provider.Get("help-url"))
+}
+
+func TestSetDefaultFromCycle(t *testing.T) {
+	provider := settingsProvider{}
+
+	assert.Nil(t, provider.SetDefaultFrom("a", "b"))
+	assert.Nil(t, provider.SetDefaultFrom("b", "c"))
+
+	err := provider.SetDefaultFrom("c", "a")
+	assert.ErrorContains(t, err, "c -> a -> b -> c")
+
+	err = provider.SetDefaultFrom("d", "d")
+	assert.ErrorContains(t, err, "d -> d")
+
+	assert.Equal(t, map[string]string{"a": "b", "b": "c"}, provider.defaultsFrom)
+}
+
+func TestSetAllRecreatesDeletedSetting(t *testing.T) {
+	store := map[string]v3.Setting{
+		"kept": {ObjectMeta: metav1.ObjectMeta{Name: "kept", ResourceVersion: "1"}, Value: "admin-value", Default: "default"},
+	}
+	settingMap := map[string]settings.Setting{
+		"kept":    settings.NewSetting("kept", "default"),
+		"deleted": settings.NewSetting("deleted", "deleted-default"),
+	}
+
+	provider := settingsProvider{
+		settings: newStoreBackedClient(t, store),
+	}
+
+	err := provider.SetAll(settingMap)
+	assert.Nil(t, err)
+
+	recreated, ok := store["deleted"]
+	assert.True(t, ok, "deleted setting should be recreated")
+	assert.Equal(t, "deleted-default", recreated.Default)
+	assert.Equal(t, "", recreated.Value)
+	assert.Equal(t, "", recreated.Source)
+	assert.Equal(t, "deleted-default", provider.getFallback("deleted"))
+
+	assert.Equal(t, "admin-value", store["kept"].Value, "existing settings should be left untouched")
+}
+
+func TestSetAllSteadyStateListsOnce(t *testing.T) {
+	store := map[string]v3.Setting{
+		"a": {ObjectMeta: metav1.ObjectMeta{Name: "a"}, Value: "admin-value", Default: "default"},
+		"b": {ObjectMeta: metav1.ObjectMeta{Name: "b"}, Default: "default"},
+	}
+	settingMap := map[string]settings.Setting{
+		"a": settings.NewSetting("a", "default"),
+		"b": settings.NewSetting("b", "default"),
+	}
+
+	client := fake.NewMockNonNamespacedControllerInterface[*v3.Setting, *v3.SettingList](gomock.NewController(t))
+	client.EXPECT().List(gomock.Any()).DoAndReturn(func(opts metav1.ListOptions) (*v3.SettingList, error) {
+		var items []v3.Setting
+		for _, setting := range store {
+			items = append(items, setting)
+		}
+
+		return &v3.SettingList{Items: items}, nil
+	}).Times(1)
+	client.EXPECT().Get(gomock.Any(), gomock.Any()).Times(0)
+	client.EXPECT().Create(gomock.Any()).Times(0)
+	client.EXPECT().Update(gomock.Any()).Times(0)
+
+	provider := settingsProvider{
+		settings: client,
+	}
+
+	err := provider.SetAll(settingMap)
+	assert.Nil(t, err)
+	assert.Equal(t, "admin-value", provider.getFallback("a"))
+	assert.Equal(t, "default", provider.getFallback("b"))
+}
+
+func BenchmarkSetAll(b *testing.B) {
+	const settingCount = 1000
+
+	store := map[string]v3.Setting{}
+	settingMap := map[string]settings.Setting{}
+	for i := 0; i < settingCount; i++ {
+		name := fmt.Sprintf("setting%d", i)
+		store[name] = v3.Setting{ObjectMeta: metav1.ObjectMeta{Name: name, ResourceVersion: "1"}, Default: "default"}
+		settingMap[name] = settings.NewSetting(name, "default")
+	}
+
+	provider := settingsProvider{
+		settings: newStoreBackedClient(b, store),
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := provider.SetAll(settingMap); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestSetAllUnknownLabelIsIdempotent(t *testing.T) {
+	store := map[string]v3.Setting{
+		"unknown1": {ObjectMeta: metav1.ObjectMeta{Name: "unknown1", ResourceVersion: "1"}, Value: "unknown"},
+		"unknown2": {ObjectMeta: metav1.ObjectMeta{Name: "unknown2", ResourceVersion: "1"}, Value: "unknown"},
+	}
+
+	client := fake.NewMockNonNamespacedControllerInterface[*v3.Setting, *v3.SettingList](gomock.NewController(t))
+	client.EXPECT().List(gomock.Any()).DoAndReturn(func(opts metav1.ListOptions) (*v3.SettingList, error) {
+		var items []v3.Setting
+		for _, setting := range store {
+			items = append(items, *setting.DeepCopy())
+		}
+
+		return &v3.SettingList{Items: items}, nil
+	}).Times(2)
+
+	failUnknown2 := true
+	updates := map[string]int{}
+	client.EXPECT().Update(gomock.Any()).DoAndReturn(func(setting *v3.Setting) (*v3.Setting, error) {
+		if setting.Name == "unknown2" && failUnknown2 {
+			return nil, fmt.Errorf("some error")
+		}
+
+		updates[setting.Name]++
+		store[setting.Name] = *setting.DeepCopy()
+		return setting, nil
+	}).AnyTimes()
+
+	provider := settingsProvider{
+		settings: client,
+	}
+
+	// The first cleanup fails after labeling unknown1.
+	err := provider.SetAll(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "true", store["unknown1"].Labels[unknownSettingLabelKey])
+	assert.NotContains(t, store["unknown2"].Labels, unknownSettingLabelKey)
+
+	failUnknown2 = false
+	err = provider.SetAll(nil)
+	assert.Nil(t, err)
+
+	for _, name := range []string{"unknown1", "unknown2"} {
+		assert.Equal(t, map[string]string{unknownSettingLabelKey: "true"}, store[name].Labels, name)
+		assert.Equal(t, 1, updates[name], "%s should be labeled exactly once", name)
+	}
+}
+
+func TestSetAllWithoutSettings(t *testing.T) {
+	for name, settingMap := range map[string]map[string]settings.Setting{
+		"nil":   nil,
+		"empty": {},
+	} {
+		t.Run(name, func(t *testing.T) {
+			store := map[string]v3.Setting{
+				"unknown":  {ObjectMeta: metav1.ObjectMeta{Name: "unknown", ResourceVersion: "1"}, Value: "unknown"},
+				"existing": {ObjectMeta: metav1.ObjectMeta{Name: "existing", ResourceVersion: "1"}, Value: "admin-value", Default: "default", Source: "env"},
+			}
+
+			client := fake.NewMockNonNamespacedControllerInterface[*v3.Setting, *v3.SettingList](gomock.NewController(t))
+			client.EXPECT().List(gomock.Any()).DoAndReturn(func(opts metav1.ListOptions) (*v3.SettingList, error) {
+				var items []v3.Setting
+				for _, setting := range store {
+					items = append(items, *setting.DeepCopy())
+				}
+
+				return &v3.SettingList{Items: items}, nil
+			}).Times(1)
+			client.EXPECT().Create(gomock.Any()).Times(0)
+			client.EXPECT().Update(gomock.Any()).DoAndReturn(func(setting *v3.Setting) (*v3.Setting, error) {
+				store[setting.Name] = *setting.DeepCopy()
+				return setting, nil
+			}).AnyTimes()
+
+			provider := settingsProvider{
+				settings: client,
+				fallback: map[string]string{"existing": "admin-value"},
+			}
+
+			assert.NotPanics(t, func() {
+				err := provider.SetAll(settingMap)
+				assert.Nil(t, err)
+			})
+
+			for _, name := range []string{"unknown", "existing"} {
+				assert.Equal(t, "true", store[name].Labels[unknownSettingLabelKey], name)
+			}
+
+			existing := store["existing"]
+			assert.Equal(t, "admin-value", existing.Value)
+			assert.Equal(t, "default", existing.Default)
+			assert.Equal(t, "env", existing.Source)
+			assert.Equal(t, "admin-value", provider.getFallback("existing"))
+		})
+	}
+}
+
+func TestSetAllMaxSize(t *testing.T) {
+	store := map[string]v3.Setting{
+		"over-limit": {ObjectMeta: metav1.ObjectMeta{Name: "over-limit", ResourceVersion: "1"}, Value: "prior", Default: "default", Source: "env"},
+		"at-limit":   {ObjectMeta: metav1.ObjectMeta{Name: "at-limit", ResourceVersion: "1"}, Value: "prior", Default: "default"},
+	}
+	settingMap := map[string]settings.Setting{
+		"over-limit": settings.NewSetting("over-limit", "new-default"),
+		"at-limit":   settings.NewSetting("at-limit", "default"),
+		"new":        settings.NewSetting("new", "default"),
+	}
+
+	provider := settingsProvider{
+		settings: newStoreBackedClient(t, store),
+	}
+	provider.SetMaxSize("over-limit", 8)
+	provider.SetMaxSize("at-limit", 8)
+	provider.SetMaxSize("new", 8)
+
+	t.Setenv(settings.GetEnvKey("over-limit"), "123456789")
+	t.Setenv(settings.GetEnvKey("at-limit"), "12345678")
+	t.Setenv(settings.GetEnvKey("new"), "123456789")
+
+	err := provider.SetAll(settingMap)
+	assert.ErrorContains(t, err, "value of setting over-limit from env var CATTLE_OVER_LIMIT is 9 bytes, exceeding the limit of 8 bytes")
+	assert.ErrorContains(t, err, "value of setting new from env var CATTLE_NEW is 9 bytes")
+	assert.NotContains(t, err.Error(), "at-limit")
+
+	overLimit := store["over-limit"]
+	assert.Equal(t, "prior", overLimit.Value)
+	assert.Equal(t, "env", overLimit.Source)
+	assert.Equal(t, "new-default", overLimit.Default, "the default should still be reconciled")
+	assert.Equal(t, "prior", provider.getFallback("over-limit"))
+
+	assert.Equal(t, "12345678", store["at-limit"].Value)
+	assert.Equal(t, "12345678", provider.getFallback("at-limit"))
+
+	assert.Equal(t, "", store["new"].Value)
+	assert.Equal(t, "default", provider.getFallback("new"))
+}
+
+func TestSetAllCustomEnvKey(t *testing.T) {
+	store := map[string]v3.Setting{}
+	settingMap := map[string]settings.Setting{
+		"server-url": settings.NewSetting("server-url", ""),
+		"ui-url":     settings.NewSetting("ui-url", "default"),
+	}
+
+	provider := settingsProvider{
+		settings: newStoreBackedClient(t, store),
+	}
+	provider.SetEnvKey("server-url", "RANCHER_SERVER_URL")
+	provider.SetEnvKey("ui-url", "RANCHER_UI_URL")
+
+	t.Setenv("RANCHER_SERVER_URL", "https://rancher.example.com")
+	t.Setenv(settings.GetEnvKey("server-url"), "https://ignored.example.com")
+	t.Setenv(settings.GetEnvKey("ui-url"), "https://ignored.example.com")
+
+	err := provider.SetAll(settingMap)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "https://rancher.example.com", store["server-url"].Value)
+	assert.Equal(t, "env", store["server-url"].Source)
+	assert.Equal(t, "https://rancher.example.com", provider.getFallback("server-url"))
+
+	assert.Equal(t, "", store["ui-url"].Value, "the env var derived from the name should be ignored")
+	assert.Equal(t, "default", provider.getFallback("ui-url"))
+}
+
+func TestSetAllReconcileOrder(t *testing.T) {
+	client := fake.NewMockNonNamespacedControllerInterface[*v3.Setting, *v3.SettingList](gomock.NewController(t))
+	client.EXPECT().List(gomock.Any()).Return(&v3.SettingList{}, nil).AnyTimes()
+
+	var created []string
+	client.EXPECT().Create(gomock.Any()).DoAndReturn(func(setting *v3.Setting) (*v3.Setting, error) {
+		created = append(created, setting.Name)
+		return setting, nil
+	}).AnyTimes()
+
+	settingMap := map[string]settings.Setting{}
+	for _, name := range []string{"ui-url", "a-derived", "server-url", "b", "a"} {
+		settingMap[name] = settings.NewSetting(name, "default")
+	}
+
+	provider := settingsProvider{
+		settings: client,
+	}
+	assert.Nil(t, provider.SetDefaultFrom("a-derived", "ui-url"))
+	assert.Nil(t, provider.SetDefaultFrom("ui-url", "server-url"))
+	assert.Nil(t, provider.SetDefaultFrom("b", "not-reconciled"))
+
+	want := []string{"a", "server-url", "ui-url", "a-derived", "b"}
+	for i := 0; i < 5; i++ {
+		created = nil
+		err := provider.SetAll(settingMap)
+		assert.Nil(t, err)
+		assert.Equal(t, want, created)
+	}
+}
+
+func TestSetAllLeaderElection(t *testing.T) {
+	tests := []struct {
+		name         string
+		isLeader     bool
+		wantStore    map[string]string
+		wantFallback map[string]string
+	}{
+		{
+			name:         "leader",
+			isLeader:     true,
+			wantStore:    map[string]string{"existing": "env-value", "new": "", "stale": "admin-value"},
+			wantFallback: map[string]string{"existing": "env-value", "new": "new-default"},
+		},
+		{
+			name:         "non-leader",
+			isLeader:     false,
+			wantStore:    map[string]string{"existing": "admin-value", "stale": "admin-value"},
+			wantFallback: map[string]string{"existing": "env-value", "new": "new-default"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := map[string]v3.Setting{
+				"existing": {ObjectMeta: metav1.ObjectMeta{Name: "existing", ResourceVersion: "1"}, Value: "admin-value", Default: "default"},
+				"stale":    {ObjectMeta: metav1.ObjectMeta{Name: "stale", ResourceVersion: "1"}, Value: "admin-value"},
+			}
+			settingMap := map[string]settings.Setting{
+				"existing": settings.NewSetting("existing", "default"),
+				"new":      settings.NewSetting("new", "new-default"),
+			}
+
+			provider := settingsProvider{
+				settings: newStoreBackedClient(t, store),
+				isLeader: func() bool { return test.isLeader },
+			}
+			t.Setenv(settings.GetEnvKey("existing"), "env-value")
+
+			err := provider.SetAll(settingMap)
+			assert.Nil(t, err)
+
+			values := map[string]string{}
+			for name, setting := range store {
+				values[name] = setting.Value
+			}
+			assert.Equal(t, test.wantStore, values)
+			assert.Equal(t, test.isLeader, store["stale"].Labels[unknownSettingLabelKey] == "true", "only the leader should mark settings as unknown")
+			for name, want := range test.wantFallback {
+				assert.Equal(t, want, provider.getFallback(name))
+			}
+		})
+	}
+}
+
+func TestSetAllReconcilesOnLeader(t *testing.T) {
+	store := map[string]v3.Setting{
+		"existing": {ObjectMeta: metav1.ObjectMeta{Name: "existing", ResourceVersion: "1"}, Default: "old-default"},
+		"stale":    {ObjectMeta: metav1.ObjectMeta{Name: "stale", ResourceVersion: "1"}},
+	}
+	settingMap := map[string]settings.Setting{
+		"existing": settings.NewSetting("existing", "default"),
+		"new":      settings.NewSetting("new", "default"),
+	}
+
+	var onLeader func(ctx context.Context) error
+	provider := newSettingsProvider(newStoreBackedClient(t, store), nil, Options{
+		OnLeader: func(f func(ctx context.Context) error) { onLeader = f },
+	})
+	require.NotNil(t, onLeader)
+	provider.settingCache = newStoreBackedCache(t, store)
+
+	result, err := provider.SetAllWithResult(settingMap)
+	assert.Nil(t, err)
+	assert.Equal(t, settings.SetAllResult{}, result, "a replica that is not the leader yet should not write settings")
+	assert.Equal(t, "old-default", store["existing"].Default)
+	assert.NotContains(t, store, "new")
+	assert.NotContains(t, store["stale"].Labels, unknownSettingLabelKey)
+	assert.Equal(t, "default", provider.Get("existing"), "the default of SetAll should be used over the outdated stored default")
+	assert.Equal(t, "default", provider.Get("new"))
+
+	assert.Nil(t, onLeader(context.Background()))
+	assert.Equal(t, "default", store["existing"].Default)
+	assert.Equal(t, "default", store["new"].Default)
+	assert.Equal(t, "true", store["stale"].Labels[unknownSettingLabelKey])
+	assert.Equal(t, "default", provider.Get("existing"))
+}
+
+func TestSetAllResult(t *testing.T) {
+	store := map[string]v3.Setting{
+		"changed":   {ObjectMeta: metav1.ObjectMeta{Name: "changed", ResourceVersion: "1"}, Default: "old-default"},
+		"unchanged": {ObjectMeta: metav1.ObjectMeta{Name: "unchanged", ResourceVersion: "1"}, Default: "default"},
+		"from-env":  {ObjectMeta: metav1.ObjectMeta{Name: "from-env", ResourceVersion: "1"}, Default: "default"},
+		"removed":   {ObjectMeta: metav1.ObjectMeta{Name: "removed", ResourceVersion: "1"}},
+	}
+	settingMap := map[string]settings.Setting{
+		"new":       settings.NewSetting("new", "default"),
+		"changed":   settings.NewSetting("changed", "new-default"),
+		"unchanged": settings.NewSetting("unchanged", "default"),
+		"from-env":  settings.NewSetting("from-env", "default"),
+	}
+
+	provider := settingsProvider{
+		settings: newStoreBackedClient(t, store),
+	}
+	t.Setenv(settings.GetEnvKey("from-env"), "env-value")
+
+	result, err := provider.SetAllWithResult(settingMap)
+	assert.Nil(t, err)
+	assert.Equal(t, settings.SetAllResult{Created: 1, Updated: 2, Unchanged: 1, LabeledUnknown: 1}, result)
+
+	result, err = provider.SetAllWithResult(settingMap)
+	assert.Nil(t, err)
+	assert.Equal(t, settings.SetAllResult{Unchanged: 4}, result, "a reconcile without changes should not write")
+}
+
+func TestSetAllUnknownLabelForbidden(t *testing.T) {
+	store := map[string]v3.Setting{
+		"known":     {ObjectMeta: metav1.ObjectMeta{Name: "known", ResourceVersion: "1"}, Default: "old-default"},
+		"unknown-1": {ObjectMeta: metav1.ObjectMeta{Name: "unknown-1", ResourceVersion: "1"}},
+		"unknown-2": {ObjectMeta: metav1.ObjectMeta{Name: "unknown-2", ResourceVersion: "1"}},
+	}
+	settingMap := map[string]settings.Setting{
+		"known": settings.NewSetting("known", "new-default"),
+		"new":   settings.NewSetting("new", "default"),
+	}
+
+	groupResource := schema.GroupResource{
+		Group:    management.GroupName,
+		Resource: v3.SettingResourceName,
+	}
+	client := fake.NewMockNonNamespacedControllerInterface[*v3.Setting, *v3.SettingList](gomock.NewController(t))
+	storeClient := newStoreBackedClient(t, store)
+
+	client.EXPECT().List(gomock.Any()).DoAndReturn(storeClient.List).AnyTimes()
+	client.EXPECT().Get(gomock.Any(), gomock.Any()).DoAndReturn(storeClient.Get).AnyTimes()
+	client.EXPECT().Create(gomock.Any()).DoAndReturn(storeClient.Create).AnyTimes()
+	labelAttempts := 0
+	client.EXPECT().Update(gomock.Any()).DoAndReturn(func(setting *v3.Setting) (*v3.Setting, error) {
+		if _, ok := setting.Labels[unknownSettingLabelKey]; ok {
+			labelAttempts++
+			return nil, apierrors.NewForbidden(groupResource, setting.Name, fmt.Errorf("cannot update label"))
+		}
+
+		return storeClient.Update(setting)
+	}).AnyTimes()
+
+	provider := settingsProvider{
+		settings: client,
+	}
+
+	result, err := provider.SetAllWithResult(settingMap)
+	assert.Nil(t, err)
+	assert.Equal(t, settings.SetAllResult{Created: 1, Updated: 1}, result)
+	assert.Equal(t, "new-default", store["known"].Default)
+	assert.Equal(t, "default", store["new"].Default)
+	assert.Equal(t, 1, labelAttempts, "the cleanup should stop after the first forbidden label")
+	for _, name := range []string{"unknown-1", "unknown-2"} {
+		assert.Empty(t, store[name].Labels[unknownSettingLabelKey])
+	}
+}
+
+// SimulateDowngrade reconciles the settings known before a downgrade of Rancher, then those known after it, and returns
+// the result of the second reconcile. Settings only known before the downgrade were added by the newer version, and
+// settings in both maps may have a different default in each.
+func SimulateDowngrade(provider *settingsProvider, oldMap, newMap map[string]settings.Setting) (settings.SetAllResult, error) {
+	if err := provider.SetAll(oldMap); err != nil {
+		return settings.SetAllResult{}, fmt.Errorf("error reconciling settings before the downgrade: %w", err)
+	}
+
+	return provider.SetAllWithResult(newMap)
+}
+
+func TestSetAllDowngrade(t *testing.T) {
+	oldMap := map[string]settings.Setting{
+		"reverted":   settings.NewSetting("reverted", "new-default"),
+		"admin-set":  settings.NewSetting("admin-set", "new-default"),
+		"added":      settings.NewSetting("added", "default"),
+		"unchanged":  settings.NewSetting("unchanged", "default"),
+		"admin-kept": settings.NewSetting("admin-kept", "default"),
+	}
+	newMap := map[string]settings.Setting{
+		"reverted":  settings.NewSetting("reverted", "old-default"),
+		"admin-set": settings.NewSetting("admin-set", "old-default"),
+		"unchanged": settings.NewSetting("unchanged", "default"),
+	}
+
+	store := map[string]v3.Setting{
+		"admin-set":  {ObjectMeta: metav1.ObjectMeta{Name: "admin-set", ResourceVersion: "1"}, Value: "admin-value", Default: "new-default"},
+		"admin-kept": {ObjectMeta: metav1.ObjectMeta{Name: "admin-kept", ResourceVersion: "1"}, Value: "admin-value", Default: "default"},
+	}
+	provider := &settingsProvider{
+		settings:           newStoreBackedClient(t, store),
+		unknownAfterCycles: 3,
+	}
+
+	result, err := SimulateDowngrade(provider, oldMap, newMap)
+	assert.Nil(t, err)
+	assert.Equal(t, settings.SetAllResult{Updated: 2, Unchanged: 1}, result)
+
+	assert.Equal(t, "old-default", store["reverted"].Default)
+	assert.Equal(t, "", store["reverted"].Value)
+	assert.Equal(t, "old-default", provider.getFallback("reverted"))
+
+	assert.Equal(t, "old-default", store["admin-set"].Default)
+	assert.Equal(t, "admin-value", store["admin-set"].Value, "the admin value should survive the reverted default")
+	assert.Equal(t, "admin-value", provider.getFallback("admin-set"))
+
+	for _, name := range []string{"added", "admin-kept"} {
+		assert.NotContains(t, store[name].Labels, unknownSettingLabelKey, "%s should not be labeled before it was missing for enough cycles", name)
+		assert.Equal(t, "1", store[name].Annotations[missingCyclesAnnotationKey])
+	}
+
+	err = provider.SetAll(newMap)
+	assert.Nil(t, err)
+	result, err = provider.SetAllWithResult(newMap)
+	assert.Nil(t, err)
+	assert.Equal(t, settings.SetAllResult{Unchanged: 3, LabeledUnknown: 2}, result)
+
+	for _, name := range []string{"added", "admin-kept"} {
+		assert.Equal(t, "true", store[name].Labels[unknownSettingLabelKey], "%s should be labeled unknown once it was missing for enough cycles", name)
+	}
+	assert.Equal(t, "admin-value", store["admin-kept"].Value, "labeling a setting unknown should keep its value")
+}
+
+func TestSetAllRepairsEmptyDefault(t *testing.T) {
+	store := map[string]v3.Setting{
+		"malformed":     {ObjectMeta: metav1.ObjectMeta{Name: "malformed", ResourceVersion: "1"}},
+		"malformed-set": {ObjectMeta: metav1.ObjectMeta{Name: "malformed-set", ResourceVersion: "1"}, Value: "admin-value"},
+		"healthy":       {ObjectMeta: metav1.ObjectMeta{Name: "healthy", ResourceVersion: "1"}, Default: "old-default"},
+	}
+	settingMap := map[string]settings.Setting{
+		"malformed":     settings.NewSetting("malformed", "default"),
+		"malformed-set": settings.NewSetting("malformed-set", "default"),
+		"healthy":       settings.NewSetting("healthy", "new-default"),
+		"new":           settings.NewSetting("new", "default"),
+	}
+
+	provider := settingsProvider{
+		settings: newStoreBackedClient(t, store),
+	}
+
+	result, err := provider.SetAllWithResult(settingMap)
+	assert.Nil(t, err)
+	assert.Equal(t, settings.SetAllResult{Created: 1, Updated: 3}, result)
+
+	assert.Equal(t, "default", store["malformed"].Default)
+	assert.Equal(t, "default", provider.getFallback("malformed"))
+
+	assert.Equal(t, "default", store["malformed-set"].Default)
+	assert.Equal(t, "admin-value", store["malformed-set"].Value)
+	assert.Equal(t, "admin-value", provider.getFallback("malformed-set"))
+
+	assert.Equal(t, "new-default", store["healthy"].Default)
+	assert.Contains(t, store, "new")
+}
+
+func TestSetAllListError(t *testing.T) {
+	listErr := apierrors.NewInternalError(fmt.Errorf("etcd is unavailable"))
+
+	client := fake.NewMockNonNamespacedControllerInterface[*v3.Setting, *v3.SettingList](gomock.NewController(t))
+	client.EXPECT().List(gomock.Any()).Return(nil, listErr).Times(1)
+	client.EXPECT().Get(gomock.Any(), gomock.Any()).Times(0)
+	client.EXPECT().Create(gomock.Any()).Times(0)
+	client.EXPECT().Update(gomock.Any()).Times(0)
+
+	provider := settingsProvider{
+		settings: client,
+		fallback: map[string]string{"known": "value"},
+	}
+
+	err := provider.SetAll(map[string]settings.Setting{
+		"known": settings.NewSetting("known", "default"),
+	})
+	assert.ErrorIs(t, err, listErr)
+	assert.ErrorContains(t, err, "error listing settings")
+	assert.Equal(t, "value", provider.getFallback("known"), "the fallback values should be kept")
+}
+
+func TestSetAllPreservesMetadata(t *testing.T) {
+	store := map[string]v3.Setting{
+		"known": {
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "known",
+				ResourceVersion: "1",
+				Labels:          map[string]string{"team": "platform"},
+				Annotations:     map[string]string{"example.com/owner": "admin"},
+			},
+			Default: "old-default",
+		},
+		"unknown": {
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "unknown",
+				ResourceVersion: "1",
+				Labels:          map[string]string{"team": "platform"},
+				Annotations:     map[string]string{"example.com/owner": "admin"},
+			},
+			Value: "unknown",
+		},
+	}
+	settingMap := map[string]settings.Setting{
+		"known": settings.NewSetting("known", "new-default"),
+	}
+
+	provider := settingsProvider{
+		settings: newStoreBackedClient(t, store),
+	}
+
+	err := provider.SetAll(settingMap)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "new-default", store["known"].Default)
+	assert.Equal(t, map[string]string{"team": "platform"}, store["known"].Labels)
+	assert.Equal(t, map[string]string{"example.com/owner": "admin"}, store["known"].Annotations)
+
+	assert.Equal(t, map[string]string{"team": "platform", unknownSettingLabelKey: "true"}, store["unknown"].Labels)
+	assert.Equal(t, map[string]string{"example.com/owner": "admin"}, store["unknown"].Annotations)
+}
+
+func TestSetAllRereadsStaleSettingOnConflict(t *testing.T) {
+	stale := v3.Setting{ObjectMeta: metav1.ObjectMeta{Name: "setting", ResourceVersion: "1"}, Default: "old-default"}
+	// An admin set a value after the provider listed the settings.
+	store := map[string]v3.Setting{
+		"setting": {ObjectMeta: metav1.ObjectMeta{Name: "setting", ResourceVersion: "2"}, Value: "admin-value", Default: "old-default"},
+	}
+	settingMap := map[string]settings.Setting{
+		"setting": settings.NewSetting("setting", "new-default"),
+	}
+
+	groupResource := schema.GroupResource{
+		Group:    management.GroupName,
+		Resource: v3.SettingResourceName,
+	}
+	client := fake.NewMockNonNamespacedControllerInterface[*v3.Setting, *v3.SettingList](gomock.NewController(t))
+	client.EXPECT().List(gomock.Any()).Return(&v3.SettingList{Items: []v3.Setting{stale}}, nil).Times(1)
+	client.EXPECT().Get("setting", gomock.Any()).DoAndReturn(func(name string, options metav1.GetOptions) (*v3.Setting, error) {
+		latest := store[name]
+		return latest.DeepCopy(), nil
+	}).Times(1)
+
+	var updatedVersions []string
+	client.EXPECT().Update(gomock.Any()).DoAndReturn(func(setting *v3.Setting) (*v3.Setting, error) {
+		updatedVersions = append(updatedVersions, setting.ResourceVersion)
+		if setting.ResourceVersion != store[setting.Name].ResourceVersion {
+			return nil, apierrors.NewConflict(groupResource, setting.Name, fmt.Errorf("stale resource version"))
+		}
+
+		setting = setting.DeepCopy()
+		setting.ResourceVersion = "3"
+		store[setting.Name] = *setting
+		return setting, nil
+	}).Times(2)
+
+	provider := settingsProvider{
+		settings: client,
+	}
+
+	err := provider.SetAll(settingMap)
+	assert.Nil(t, err)
+
+	assert.Equal(t, []string{"1", "2"}, updatedVersions, "the update should be retried with the re-read setting")
+	assert.Equal(t, "new-default", store["setting"].Default)
+	assert.Equal(t, "admin-value", store["setting"].Value, "the value set after the stale read should be kept")
+	assert.Equal(t, "admin-value", provider.getFallback("setting"))
+}
+
+func TestSetAllRetriesServiceUnavailable(t *testing.T) {
+	store := map[string]v3.Setting{
+		"known": {ObjectMeta: metav1.ObjectMeta{Name: "known"}, Default: "old-default"},
+	}
+	settingMap := map[string]settings.Setting{
+		"known": settings.NewSetting("known", "new-default"),
+		"new":   settings.NewSetting("new", "default"),
+	}
+
+	unavailable := apierrors.NewServiceUnavailable("etcdserver: leader changed")
+	calls := map[string]int{}
+	// failFirst fails the first n calls of the verb with ServiceUnavailable.
+	failFirst := func(verb string, n int) error {
+		calls[verb]++
+		if calls[verb] <= n {
+			return unavailable
+		}
+		return nil
+	}
+
+	client := fake.NewMockNonNamespacedControllerInterface[*v3.Setting, *v3.SettingList](gomock.NewController(t))
+	client.EXPECT().List(gomock.Any()).DoAndReturn(func(opts metav1.ListOptions) (*v3.SettingList, error) {
+		if err := failFirst("list", 2); err != nil {
+			return nil, err
+		}
+
+		var items []v3.Setting
+		for _, setting := range store {
+			items = append(items, *setting.DeepCopy())
+		}
+		return &v3.SettingList{Items: items}, nil
+	}).Times(3)
+	client.EXPECT().Get(gomock.Any(), gomock.Any()).DoAndReturn(func(name string, options metav1.GetOptions) (*v3.Setting, error) {
+		if err := failFirst("get", 1); err != nil {
+			return nil, err
+		}
+
+		setting, ok := store[name]
+		if !ok {
+			return nil, apierrors.NewNotFound(schema.GroupResource{}, name)
+		}
+		return setting.DeepCopy(), nil
+	}).AnyTimes()
+	client.EXPECT().Create(gomock.Any()).DoAndReturn(func(setting *v3.Setting) (*v3.Setting, error) {
+		if err := failFirst("create", 2); err != nil {
+			return nil, err
+		}
+
+		store[setting.Name] = *setting.DeepCopy()
+		return setting, nil
+	}).Times(3)
+	client.EXPECT().Update(gomock.Any()).DoAndReturn(func(setting *v3.Setting) (*v3.Setting, error) {
+		if err := failFirst("update", 2); err != nil {
+			return nil, err
+		}
+
+		store[setting.Name] = *setting.DeepCopy()
+		return setting, nil
+	}).Times(3)
+
+	provider := settingsProvider{
+		settings:           client,
+		unavailableBackoff: wait.Backoff{Steps: 5, Duration: time.Millisecond},
+	}
+
+	err := provider.SetAll(settingMap)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "new-default", store["known"].Default)
+	assert.Equal(t, "default", store["new"].Default)
+	assert.Equal(t, "default", provider.getFallback("new"))
+}
+
+func TestSetAllServiceUnavailableExhausted(t *testing.T) {
+	client := fake.NewMockNonNamespacedControllerInterface[*v3.Setting, *v3.SettingList](gomock.NewController(t))
+	client.EXPECT().List(gomock.Any()).Return(nil, apierrors.NewServiceUnavailable("etcdserver: request timed out")).Times(3)
+
+	provider := settingsProvider{
+		settings:           client,
+		unavailableBackoff: wait.Backoff{Steps: 3, Duration: time.Millisecond},
+	}
+
+	err := provider.SetAll(map[string]settings.Setting{"a": settings.NewSetting("a", "default")})
+	assert.True(t, apierrors.IsServiceUnavailable(err), "unexpected error: %v", err)
+}
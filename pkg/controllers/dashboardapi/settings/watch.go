@@ -0,0 +1,81 @@
+package settings
+
+import (
+	"sync"
+
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	managementcontrollers "github.com/rancher/rancher/pkg/generated/controllers/management.cattle.io/v3"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+)
+
+// SettingEvent is a change to a setting observed by Watch.
+type SettingEvent struct {
+	Type    watch.EventType
+	Setting *v3.Setting
+}
+
+// Watch registers an event handler on the setting controller's informer and returns a channel that receives an event
+// for every added, modified or deleted setting. The returned func removes the handler and closes the channel.
+func Watch(settingController managementcontrollers.SettingController) (<-chan SettingEvent, func(), error) {
+	informer := settingController.Informer()
+
+	events := make(chan SettingEvent)
+	done := make(chan struct{})
+
+	var (
+		mu      sync.RWMutex
+		stopped bool
+	)
+	send := func(eventType watch.EventType, obj interface{}) {
+		if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+			obj = tombstone.Obj
+		}
+		setting, ok := obj.(*v3.Setting)
+		if !ok {
+			return
+		}
+
+		mu.RLock()
+		defer mu.RUnlock()
+		if stopped {
+			return
+		}
+
+		select {
+		case events <- SettingEvent{Type: eventType, Setting: setting}:
+		case <-done:
+		}
+	}
+
+	registration, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			send(watch.Added, obj)
+		},
+		UpdateFunc: func(_, newObj interface{}) {
+			send(watch.Modified, newObj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			send(watch.Deleted, obj)
+		},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var once sync.Once
+	stop := func() {
+		once.Do(func() {
+			// Unblock any pending send before taking the write lock.
+			close(done)
+			_ = informer.RemoveEventHandler(registration)
+
+			mu.Lock()
+			defer mu.Unlock()
+			stopped = true
+			close(events)
+		})
+	}
+
+	return events, stop, nil
+}
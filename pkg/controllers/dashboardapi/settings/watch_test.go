@@ -0,0 +1,114 @@
+package settings
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/wrangler/v2/pkg/generic/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+)
+
+// fakeInformer is a SharedIndexInformer that only tracks event handlers so that tests can emit events directly.
+type fakeInformer struct {
+	cache.SharedIndexInformer
+	handlers map[*fakeRegistration]cache.ResourceEventHandler
+}
+
+type fakeRegistration struct{}
+
+func (r *fakeRegistration) HasSynced() bool { return true }
+
+func (f *fakeInformer) AddEventHandler(handler cache.ResourceEventHandler) (cache.ResourceEventHandlerRegistration, error) {
+	registration := &fakeRegistration{}
+	f.handlers[registration] = handler
+	return registration, nil
+}
+
+func (f *fakeInformer) RemoveEventHandler(registration cache.ResourceEventHandlerRegistration) error {
+	delete(f.handlers, registration.(*fakeRegistration))
+	return nil
+}
+
+func (f *fakeInformer) onlyHandler() cache.ResourceEventHandler {
+	for _, handler := range f.handlers {
+		return handler
+	}
+	return nil
+}
+
+func newTestSetting(name, value string) *v3.Setting {
+	return &v3.Setting{ObjectMeta: metav1.ObjectMeta{Name: name}, Value: value}
+}
+
+func TestWatch(t *testing.T) {
+	informer := &fakeInformer{handlers: map[*fakeRegistration]cache.ResourceEventHandler{}}
+	controller := fake.NewMockNonNamespacedControllerInterface[*v3.Setting, *v3.SettingList](gomock.NewController(t))
+	controller.EXPECT().Informer().Return(informer).AnyTimes()
+
+	events, stop, err := Watch(controller)
+	require.NoError(t, err)
+	require.Len(t, informer.handlers, 1)
+
+	handler := informer.onlyHandler()
+	go func() {
+		handler.OnAdd(newTestSetting("a", "1"), false)
+		handler.OnUpdate(newTestSetting("a", "1"), newTestSetting("a", "2"))
+		handler.OnDelete(newTestSetting("a", "2"))
+		handler.OnDelete(cache.DeletedFinalStateUnknown{Key: "b", Obj: newTestSetting("b", "3")})
+	}()
+
+	want := []SettingEvent{
+		{Type: watch.Added, Setting: newTestSetting("a", "1")},
+		{Type: watch.Modified, Setting: newTestSetting("a", "2")},
+		{Type: watch.Deleted, Setting: newTestSetting("a", "2")},
+		{Type: watch.Deleted, Setting: newTestSetting("b", "3")},
+	}
+	for _, wantEvent := range want {
+		select {
+		case event := <-events:
+			assert.Equal(t, wantEvent, event)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %s event", wantEvent.Type)
+		}
+	}
+
+	stop()
+	assert.Empty(t, informer.handlers, "stop should remove the event handler")
+
+	_, ok := <-events
+	assert.False(t, ok, "stop should close the events channel")
+
+	// Stopping twice must not panic.
+	stop()
+}
+
+func TestWatchStopUnblocksPendingSend(t *testing.T) {
+	informer := &fakeInformer{handlers: map[*fakeRegistration]cache.ResourceEventHandler{}}
+	controller := fake.NewMockNonNamespacedControllerInterface[*v3.Setting, *v3.SettingList](gomock.NewController(t))
+	controller.EXPECT().Informer().Return(informer).AnyTimes()
+
+	_, stop, err := Watch(controller)
+	require.NoError(t, err)
+
+	handler := informer.onlyHandler()
+	sent := make(chan struct{})
+	go func() {
+		// Nobody reads the events, so this blocks until the watch is stopped.
+		handler.OnAdd(newTestSetting("a", "1"), false)
+		close(sent)
+	}()
+
+	stop()
+
+	select {
+	case <-sent:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stop did not unblock the pending event")
+	}
+}
@@ -6,6 +6,7 @@
 	"fmt"
 	"os"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -27,6 +28,9 @@
 	releasePattern = regexp.MustCompile("^v[0-9]")
 	settings       = map[string]Setting{}
 	provider       Provider
+	defaultsFrom   = map[string]string{}
+	maxSizes       = map[string]int{}
+	envKeys        = map[string]string{}
 	InjectDefaults string
 
 	systemNamespaces = []string{
@@ -351,6 +355,28 @@ type Provider interface {
 	SetAll(settings map[string]Setting) error
 }
 
+// SetAllResult summarizes how a provider reconciled the settings, as returned by providers that support it alongside
+// SetAll, e.g. with a SetAllWithResult method.
+type SetAllResult struct {
+	// Created is the number of settings that were created.
+	Created int
+	// Updated is the number of settings that were updated to match their default or env var.
+	Updated int
+	// Unchanged is the number of settings that were already up to date.
+	Unchanged int
+	// LabeledUnknown is the number of settings that were marked as unknown because they are no longer known.
+	LabeledUnknown int
+}
+
+// ConfigurableProvider is a Provider that supports configuring how individual settings are resolved. The
+// configuration registered on the settings is passed to such a provider by SetProvider before its first SetAll.
+type ConfigurableProvider interface {
+	Provider
+	SetDefaultFrom(name, from string) error
+	SetMaxSize(name string, maxBytes int)
+	SetEnvKey(name, envKey string)
+}
+
 // Setting stores information about a specific server setting.
 type Setting struct {
 	Name     string
@@ -389,6 +415,36 @@ func (s Setting) Get() string {
 	return provider.Get(s.Name)
 }
 
+// SetDefaultFrom makes the setting default to the effective value of the from setting when it has no value and no env
+// var is set for it. It only takes effect with a ConfigurableProvider.
+func (s Setting) SetDefaultFrom(from Setting) error {
+	if p, ok := provider.(ConfigurableProvider); ok {
+		if err := p.SetDefaultFrom(s.Name, from.Name); err != nil {
+			return err
+		}
+	}
+	defaultsFrom[s.Name] = from.Name
+	return nil
+}
+
+// SetMaxSize limits the size of values applied to the setting from its env var to maxBytes. It only takes effect with
+// a ConfigurableProvider.
+func (s Setting) SetMaxSize(maxBytes int) {
+	if p, ok := provider.(ConfigurableProvider); ok {
+		p.SetMaxSize(s.Name, maxBytes)
+	}
+	maxSizes[s.Name] = maxBytes
+}
+
+// SetEnvKey makes the setting configurable by the env var envKey instead of the one returned by GetEnvKey. It only
+// takes effect with a ConfigurableProvider.
+func (s Setting) SetEnvKey(envKey string) {
+	if p, ok := provider.(ConfigurableProvider); ok {
+		p.SetEnvKey(s.Name, envKey)
+	}
+	envKeys[s.Name] = envKey
+}
+
 // GetInt will return the currently stored value of the setting as an integer.
 // If the stored value is not an integer then the default value will be returned as an integer.
 // If the default value is not an integer then the function will return 0
@@ -408,6 +464,11 @@ func (s Setting) GetInt() int {
 
 // SetProvider will set the given provider as the global provider for all settings.
 func SetProvider(p Provider) error {
+	if cp, ok := p.(ConfigurableProvider); ok {
+		if err := configure(cp); err != nil {
+			return err
+		}
+	}
 	if err := p.SetAll(settings); err != nil {
 		return err
 	}
@@ -415,6 +476,22 @@ func SetProvider(p Provider) error {
 	return nil
 }
 
+// configure passes the configuration registered on the settings to the provider.
+func configure(p ConfigurableProvider) error {
+	for name, from := range defaultsFrom {
+		if err := p.SetDefaultFrom(name, from); err != nil {
+			return err
+		}
+	}
+	for name, maxBytes := range maxSizes {
+		p.SetMaxSize(name, maxBytes)
+	}
+	for name, envKey := range envKeys {
+		p.SetEnvKey(name, envKey)
+	}
+	return nil
+}
+
 // NewSetting will create and store a new server setting.
 func NewSetting(name, def string) Setting {
 	s := Setting{
@@ -430,6 +507,32 @@ func GetEnvKey(key string) string {
 	return "CATTLE_" + strings.ToUpper(strings.Replace(key, "-", "_", -1))
 }
 
+// DetectEnvKeyCollisions returns the env keys that more than one of the given setting names map to, along with the
+// sorted names mapping to each. A value set for such an env key would silently apply to all of these settings.
+func DetectEnvKeyCollisions(names []string) map[string][]string {
+	namesByKey := map[string][]string{}
+	seen := map[string]bool{}
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		key := GetEnvKey(name)
+		namesByKey[key] = append(namesByKey[key], name)
+	}
+
+	collisions := map[string][]string{}
+	for key, keyNames := range namesByKey {
+		if len(keyNames) > 1 {
+			sort.Strings(keyNames)
+			collisions[key] = keyNames
+		}
+	}
+
+	return collisions
+}
+
 func getMetadataConfig() string {
 	branch := KDMBranch.Get()
 	data := map[string]interface{}{
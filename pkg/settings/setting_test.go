@@ -9,6 +9,7 @@
 
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	v1 "k8s.io/api/core/v1"
 )
 
@@ -186,3 +187,70 @@ func TestGetRancherVersion(t *testing.T) {
 		assert.Equal(t, value, result)
 	}
 }
+
+func TestDetectEnvKeyCollisions(t *testing.T) {
+	collisions := DetectEnvKeyCollisions([]string{"ui-url", "ui_url", "UI-URL", "server-url", "server-url"})
+	assert.Equal(t, map[string][]string{
+		"CATTLE_UI_URL": {"UI-URL", "ui-url", "ui_url"},
+	}, collisions)
+
+	assert.Empty(t, DetectEnvKeyCollisions([]string{"ui-url", "server-url", "server-version"}))
+
+	var names []string
+	for name := range settings {
+		names = append(names, name)
+	}
+	assert.Empty(t, DetectEnvKeyCollisions(names), "built-in settings should not share env keys")
+}
+
+// fakeConfigurableProvider records the configuration passed to it.
+type fakeConfigurableProvider struct {
+	Provider
+	defaultsFrom map[string]string
+	maxSizes     map[string]int
+	envKeys      map[string]string
+}
+
+func (f *fakeConfigurableProvider) SetAll(map[string]Setting) error {
+	return nil
+}
+
+func (f *fakeConfigurableProvider) SetDefaultFrom(name, from string) error {
+	f.defaultsFrom[name] = from
+	return nil
+}
+
+func (f *fakeConfigurableProvider) SetMaxSize(name string, maxBytes int) {
+	f.maxSizes[name] = maxBytes
+}
+
+func (f *fakeConfigurableProvider) SetEnvKey(name, envKey string) {
+	f.envKeys[name] = envKey
+}
+
+func TestSetProviderConfigures(t *testing.T) {
+	defer func(p Provider) { provider = p }(provider)
+	provider = nil
+
+	uiURL := NewSetting("test-ui-url", "")
+	caCerts := NewSetting("test-cacerts", "")
+	defer func() {
+		for _, name := range []string{uiURL.Name, caCerts.Name} {
+			delete(settings, name)
+			delete(defaultsFrom, name)
+			delete(maxSizes, name)
+			delete(envKeys, name)
+		}
+	}()
+
+	require.NoError(t, uiURL.SetDefaultFrom(ServerURL))
+	caCerts.SetMaxSize(1024)
+
+	fake := &fakeConfigurableProvider{defaultsFrom: map[string]string{}, maxSizes: map[string]int{}, envKeys: map[string]string{}}
+	require.NoError(t, SetProvider(fake))
+	assert.Equal(t, "server-url", fake.defaultsFrom["test-ui-url"])
+	assert.Equal(t, 1024, fake.maxSizes["test-cacerts"])
+
+	uiURL.SetEnvKey("TEST_UI_URL")
+	assert.Equal(t, "TEST_UI_URL", fake.envKeys["test-ui-url"], "configuration registered after SetProvider should be passed to the provider")
+}
@@ -0,0 +1,81 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	catalogv1 "github.com/rancher/rancher/pkg/apis/catalog.cattle.io/v1"
+	rancherClient "github.com/rancher/shepherd/clients/rancher"
+	"github.com/rancher/shepherd/extensions/charts"
+)
+
+// InstallApp installs the chart at the given version as an app named after the chart with `rancher app install`,
+// setting the given values. The rancher CLI installs into the cluster of its current context, which must be the
+// cluster with the given ID. Use VerifyAppInstalled to check that the app was deployed.
+func InstallApp(clusterID, chart, version string, values map[string]string) error {
+	if err := checkCurrentCluster(clusterID); err != nil {
+		return err
+	}
+
+	return installApp(RunCommand, chart, version, values)
+}
+
+// VerifyAppInstalled verifies through the charts extension that the app is installed in the namespace of the cluster,
+// was deployed and runs the chart at the given version.
+func VerifyAppInstalled(client *rancherClient.Client, clusterID, namespace, chart, version string) error {
+	status, err := charts.GetChartStatus(client, clusterID, namespace, chart)
+	if err != nil {
+		return err
+	}
+
+	return verifyAppInstalled(status, chart, version)
+}
+
+func installApp(run func(args ...string) (string, int, error), chart, version string, values map[string]string) error {
+	args := []string{"app", "install", "--no-prompt", "--version", version}
+
+	// Sort the values so that the command is the same on every run.
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		args = append(args, "--set", fmt.Sprintf("%s=%s", key, values[key]))
+	}
+
+	args = append(args, chart, chart)
+
+	output, code, err := run(args...)
+	if err != nil {
+		return fmt.Errorf("failed to install app %s %s with exit code %d: %s", chart, version, code, strings.TrimSpace(output))
+	}
+
+	return nil
+}
+
+func verifyAppInstalled(status *charts.ChartStatus, chart, version string) error {
+	if !status.IsAlreadyInstalled || status.ChartDetails == nil {
+		return fmt.Errorf("app %s is not installed", chart)
+	}
+
+	app := status.ChartDetails
+	if app.Spec.Info == nil || app.Spec.Info.Status != catalogv1.StatusDeployed {
+		state := "unknown"
+		if app.Spec.Info != nil {
+			state = string(app.Spec.Info.Status)
+		}
+		return fmt.Errorf("app %s is %s, expected %s", chart, state, catalogv1.StatusDeployed)
+	}
+
+	if app.Spec.Chart == nil || app.Spec.Chart.Metadata == nil || app.Spec.Chart.Metadata.Version != version {
+		installed := ""
+		if app.Spec.Chart != nil && app.Spec.Chart.Metadata != nil {
+			installed = app.Spec.Chart.Metadata.Version
+		}
+		return fmt.Errorf("app %s runs chart version %q, expected %q", chart, installed, version)
+	}
+
+	return nil
+}
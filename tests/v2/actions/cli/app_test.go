@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	catalogv1 "github.com/rancher/rancher/pkg/apis/catalog.cattle.io/v1"
+	"github.com/rancher/shepherd/extensions/charts"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstallApp(t *testing.T) {
+	var got []string
+	run := func(args ...string) (string, int, error) {
+		got = args
+		return "", 0, nil
+	}
+
+	err := installApp(run, "rancher-monitoring", "103.1.0", map[string]string{"prometheus.enabled": "true", "alertmanager.enabled": "false"})
+	assert.NoError(t, err)
+	assert.Equal(t, "app install --no-prompt --version 103.1.0 --set alertmanager.enabled=false --set prometheus.enabled=true rancher-monitoring rancher-monitoring", strings.Join(got, " "))
+}
+
+func TestInstallAppError(t *testing.T) {
+	run := func(args ...string) (string, int, error) {
+		return "chart rancher-monitoring version 1.0.0 not found\n", 1, errors.New("exit status 1")
+	}
+
+	err := installApp(run, "rancher-monitoring", "1.0.0", nil)
+	assert.EqualError(t, err, "failed to install app rancher-monitoring 1.0.0 with exit code 1: chart rancher-monitoring version 1.0.0 not found")
+}
+
+func TestVerifyAppInstalled(t *testing.T) {
+	newStatus := func(state catalogv1.Status, version string) *charts.ChartStatus {
+		return &charts.ChartStatus{
+			IsAlreadyInstalled: true,
+			ChartDetails: &catalogv1.App{
+				Spec: catalogv1.ReleaseSpec{
+					Info:  &catalogv1.Info{Status: state},
+					Chart: &catalogv1.Chart{Metadata: &catalogv1.Metadata{Name: "rancher-monitoring", Version: version}},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		status  *charts.ChartStatus
+		wantErr string
+	}{
+		{
+			name:   "deployed",
+			status: newStatus(catalogv1.StatusDeployed, "103.1.0"),
+		},
+		{
+			name:    "not installed",
+			status:  &charts.ChartStatus{},
+			wantErr: "app rancher-monitoring is not installed",
+		},
+		{
+			name:    "failed",
+			status:  newStatus(catalogv1.StatusFailed, "103.1.0"),
+			wantErr: "app rancher-monitoring is failed, expected deployed",
+		},
+		{
+			name:    "wrong version",
+			status:  newStatus(catalogv1.StatusDeployed, "102.0.0"),
+			wantErr: `app rancher-monitoring runs chart version "102.0.0", expected "103.1.0"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifyAppInstalled(tt.status, "rancher-monitoring", "103.1.0")
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+)
+
+var (
+	// authErrors are the messages the rancher CLI prints when the server rejects its token. Status codes are only
+	// matched in the forms the CLI prints them in, as bare digits also occur in ports, addresses and resource names.
+	authErrors = []string{"[401]", "status code 401", "unauthorized", "must authenticate", "token is expired", "invalid token"}
+
+	// proxyAuthErrors are the messages printed when a proxy rejects the request, which must not be taken for an
+	// authentication failure of the Rancher server.
+	proxyAuthErrors = []string{"[407]", "status code 407", "proxy authentication required"}
+)
+
+// ExpectAuthError runs a rancher CLI command that requires authentication and returns an error unless the command
+// failed because the server rejected the token, e.g. because it expired, as opposed to a network or proxy error.
+func ExpectAuthError(args ...string) error {
+	return expectAuthError(RunCommand, args...)
+}
+
+func expectAuthError(run func(args ...string) (string, int, error), args ...string) error {
+	output, _, err := run(args...)
+	if err == nil {
+		return fmt.Errorf("command %q succeeded but was expected to fail authentication", strings.Join(args, " "))
+	}
+	if !IsAuthError(output) {
+		return fmt.Errorf("command %q failed without an authentication error: %s", strings.Join(args, " "), output)
+	}
+
+	return nil
+}
+
+// IsAuthError returns true if the CLI output reports that the Rancher server rejected the token.
+func IsAuthError(output string) bool {
+	output = strings.ToLower(output)
+	for _, message := range proxyAuthErrors {
+		if strings.Contains(output, message) {
+			return false
+		}
+	}
+
+	for _, message := range authErrors {
+		if strings.Contains(output, message) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,66 @@
+package cli
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpectAuthError(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		err     error
+		wantErr string
+	}{
+		{
+			name:   "401 response",
+			output: "Bad response statusCode [401]. Status [401 Unauthorized]. Body: [message=must authenticate]",
+			err:    errors.New("exit status 1"),
+		},
+		{
+			name:   "expired token with 407 in its name",
+			output: "Bad response statusCode [401]. Status [401 Unauthorized]. Body: [message=token token-40712 is expired]",
+			err:    errors.New("exit status 1"),
+		},
+		{
+			name:    "non-auth error containing 401",
+			output:  "Bad response statusCode [404]. Status [404 Not Found]. Body: [message=cluster c-m-401xz not found]",
+			err:     errors.New("exit status 1"),
+			wantErr: "failed without an authentication error",
+		},
+		{
+			name:    "proxy authentication required",
+			output:  "proxyconnect tcp: 407 Proxy Authentication Required",
+			err:     errors.New("exit status 1"),
+			wantErr: "failed without an authentication error",
+		},
+		{
+			name:    "network error",
+			output:  "dial tcp 10.0.0.1:443: connect: connection refused",
+			err:     errors.New("exit status 1"),
+			wantErr: "failed without an authentication error",
+		},
+		{
+			name:    "command succeeds",
+			output:  "CURRENT   ID    STATE    NAME",
+			wantErr: "succeeded but was expected to fail authentication",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			run := func(args ...string) (string, int, error) {
+				return tt.output, ExitCode(tt.err), tt.err
+			}
+
+			err := expectAuthError(run, "clusters", "ls")
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// tlsErrors are the messages printed when the rancher CLI can not verify the certificate of the server.
+var tlsErrors = []string{"x509:", "tls:", "certificate signed by unknown authority"}
+
+// LoginWithCACert logs the rancher CLI in to the server, verifying its certificate with the CA at caPath instead of
+// skipping verification, so that tests can check that a private CA is honored.
+func LoginWithCACert(hostname, token, caPath string) error {
+	return loginWithCACert(RunCommand, hostname, token, caPath)
+}
+
+func loginWithCACert(run func(args ...string) (string, int, error), hostname, token, caPath string) error {
+	if _, err := os.Stat(caPath); err != nil {
+		return fmt.Errorf("failed to read CA cert: %w", err)
+	}
+
+	output, _, err := run("login", "https://"+hostname, "--token", token, "--cacert", caPath)
+	if err != nil {
+		return fmt.Errorf("failed to login to %s with CA cert %s: %w: %s", hostname, caPath, err, output)
+	}
+
+	return nil
+}
+
+// IsTLSError returns true if the CLI output reports that the certificate of the server could not be verified.
+func IsTLSError(output string) bool {
+	output = strings.ToLower(output)
+	for _, message := range tlsErrors {
+		if strings.Contains(output, message) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,47 @@
+package cli
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoginWithCACert(t *testing.T) {
+	dir := t.TempDir()
+	rightCA := filepath.Join(dir, "right-ca.pem")
+	wrongCA := filepath.Join(dir, "wrong-ca.pem")
+	require.NoError(t, os.WriteFile(rightCA, []byte("right"), 0600))
+	require.NoError(t, os.WriteFile(wrongCA, []byte("wrong"), 0600))
+
+	// The fake server only trusts logins that verify its certificate with the right CA.
+	var gotArgs []string
+	run := func(args ...string) (string, int, error) {
+		gotArgs = args
+		if args[len(args)-1] != rightCA {
+			return "Get \"https://rancher.example.com/v3\": tls: failed to verify certificate: x509: certificate signed by unknown authority", 1, errors.New("exit status 1")
+		}
+		return "Saving config to /root/.rancher/cli2.json\n", 0, nil
+	}
+
+	err := loginWithCACert(run, "rancher.example.com", "token-abcde:secret", rightCA)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"login", "https://rancher.example.com", "--token", "token-abcde:secret", "--cacert", rightCA}, gotArgs)
+
+	err = loginWithCACert(run, "rancher.example.com", "token-abcde:secret", wrongCA)
+	require.Error(t, err)
+	assert.True(t, IsTLSError(err.Error()))
+
+	err = loginWithCACert(run, "rancher.example.com", "token-abcde:secret", filepath.Join(dir, "missing.pem"))
+	assert.ErrorContains(t, err, "failed to read CA cert")
+}
+
+func TestIsTLSError(t *testing.T) {
+	assert.True(t, IsTLSError("x509: certificate signed by unknown authority"))
+	assert.True(t, IsTLSError("remote error: tls: bad certificate"))
+	assert.False(t, IsTLSError("dial tcp 10.0.0.1:443: connect: connection refused"))
+	assert.False(t, IsTLSError("Bad response statusCode [401]. Status [401 Unauthorized]."))
+}
@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"syscall"
+)
+
+// ErrorKind is the kind of failure reported by a rancher CLI command.
+type ErrorKind string
+
+const (
+	ErrorKindUnknown     ErrorKind = "Unknown"
+	ErrorKindAuth        ErrorKind = "Auth"
+	ErrorKindProxyAuth   ErrorKind = "ProxyAuth"
+	ErrorKindTimeout     ErrorKind = "Timeout"
+	ErrorKindConnRefused ErrorKind = "ConnRefused"
+)
+
+var (
+	connRefusedErrors = []string{"connection refused", "connectex: no connection could be made"}
+	timeoutErrors     = []string{"timeout", "deadline exceeded", "timed out"}
+)
+
+// ClassifyError returns the kind of failure of a rancher CLI command from the error returned by running it and its
+// output, so that tests can tell a proxy that is down from one that rejects the credentials or is merely slow.
+func ClassifyError(err error, output string) ErrorKind {
+	if err == nil {
+		return ErrorKindUnknown
+	}
+
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return ErrorKindConnRefused
+	}
+
+	// Also matches context.DeadlineExceeded and os.ErrDeadlineExceeded, which implement net.Error.
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return ErrorKindTimeout
+	}
+
+	// Network failures are checked first, as their output contains addresses whose digits may look like a status code.
+	lowerOutput := strings.ToLower(output)
+	switch {
+	case containsAny(lowerOutput, connRefusedErrors):
+		return ErrorKindConnRefused
+	case containsAny(lowerOutput, timeoutErrors):
+		return ErrorKindTimeout
+	case containsAny(lowerOutput, proxyAuthErrors):
+		return ErrorKindProxyAuth
+	case IsAuthError(output):
+		return ErrorKindAuth
+	}
+
+	return ErrorKindUnknown
+}
+
+func containsAny(output string, messages []string) bool {
+	for _, message := range messages {
+		if strings.Contains(output, message) {
+			return true
+		}
+	}
+
+	return false
+}
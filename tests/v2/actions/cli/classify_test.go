@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassifyError(t *testing.T) {
+	exitErr := errors.New("exit status 1")
+
+	tests := []struct {
+		name   string
+		err    error
+		output string
+		want   ErrorKind
+	}{
+		{
+			name:   "auth",
+			err:    exitErr,
+			output: "Bad response statusCode [401]. Status [401 Unauthorized]. Body: [message=must authenticate]",
+			want:   ErrorKindAuth,
+		},
+		{
+			name:   "proxy auth",
+			err:    exitErr,
+			output: "proxyconnect tcp: 407 Proxy Authentication Required",
+			want:   ErrorKindProxyAuth,
+		},
+		{
+			name:   "timeout",
+			err:    exitErr,
+			output: "Get \"https://rancher.example.com/v3\": net/http: request canceled (Client.Timeout exceeded while awaiting headers)",
+			want:   ErrorKindTimeout,
+		},
+		{
+			name:   "connection refused",
+			err:    exitErr,
+			output: "Get \"https://rancher.example.com/v3\": proxyconnect tcp: dial tcp 127.0.0.1:3128: connect: connection refused",
+			want:   ErrorKindConnRefused,
+		},
+		{
+			name:   "connection refused on a port containing 401",
+			err:    exitErr,
+			output: "Get \"https://rancher.example.com/v3\": proxyconnect tcp: dial tcp 127.0.0.1:40123: connect: connection refused",
+			want:   ErrorKindConnRefused,
+		},
+		{
+			name: "connection refused error",
+			err:  fmt.Errorf("dial: %w", syscall.ECONNREFUSED),
+			want: ErrorKindConnRefused,
+		},
+		{
+			name: "deadline exceeded error",
+			err:  fmt.Errorf("login: %w", os.ErrDeadlineExceeded),
+			want: ErrorKindTimeout,
+		},
+		{
+			name:   "unknown",
+			err:    exitErr,
+			output: "Incorrect Usage: flag provided but not defined: -foo",
+			want:   ErrorKindUnknown,
+		},
+		{
+			name: "no error",
+			want: ErrorKindUnknown,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ClassifyError(tt.err, tt.output))
+		})
+	}
+}
+
+func TestClosedProxyIsConnRefused(t *testing.T) {
+	if _, err := exec.LookPath(rancher); err != nil {
+		t.Skip("the rancher CLI is not installed")
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	proxyURL := "http://" + listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	t.Setenv("HTTP_PROXY", proxyURL)
+	t.Setenv("HTTPS_PROXY", proxyURL)
+	t.Setenv("NO_PROXY", "")
+	t.Setenv(configDirEnv, t.TempDir())
+
+	output, _, err := RunCommand("login", "https://rancher.example.com", "--token", "token-abcde:secret", "--skip-verify")
+	require.Error(t, err)
+	assert.Equal(t, ErrorKindConnRefused, ClassifyError(err, output), output)
+}
@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+
+	"github.com/rancher/norman/types"
+	rancherClient "github.com/rancher/shepherd/clients/rancher"
+)
+
+// clusterFormat is the Go template `rancher clusters ls` prints each cluster with, instead of its table output whose
+// columns change across CLI versions.
+const clusterFormat = "{{.Cluster.ID}} {{.Cluster.Name}}"
+
+// CLICluster is a cluster as listed by the rancher CLI.
+type CLICluster struct {
+	ID   string
+	Name string
+}
+
+// ListAllClusters returns every cluster listed by `rancher clusters ls`. The CLI has no continuation flag, it is
+// expected to list all clusters at once, so the output is checked against all clusters listed through the API and an
+// error is returned if the CLI dropped any of them, e.g. because it only printed the first page.
+func ListAllClusters(client *rancherClient.Client) ([]CLICluster, error) {
+	apiClusterIDs := func() ([]string, error) {
+		collection, err := client.Management.Cluster.ListAll(&types.ListOpts{})
+		if err != nil {
+			return nil, err
+		}
+
+		ids := make([]string, 0, len(collection.Data))
+		for _, cluster := range collection.Data {
+			ids = append(ids, cluster.ID)
+		}
+		return ids, nil
+	}
+
+	return listAllClusters(RunCommand, apiClusterIDs)
+}
+
+func listAllClusters(run func(args ...string) (string, int, error), apiClusterIDs func() ([]string, error)) ([]CLICluster, error) {
+	output, code, err := run(clusters, "ls", "--format", clusterFormat)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list clusters with exit code %d: %s", code, strings.TrimSpace(output))
+	}
+
+	listed, err := parseClusters(output)
+	if err != nil {
+		return nil, err
+	}
+
+	ids, err := apiClusterIDs()
+	if err != nil {
+		return nil, err
+	}
+
+	found := make(map[string]bool, len(listed))
+	for _, cluster := range listed {
+		found[cluster.ID] = true
+	}
+
+	var missing []string
+	for _, id := range ids {
+		if !found[id] {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("the rancher CLI listed %d of %d clusters, missing %v", len(ids)-len(missing), len(ids), missing)
+	}
+
+	return listed, nil
+}
+
+// parseClusters parses the output of `rancher clusters ls` printed with clusterFormat.
+func parseClusters(output string) ([]CLICluster, error) {
+	var listed []CLICluster
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		id, name, ok := strings.Cut(line, " ")
+		if !ok {
+			return nil, fmt.Errorf("failed to parse cluster %q", line)
+		}
+		listed = append(listed, CLICluster{ID: id, Name: name})
+	}
+
+	return listed, scanner.Err()
+}
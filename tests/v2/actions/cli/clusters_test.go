@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordedClusterIDs returns the IDs of the clusters of the recorded `rancher clusters ls` output.
+func recordedClusterIDs(output string) func() ([]string, error) {
+	return func() ([]string, error) {
+		var ids []string
+		for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+			id, _, _ := strings.Cut(line, " ")
+			ids = append(ids, id)
+		}
+		return ids, nil
+	}
+}
+
+func TestListAllClusters(t *testing.T) {
+	output := readTestdata(t, "clusters_ls.txt")
+
+	var gotArgs []string
+	run := func(args ...string) (string, int, error) {
+		gotArgs = args
+		return output, 0, nil
+	}
+
+	listed, err := listAllClusters(run, recordedClusterIDs(output))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"clusters", "ls", "--format", clusterFormat}, gotArgs)
+
+	require.Len(t, listed, 500)
+	assert.Equal(t, CLICluster{ID: "local", Name: "local"}, listed[0])
+	assert.Equal(t, CLICluster{ID: "c-m-00499", Name: "downstream-499"}, listed[499])
+}
+
+func TestListAllClustersTruncated(t *testing.T) {
+	output := readTestdata(t, "clusters_ls.txt")
+	lines := strings.SplitAfter(output, "\n")
+	firstPage := strings.Join(lines[:498], "")
+
+	run := func(args ...string) (string, int, error) {
+		return firstPage, 0, nil
+	}
+
+	_, err := listAllClusters(run, recordedClusterIDs(output))
+	assert.EqualError(t, err, "the rancher CLI listed 498 of 500 clusters, missing [c-m-00498 c-m-00499]")
+}
+
+func TestListAllClustersErrors(t *testing.T) {
+	failing := func(args ...string) (string, int, error) {
+		return "Unauthorized 401: must authenticate\n", 1, errors.New("exit status 1")
+	}
+	_, err := listAllClusters(failing, recordedClusterIDs(""))
+	assert.EqualError(t, err, "failed to list clusters with exit code 1: Unauthorized 401: must authenticate")
+
+	malformed := func(args ...string) (string, int, error) {
+		return "local local\nc-m-00001\n", 0, nil
+	}
+	_, err = listAllClusters(malformed, recordedClusterIDs(""))
+	assert.EqualError(t, err, `failed to parse cluster "c-m-00001"`)
+}
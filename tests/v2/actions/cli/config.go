@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	configDirEnv     = "RANCHER_CONFIG_DIR"
+	defaultConfigDir = ".rancher"
+	configFileName   = "cli2.json"
+	redacted         = "*****"
+)
+
+// CLIConfig is the rancher CLI config with secrets redacted.
+type CLIConfig struct {
+	CurrentServer string                   `json:"CurrentServer"`
+	Servers       map[string]*ServerConfig `json:"Servers"`
+}
+
+// ServerConfig is a server entry of the rancher CLI config.
+type ServerConfig struct {
+	AccessKey string `json:"accessKey"`
+	SecretKey string `json:"secretKey"`
+	TokenKey  string `json:"tokenKey"`
+	URL       string `json:"url"`
+	Project   string `json:"project"`
+	CACerts   string `json:"cacert"`
+}
+
+// Current returns the entry of the current server, or nil if there is none.
+func (c *CLIConfig) Current() *ServerConfig {
+	return c.Servers[c.CurrentServer]
+}
+
+// ReadConfig will read and parse the rancher CLI config file, so that tests can assert what login persisted.
+func ReadConfig() (*CLIConfig, error) {
+	path, err := configPath()
+	if err != nil {
+		return nil, err
+	}
+
+	return readConfigFile(path)
+}
+
+// configPath returns the path of the rancher CLI config file, honoring RANCHER_CONFIG_DIR like the CLI does.
+func configPath() (string, error) {
+	configDir := os.Getenv(configDirEnv)
+	if configDir == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		configDir = filepath.Join(homeDir, defaultConfigDir)
+	}
+
+	return filepath.Join(configDir, configFileName), nil
+}
+
+func readConfigFile(path string) (*CLIConfig, error) {
+	config, err := parseConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, server := range config.Servers {
+		server.SecretKey = redactSecret(server.SecretKey)
+		server.TokenKey = redactToken(server.TokenKey)
+	}
+
+	return config, nil
+}
+
+// parseConfigFile parses the rancher CLI config file without redacting its secrets.
+func parseConfigFile(path string) (*CLIConfig, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &CLIConfig{}
+	if err := json.Unmarshal(content, config); err != nil {
+		return nil, fmt.Errorf("error parsing CLI config %s: %w", path, err)
+	}
+
+	return config, nil
+}
+
+func redactSecret(secret string) string {
+	if secret == "" {
+		return ""
+	}
+
+	return redacted
+}
+
+// redactToken keeps the token name so that it can still be matched, but redacts the secret part of the token.
+func redactToken(token string) string {
+	name, _, found := strings.Cut(token, ":")
+	if !found {
+		return redactSecret(token)
+	}
+
+	return name + ":" + redacted
+}
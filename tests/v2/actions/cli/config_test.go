@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadConfig(t *testing.T) {
+	configDir := t.TempDir()
+	content, err := os.ReadFile(filepath.Join("testdata", configFileName))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(configDir, configFileName), content, 0600))
+
+	t.Setenv(configDirEnv, configDir)
+
+	config, err := ReadConfig()
+	require.NoError(t, err)
+
+	assert.Equal(t, "staging", config.CurrentServer)
+	require.Len(t, config.Servers, 2)
+
+	current := config.Current()
+	require.NotNil(t, current)
+	assert.Equal(t, "https://staging.example.com", current.URL)
+	assert.Equal(t, "token-fghij", current.AccessKey)
+	assert.Equal(t, "token-fghij:"+redacted, current.TokenKey)
+	assert.Equal(t, redacted, current.SecretKey)
+	assert.Contains(t, current.CACerts, "BEGIN CERTIFICATE")
+
+	other := config.Servers["rancherDefault"]
+	assert.Equal(t, "https://rancher.example.com", other.URL)
+	assert.Equal(t, "local:p-12345", other.Project)
+	assert.Equal(t, "token-abcde:"+redacted, other.TokenKey)
+	assert.Empty(t, other.CACerts)
+}
+
+func TestReadConfigMissing(t *testing.T) {
+	t.Setenv(configDirEnv, t.TempDir())
+
+	_, err := ReadConfig()
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestReadConfigMalformed(t *testing.T) {
+	configDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(configDir, configFileName), []byte("{"), 0600))
+	t.Setenv(configDirEnv, configDir)
+
+	_, err := ReadConfig()
+	assert.Error(t, err)
+}
@@ -0,0 +1,32 @@
+package cli
+
+import (
+	"errors"
+	"os/exec"
+)
+
+// RunCommand runs the rancher CLI with the given arguments and returns its combined output and exit code, so that
+// tests can assert on the exit code independently of the output, which changes across CLI versions.
+func RunCommand(args ...string) (string, int, error) {
+	return runCommand(rancher, args...)
+}
+
+func runCommand(name string, args ...string) (string, int, error) {
+	output, err := exec.Command(name, args...).CombinedOutput()
+	return string(output), ExitCode(err), err
+}
+
+// ExitCode returns the exit code of a command from the error returned by running it. It returns 0 if err is nil and
+// -1 if err does not wrap an *exec.ExitError, e.g. because the command could not be started.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+
+	return -1
+}
@@ -0,0 +1,33 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunCommandExitCode(t *testing.T) {
+	output, code, err := runCommand("sh", "-c", "echo ok")
+	require.NoError(t, err)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "ok\n", output)
+
+	output, code, err = runCommand("sh", "-c", "echo 'Proxy Authentication Required' >&2; exit 3")
+	assert.Error(t, err)
+	assert.Equal(t, 3, code)
+	assert.Contains(t, output, "Proxy Authentication Required")
+}
+
+func TestExitCode(t *testing.T) {
+	assert.Equal(t, 0, ExitCode(nil))
+	assert.Equal(t, -1, ExitCode(errors.New("not an exit error")))
+
+	_, _, err := runCommand("sh", "-c", "exit 1")
+	assert.Equal(t, 1, ExitCode(fmt.Errorf("wrapped: %w", err)))
+
+	_, _, err = runCommand("command-that-does-not-exist")
+	assert.Equal(t, -1, ExitCode(err))
+}
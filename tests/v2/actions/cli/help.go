@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+)
+
+// unknownCommandHelp is printed by the rancher CLI when help is requested for a command it does not know.
+const unknownCommandHelp = "No help topic for"
+
+// CommandExists runs `rancher <command> --help` and returns whether the CLI recognizes the command, so that tests can
+// guard commands that only exist in some CLI versions. The command may include subcommands, e.g. "clusters create".
+func CommandExists(command string) (bool, error) {
+	exists, _, err := commandHelp(RunCommand, command)
+	return exists, err
+}
+
+// ListSubcommands returns the names of the subcommands of the command as listed by `rancher <command> --help`, without
+// their aliases and the builtin help command. It returns an error if the CLI does not recognize the command.
+func ListSubcommands(command string) ([]string, error) {
+	exists, subcommands, err := commandHelp(RunCommand, command)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("the rancher CLI has no command %q", command)
+	}
+
+	return subcommands, nil
+}
+
+func commandHelp(run func(args ...string) (string, int, error), command string) (bool, []string, error) {
+	args := append(strings.Fields(command), "--help")
+	output, code, err := run(args...)
+	if strings.Contains(output, unknownCommandHelp) {
+		return false, nil, nil
+	}
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to get help of command %q with exit code %d: %s", command, code, strings.TrimSpace(output))
+	}
+
+	return true, parseSubcommands(output), nil
+}
+
+// parseSubcommands returns the subcommands of the COMMANDS section of the help output. Each line of the section holds
+// the comma separated names of a subcommand followed by its description.
+func parseSubcommands(output string) []string {
+	var subcommands []string
+	inCommands := false
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, " ") {
+			// Sections start with an unindented header, e.g. "COMMANDS:".
+			inCommands = strings.TrimSpace(line) == "COMMANDS:"
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if !inCommands || len(fields) == 0 || strings.HasSuffix(line, ":") {
+			// Skip lines outside of the section and category headers within it.
+			continue
+		}
+
+		name := strings.TrimSuffix(fields[0], ",")
+		if name != "help" {
+			subcommands = append(subcommands, name)
+		}
+	}
+
+	return subcommands
+}
@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeHelpCLI serves the recorded help output of the rancher CLI.
+func fakeHelpCLI(t *testing.T) func(args ...string) (string, int, error) {
+	return func(args ...string) (string, int, error) {
+		switch strings.Join(args, " ") {
+		case "clusters --help":
+			return readTestdata(t, "help_clusters.txt"), 0, nil
+		case "clusters create --help":
+			return readTestdata(t, "help_clusters_create.txt"), 0, nil
+		case "clusters rotate-certs --help":
+			return "No help topic for 'rotate-certs'\n", 3, errors.New("exit status 3")
+		case "apps --help":
+			return "No help topic for 'apps'\n", 3, errors.New("exit status 3")
+		}
+
+		return "", 1, errors.New("exit status 1")
+	}
+}
+
+func readTestdata(t *testing.T, name string) string {
+	content, err := os.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	return string(content)
+}
+
+func TestCommandHelp(t *testing.T) {
+	tests := []struct {
+		command         string
+		wantExists      bool
+		wantSubcommands []string
+		wantErr         string
+	}{
+		{
+			command:         "clusters",
+			wantExists:      true,
+			wantSubcommands: []string{"ls", "create", "import", "kubeconfig", "rm", "add-node"},
+		},
+		{
+			command:    "clusters create",
+			wantExists: true,
+		},
+		{
+			command: "clusters rotate-certs",
+		},
+		{
+			command: "apps",
+		},
+		{
+			command: "login",
+			wantErr: `failed to get help of command "login" with exit code 1`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			exists, subcommands, err := commandHelp(fakeHelpCLI(t), tt.command)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantExists, exists)
+			assert.Equal(t, tt.wantSubcommands, subcommands)
+		})
+	}
+}
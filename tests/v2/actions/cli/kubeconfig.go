@@ -0,0 +1,37 @@
+package cli
+
+import (
+	"fmt"
+	"os/exec"
+
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+const clusters = "clusters"
+
+// ExportKubeconfig runs `rancher clusters kubeconfig` for the cluster and parses its output, so that tests can assert
+// the server, cluster CA and context of the exported kubeconfig.
+func ExportKubeconfig(clusterID string) (*api.Config, error) {
+	// Only stdout is parsed, as the CLI may print warnings to stderr.
+	output, err := exec.Command(rancher, clusters, "kubeconfig", clusterID).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to export kubeconfig of cluster %s: %w", clusterID, err)
+	}
+
+	return parseKubeconfig(output)
+}
+
+// parseKubeconfig parses the kubeconfig and verifies that its current context exists.
+func parseKubeconfig(data []byte) (*api.Config, error) {
+	config, err := clientcmd.Load(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse kubeconfig: %w", err)
+	}
+
+	if _, ok := config.Contexts[config.CurrentContext]; !ok {
+		return nil, fmt.Errorf("current context %q of kubeconfig does not exist", config.CurrentContext)
+	}
+
+	return config, nil
+}
@@ -0,0 +1,41 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseKubeconfig(t *testing.T) {
+	content, err := os.ReadFile(filepath.Join("testdata", "kubeconfig.yaml"))
+	require.NoError(t, err)
+
+	config, err := parseKubeconfig(content)
+	require.NoError(t, err)
+
+	assert.Equal(t, "downstream", config.CurrentContext)
+	require.Len(t, config.Clusters, 2)
+	require.Len(t, config.Contexts, 2)
+
+	cluster := config.Clusters[config.Contexts[config.CurrentContext].Cluster]
+	require.NotNil(t, cluster)
+	assert.Equal(t, "https://rancher.example.com/k8s/clusters/c-m-abcde", cluster.Server)
+	assert.Contains(t, string(cluster.CertificateAuthorityData), "BEGIN CERTIFICATE")
+
+	nodeCluster := config.Clusters["downstream-node-1"]
+	require.NotNil(t, nodeCluster)
+	assert.Equal(t, "https://10.0.0.1:6443", nodeCluster.Server)
+
+	assert.Equal(t, "kubeconfig-user-abcde:xyz", config.AuthInfos["downstream"].Token)
+}
+
+func TestParseKubeconfigInvalid(t *testing.T) {
+	_, err := parseKubeconfig([]byte("not: [valid"))
+	assert.ErrorContains(t, err, "failed to parse kubeconfig")
+
+	_, err = parseKubeconfig([]byte("apiVersion: v1\nkind: Config\ncurrent-context: missing\n"))
+	assert.ErrorContains(t, err, `current context "missing" of kubeconfig does not exist`)
+}
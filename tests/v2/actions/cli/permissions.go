@@ -0,0 +1,76 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// permissionErrors are the messages the rancher CLI prints when the API rejects a request due to RBAC.
+var permissionErrors = []string{"403", "forbidden", "permission denied", "not allowed"}
+
+// LoginWithScopedToken logs the rancher CLI in to the current server of the CLI config with the given token, e.g. a
+// read-only token, so that tests can verify that RBAC is enforced through the CLI.
+func LoginWithScopedToken(token string) error {
+	config, err := ReadConfig()
+	if err != nil {
+		return err
+	}
+
+	server := config.Current()
+	if server == nil {
+		return errors.New("the rancher CLI is not logged in to any server")
+	}
+
+	args := []string{"login", "--token", token, server.URL, "--skip-verify"}
+	if server.Project != "" {
+		args = append(args, "--context", server.Project)
+	}
+
+	output, err := exec.Command(rancher, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to login with scoped token: %w: %s", err, output)
+	}
+
+	return nil
+}
+
+// VerifyReadOnly runs the read commands and the write commands with the rancher CLI, and returns an error unless
+// every read command succeeds and every write command fails with a permission error.
+func VerifyReadOnly(readCommands, writeCommands [][]string) error {
+	return verifyReadOnly(RunCommand, readCommands, writeCommands)
+}
+
+func verifyReadOnly(run func(args ...string) (string, int, error), readCommands, writeCommands [][]string) error {
+	for _, args := range readCommands {
+		output, code, err := run(args...)
+		if err != nil {
+			return fmt.Errorf("read command %q failed with exit code %d: %s", strings.Join(args, " "), code, output)
+		}
+	}
+
+	for _, args := range writeCommands {
+		output, _, err := run(args...)
+		if err == nil {
+			return fmt.Errorf("write command %q succeeded but was expected to be denied", strings.Join(args, " "))
+		}
+		if !IsPermissionError(output) {
+			return fmt.Errorf("write command %q failed without a permission error: %s", strings.Join(args, " "), output)
+		}
+	}
+
+	return nil
+}
+
+// IsPermissionError returns true if the CLI output reports that a request was denied due to RBAC.
+func IsPermissionError(output string) bool {
+	output = strings.ToLower(output)
+	for _, message := range permissionErrors {
+		if strings.Contains(output, message) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeReadOnlyCLI simulates the rancher CLI logged in with a read-only token.
+func fakeReadOnlyCLI(args ...string) (string, int, error) {
+	switch strings.Join(args, " ") {
+	case "clusters ls":
+		return "CURRENT   ID        STATE    NAME\n*         local     active   local\n", 0, nil
+	case "clusters create test":
+		return "Bad response statusCode [403]. Status [403 Forbidden]. Body: [message=clusters.management.cattle.io is forbidden]", 1, errors.New("exit status 1")
+	case "namespaces create test":
+		return "Get \"https://rancher.example.com/v3\": dial tcp: connection refused", 1, errors.New("exit status 1")
+	}
+
+	return "", 0, nil
+}
+
+func TestVerifyReadOnly(t *testing.T) {
+	tests := []struct {
+		name          string
+		readCommands  [][]string
+		writeCommands [][]string
+		wantErr       string
+	}{
+		{
+			name:          "read succeeds and write is denied",
+			readCommands:  [][]string{{"clusters", "ls"}},
+			writeCommands: [][]string{{"clusters", "create", "test"}},
+		},
+		{
+			name:          "write succeeds",
+			writeCommands: [][]string{{"clusters", "ls"}},
+			wantErr:       "succeeded but was expected to be denied",
+		},
+		{
+			name:         "read fails",
+			readCommands: [][]string{{"clusters", "create", "test"}},
+			wantErr:      `read command "clusters create test" failed with exit code 1`,
+		},
+		{
+			name:          "write fails without permission error",
+			writeCommands: [][]string{{"namespaces", "create", "test"}},
+			wantErr:       "failed without a permission error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifyReadOnly(fakeReadOnlyCLI, tt.readCommands, tt.writeCommands)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+)
+
+const server = "server"
+
+// SwitchServer makes the server entry with the given name of the rancher CLI config the current one, so that
+// subsequent commands are sent to that server, and verifies that the CLI reports it as current afterwards.
+func SwitchServer(name string) error {
+	return switchServer(RunCommand, name)
+}
+
+func switchServer(run func(args ...string) (string, int, error), name string) error {
+	output, _, err := run(server, "switch", name)
+	if err != nil {
+		return fmt.Errorf("failed to switch to server %s: %w: %s", name, err, output)
+	}
+
+	current, err := currentServer(run)
+	if err != nil {
+		return err
+	}
+	if current != name {
+		return fmt.Errorf("current server is %s after switching to %s", current, name)
+	}
+
+	return nil
+}
+
+// CurrentServer returns the name of the server entry of the rancher CLI config that commands are sent to.
+func CurrentServer() (string, error) {
+	return currentServer(RunCommand)
+}
+
+func currentServer(run func(args ...string) (string, int, error)) (string, error) {
+	output, _, err := run(server, "current")
+	if err != nil {
+		return "", fmt.Errorf("failed to get the current server: %w: %s", err, output)
+	}
+
+	return parseCurrentServer(output)
+}
+
+// parseCurrentServer parses the output of rancher server current, which is of the form "Name: <name> URL: <url>".
+func parseCurrentServer(output string) (string, error) {
+	fields := strings.Fields(output)
+	if len(fields) < 2 || fields[0] != "Name:" {
+		return "", fmt.Errorf("unexpected output of %s current: %q", server, output)
+	}
+
+	return fields[1], nil
+}
@@ -0,0 +1,84 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeServers simulates the rancher CLI server commands for a config holding two logged-in servers. With ignoreSwitch
+// set, switching succeeds but keeps the current server.
+type fakeServers struct {
+	urls         map[string]string
+	current      string
+	ignoreSwitch bool
+}
+
+func newFakeServers() *fakeServers {
+	return &fakeServers{
+		urls:    map[string]string{"rancherDefault": "https://rancher.example.com", "staging": "https://staging.example.com"},
+		current: "rancherDefault",
+	}
+}
+
+func (f *fakeServers) run(args ...string) (string, int, error) {
+	switch {
+	case len(args) == 2 && args[1] == "current":
+		return fmt.Sprintf("Name: %s URL: %s\n", f.current, f.urls[f.current]), 0, nil
+	case len(args) == 3 && args[1] == "switch":
+		if _, ok := f.urls[args[2]]; !ok {
+			return "FATA[0000] Server not found\n", 1, errors.New("exit status 1")
+		}
+		if !f.ignoreSwitch {
+			f.current = args[2]
+		}
+		return "", 0, nil
+	default:
+		return "", 1, fmt.Errorf("unexpected command %v", args)
+	}
+}
+
+func TestSwitchServer(t *testing.T) {
+	fake := newFakeServers()
+
+	current, err := currentServer(fake.run)
+	require.NoError(t, err)
+	assert.Equal(t, "rancherDefault", current)
+
+	require.NoError(t, switchServer(fake.run, "staging"))
+	current, err = currentServer(fake.run)
+	require.NoError(t, err)
+	assert.Equal(t, "staging", current)
+	assert.Equal(t, "https://staging.example.com", fake.urls[fake.current])
+
+	require.NoError(t, switchServer(fake.run, "rancherDefault"))
+	assert.Equal(t, "rancherDefault", fake.current)
+}
+
+func TestSwitchServerUnknown(t *testing.T) {
+	fake := newFakeServers()
+
+	err := switchServer(fake.run, "missing")
+	assert.EqualError(t, err, "failed to switch to server missing: exit status 1: FATA[0000] Server not found\n")
+	assert.Equal(t, "rancherDefault", fake.current)
+}
+
+func TestSwitchServerNotApplied(t *testing.T) {
+	fake := newFakeServers()
+	fake.ignoreSwitch = true
+
+	err := switchServer(fake.run, "staging")
+	assert.EqualError(t, err, "current server is rancherDefault after switching to staging")
+}
+
+func TestParseCurrentServer(t *testing.T) {
+	name, err := parseCurrentServer("Name: staging URL: https://staging.example.com\n")
+	require.NoError(t, err)
+	assert.Equal(t, "staging", name)
+
+	_, err = parseCurrentServer("")
+	assert.EqualError(t, err, `unexpected output of server current: ""`)
+}
@@ -0,0 +1,162 @@
+package cli
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const tokenInfoTimeout = 30 * time.Second
+
+// TokenInfo returns when the token of the current server in the rancher CLI config expires and its TTL in seconds, as
+// reported by the Rancher server, so that tests of long-running sessions can tell how close the token is to expiry.
+// A token that never expires has a zero expiresAt and TTL.
+func TokenInfo() (expiresAt time.Time, ttlSeconds int, err error) {
+	server, err := currentServerConfig()
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+
+	return tokenInfo(server)
+}
+
+// VerifyTokenNearExpiry runs a rancher CLI command while the token of the current server expires within window and
+// returns an error unless the CLI handled the upcoming expiry, i.e. either stored a refreshed token in its config that
+// does not expire within window, or warned that the token is about to expire.
+func VerifyTokenNearExpiry(window time.Duration, args ...string) error {
+	return verifyTokenNearExpiry(RunCommand, window, args...)
+}
+
+func verifyTokenNearExpiry(run func(args ...string) (string, int, error), window time.Duration, args ...string) error {
+	before, err := currentServerConfig()
+	if err != nil {
+		return err
+	}
+
+	expiresAt, _, err := tokenInfo(before)
+	if err != nil {
+		return err
+	}
+	if expiresAt.IsZero() || time.Until(expiresAt) > window {
+		return fmt.Errorf("token of server %s does not expire within %s", before.URL, window)
+	}
+
+	output, _, err := run(args...)
+	if err != nil {
+		return fmt.Errorf("command %q failed: %w: %s", strings.Join(args, " "), err, output)
+	}
+
+	after, err := currentServerConfig()
+	if err != nil {
+		return err
+	}
+
+	if after.TokenKey != before.TokenKey {
+		refreshedExpiresAt, _, err := tokenInfo(after)
+		if err != nil {
+			return fmt.Errorf("error checking the refreshed token: %w", err)
+		}
+		if !refreshedExpiresAt.IsZero() && time.Until(refreshedExpiresAt) <= window {
+			return fmt.Errorf("refreshed token of server %s still expires at %s", after.URL, refreshedExpiresAt.Format(time.RFC3339))
+		}
+
+		return nil
+	}
+
+	if strings.Contains(strings.ToLower(output), "expir") {
+		return nil
+	}
+
+	return fmt.Errorf("command %q neither refreshed nor warned about the token of server %s expiring at %s", strings.Join(args, " "), before.URL, expiresAt.Format(time.RFC3339))
+}
+
+// currentServerConfig returns the entry of the current server in the rancher CLI config, with its secrets.
+func currentServerConfig() (*ServerConfig, error) {
+	path, err := configPath()
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := parseConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	server := config.Current()
+	if server == nil {
+		return nil, fmt.Errorf("CLI config %s has no current server", path)
+	}
+
+	return server, nil
+}
+
+func tokenInfo(server *ServerConfig) (time.Time, int, error) {
+	name, _, found := strings.Cut(server.TokenKey, ":")
+	if !found {
+		return time.Time{}, 0, fmt.Errorf("token of server %s is not of the form name:secret", server.URL)
+	}
+
+	httpClient, err := newServerHTTPClient(server.CACerts)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(server.URL, "/")+"/v3/tokens/"+name, nil)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	req.Header.Set("Authorization", "Bearer "+server.TokenKey)
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return time.Time{}, 0, fmt.Errorf("error getting token %s: %w", name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return time.Time{}, 0, fmt.Errorf("error getting token %s: %s", name, resp.Status)
+	}
+
+	var token struct {
+		ExpiresAt string `json:"expiresAt"`
+		// TTL is in milliseconds.
+		TTL int64 `json:"ttl"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return time.Time{}, 0, fmt.Errorf("error parsing token %s: %w", name, err)
+	}
+
+	if token.ExpiresAt == "" {
+		return time.Time{}, 0, nil
+	}
+
+	expiresAt, err := time.Parse(time.RFC3339, token.ExpiresAt)
+	if err != nil {
+		return time.Time{}, 0, fmt.Errorf("token %s has invalid expiresAt %q: %w", name, token.ExpiresAt, err)
+	}
+
+	return expiresAt, int(token.TTL / 1000), nil
+}
+
+// newServerHTTPClient returns an HTTP client that trusts the CA certs of the server entry, if any, like the CLI does.
+func newServerHTTPClient(caCerts string) (*http.Client, error) {
+	httpClient := &http.Client{Timeout: tokenInfoTimeout}
+	if caCerts == "" {
+		return httpClient, nil
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM([]byte(caCerts)) {
+		return nil, fmt.Errorf("CA certs of the CLI config are not valid PEM")
+	}
+	httpClient.Transport = &http.Transport{
+		Proxy:           http.ProxyFromEnvironment,
+		TLSClientConfig: &tls.Config{RootCAs: pool},
+	}
+
+	return httpClient, nil
+}
@@ -0,0 +1,234 @@
+package cli
+
+import (
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testTokenServer serves the tokens of a Rancher server over TLS. Creating a token with a valid token mimics a CLI
+// refreshing its token, and returns the token-refreshed token expiring at refreshedExpiresAt.
+type testTokenServer struct {
+	*httptest.Server
+	mu                 sync.Mutex
+	tokens             map[string]map[string]any
+	refreshedExpiresAt string
+	refreshes          int
+}
+
+func (s *testTokenServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tokenKey := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	token, ok := s.tokens[tokenKey]
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/v3/tokens/"+token["id"].(string):
+		_ = json.NewEncoder(w).Encode(token)
+	case r.Method == http.MethodPost && r.URL.Path == "/v3/tokens":
+		s.refreshes++
+		s.tokens["token-refreshed:newsecret"] = map[string]any{"id": "token-refreshed", "expiresAt": s.refreshedExpiresAt, "ttl": 57600000}
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(map[string]any{"id": "token-refreshed", "token": "token-refreshed:newsecret"})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// newTestTokenServer serves the token-abcde token with the given expiry and TTL in milliseconds over TLS, and points
+// the CLI config at it.
+func newTestTokenServer(t *testing.T, expiresAt string, ttl int64) *testTokenServer {
+	server := &testTokenServer{
+		tokens: map[string]map[string]any{
+			"token-abcde:supersecret": {"id": "token-abcde", "expiresAt": expiresAt, "ttl": ttl},
+		},
+	}
+	server.Server = httptest.NewTLSServer(server)
+	t.Cleanup(server.Close)
+
+	writeTestConfig(t, &CLIConfig{
+		CurrentServer: "rancherDefault",
+		Servers: map[string]*ServerConfig{
+			"rancherDefault": {
+				AccessKey: "token-abcde",
+				SecretKey: "supersecret",
+				TokenKey:  "token-abcde:supersecret",
+				URL:       server.URL,
+				CACerts:   string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})),
+			},
+		},
+	})
+
+	return server
+}
+
+// writeTestConfig writes the CLI config to the config dir, creating a temporary one unless it is already set.
+func writeTestConfig(t *testing.T, config *CLIConfig) {
+	content, err := json.Marshal(config)
+	require.NoError(t, err)
+
+	configDir := os.Getenv(configDirEnv)
+	if configDir == "" {
+		configDir = t.TempDir()
+		t.Setenv(configDirEnv, configDir)
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(configDir, configFileName), content, 0600))
+}
+
+// refreshingCLI returns a fake CLI run that creates a new token with the current one and stores it in the CLI config.
+func refreshingCLI(t *testing.T) func(args ...string) (string, int, error) {
+	return func(args ...string) (string, int, error) {
+		path, err := configPath()
+		require.NoError(t, err)
+		config, err := parseConfigFile(path)
+		require.NoError(t, err)
+		server := config.Current()
+
+		httpClient, err := newServerHTTPClient(server.CACerts)
+		require.NoError(t, err)
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/v3/tokens", strings.NewReader("{}"))
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+server.TokenKey)
+		resp, err := httpClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+		var token struct {
+			Token string `json:"token"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&token))
+
+		server.TokenKey = token.Token
+		server.AccessKey, server.SecretKey, _ = strings.Cut(token.Token, ":")
+		writeTestConfig(t, config)
+
+		return "", 0, nil
+	}
+}
+
+// printingCLI returns a fake CLI run that prints the output and leaves the CLI config unchanged.
+func printingCLI(output string) func(args ...string) (string, int, error) {
+	return func(args ...string) (string, int, error) {
+		return output, 0, nil
+	}
+}
+
+func TestTokenInfo(t *testing.T) {
+	newTestTokenServer(t, "2024-01-01T16:00:00Z", 57600000)
+
+	expiresAt, ttlSeconds, err := TokenInfo()
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 1, 1, 16, 0, 0, 0, time.UTC), expiresAt.UTC())
+	assert.Equal(t, 57600, ttlSeconds)
+}
+
+func TestTokenInfoNeverExpires(t *testing.T) {
+	newTestTokenServer(t, "", 0)
+
+	expiresAt, ttlSeconds, err := TokenInfo()
+	require.NoError(t, err)
+	assert.True(t, expiresAt.IsZero())
+	assert.Zero(t, ttlSeconds)
+}
+
+func TestTokenInfoRejected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	_, _, err := tokenInfo(&ServerConfig{URL: server.URL, TokenKey: "token-abcde:expired"})
+	assert.EqualError(t, err, "error getting token token-abcde: 401 Unauthorized")
+}
+
+func TestTokenInfoInvalidToken(t *testing.T) {
+	_, _, err := tokenInfo(&ServerConfig{URL: "https://rancher.example.com", TokenKey: "token-abcde"})
+	assert.EqualError(t, err, "token of server https://rancher.example.com is not of the form name:secret")
+}
+
+func TestVerifyTokenNearExpiry(t *testing.T) {
+	nearExpiry := time.Now().Add(5 * time.Minute).Format(time.RFC3339)
+	farExpiry := time.Now().Add(16 * time.Hour).Format(time.RFC3339)
+
+	tests := []struct {
+		name               string
+		expiresAt          string
+		refreshedExpiresAt string
+		run                func(t *testing.T) func(args ...string) (string, int, error)
+		wantRefreshes      int
+		wantErr            string
+	}{
+		{
+			name:               "token refreshed",
+			expiresAt:          nearExpiry,
+			refreshedExpiresAt: farExpiry,
+			run:                refreshingCLI,
+			wantRefreshes:      1,
+		},
+		{
+			name:      "expiry warned about",
+			expiresAt: nearExpiry,
+			run: func(*testing.T) func(args ...string) (string, int, error) {
+				return printingCLI("WARN[0000] Your token expires in 5 minutes, please log in again\nc-m-abcde  local")
+			},
+		},
+		{
+			name:               "refreshed token also near expiry",
+			expiresAt:          nearExpiry,
+			refreshedExpiresAt: nearExpiry,
+			run:                refreshingCLI,
+			wantRefreshes:      1,
+			wantErr:            "still expires at " + nearExpiry,
+		},
+		{
+			name:      "expiry ignored",
+			expiresAt: nearExpiry,
+			run: func(*testing.T) func(args ...string) (string, int, error) {
+				return printingCLI("c-m-abcde  local")
+			},
+			wantErr: `command "clusters ls" neither refreshed nor warned about the token of server`,
+		},
+		{
+			name:      "token not near expiry",
+			expiresAt: farExpiry,
+			run:       refreshingCLI,
+			wantErr:   "does not expire within 10m0s",
+		},
+		{
+			name:    "token never expires",
+			run:     refreshingCLI,
+			wantErr: "does not expire within 10m0s",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestTokenServer(t, tt.expiresAt, 300000)
+			server.refreshedExpiresAt = tt.refreshedExpiresAt
+
+			err := verifyTokenNearExpiry(tt.run(t), 10*time.Minute, "clusters", "ls")
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantRefreshes, server.refreshes)
+		})
+	}
+}
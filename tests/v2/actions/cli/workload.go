@@ -0,0 +1,165 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+
+	rancherClient "github.com/rancher/shepherd/clients/rancher"
+	"github.com/rancher/shepherd/pkg/wrangler"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+const localCluster = "local"
+
+// WorkloadError is returned by CreateWorkload when `rancher kubectl apply` fails, and holds the output of the CLI.
+type WorkloadError struct {
+	ClusterID string
+	Namespace string
+	Output    string
+	Err       error
+}
+
+func (e *WorkloadError) Error() string {
+	return fmt.Sprintf("failed to apply workload to namespace %s of cluster %s: %v: %s", e.Namespace, e.ClusterID, e.Err, e.Output)
+}
+
+func (e *WorkloadError) Unwrap() error {
+	return e.Err
+}
+
+// workloadRef identifies a workload of a manifest.
+type workloadRef struct {
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+// CreateWorkload applies the manifest to the namespace with `rancher kubectl apply`. The rancher CLI runs kubectl
+// against the cluster of its current context, which must be the cluster with the given ID.
+func CreateWorkload(clusterID, namespace, manifestYAML string) error {
+	if err := checkCurrentCluster(clusterID); err != nil {
+		return err
+	}
+
+	return createWorkload(kubectlApply, clusterID, namespace, manifestYAML)
+}
+
+// checkCurrentCluster returns an error unless the current context of the rancher CLI is in the cluster with the ID.
+func checkCurrentCluster(clusterID string) error {
+	config, err := ReadConfig()
+	if err != nil {
+		return err
+	}
+
+	server := config.Current()
+	if server == nil {
+		return errors.New("the rancher CLI is not logged in to any server")
+	}
+
+	// The context of the CLI is a project ID of the form <cluster ID>:<project ID>.
+	if currentCluster, _, _ := strings.Cut(server.Project, ":"); currentCluster != clusterID {
+		return fmt.Errorf("the current context of the rancher CLI is in cluster %q, not %q", currentCluster, clusterID)
+	}
+
+	return nil
+}
+
+// VerifyWorkloadCreated verifies that the workloads of the manifest exist in the cluster, by reading them back through
+// the wrangler context of the cluster. Deployments, StatefulSets and DaemonSets are supported.
+func VerifyWorkloadCreated(client *rancherClient.Client, clusterID, namespace, manifestYAML string) error {
+	wranglerContext := client.WranglerContext
+	if clusterID != localCluster {
+		var err error
+		wranglerContext, err = client.WranglerContext.DownStreamClusterWranglerContext(clusterID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return verifyWorkloads(wranglerWorkloadGetter(wranglerContext), namespace, manifestYAML)
+}
+
+func createWorkload(apply func(namespace, manifestYAML string) (string, error), clusterID, namespace, manifestYAML string) error {
+	output, err := apply(namespace, manifestYAML)
+	if err != nil {
+		return &WorkloadError{ClusterID: clusterID, Namespace: namespace, Output: output, Err: err}
+	}
+
+	return nil
+}
+
+func kubectlApply(namespace, manifestYAML string) (string, error) {
+	cmd := exec.Command(rancher, "kubectl", "apply", "--namespace", namespace, "-f", "-")
+	cmd.Stdin = strings.NewReader(manifestYAML)
+
+	output, err := cmd.CombinedOutput()
+	return string(output), err
+}
+
+func verifyWorkloads(get func(ref workloadRef) error, namespace, manifestYAML string) error {
+	refs, err := parseWorkloads(namespace, manifestYAML)
+	if err != nil {
+		return err
+	}
+
+	for _, ref := range refs {
+		if err := get(ref); err != nil {
+			return fmt.Errorf("%s %s/%s was not created: %w", ref.Kind, ref.Namespace, ref.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// parseWorkloads returns the objects of the multi-document manifest. Objects without a namespace are defaulted to
+// the given namespace, as kubectl apply does.
+func parseWorkloads(namespace, manifestYAML string) ([]workloadRef, error) {
+	decoder := yaml.NewYAMLOrJSONDecoder(bytes.NewBufferString(manifestYAML), 4096)
+
+	var refs []workloadRef
+	for {
+		obj := &unstructured.Unstructured{}
+		err := decoder.Decode(&obj.Object)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse manifest: %w", err)
+		}
+		if len(obj.Object) == 0 {
+			continue
+		}
+
+		ref := workloadRef{Kind: obj.GetKind(), Namespace: obj.GetNamespace(), Name: obj.GetName()}
+		if ref.Namespace == "" {
+			ref.Namespace = namespace
+		}
+		refs = append(refs, ref)
+	}
+
+	return refs, nil
+}
+
+func wranglerWorkloadGetter(wranglerContext *wrangler.Context) func(ref workloadRef) error {
+	return func(ref workloadRef) error {
+		var err error
+		switch ref.Kind {
+		case "Deployment":
+			_, err = wranglerContext.Apps.Deployment().Get(ref.Namespace, ref.Name, metav1.GetOptions{})
+		case "StatefulSet":
+			_, err = wranglerContext.Apps.StatefulSet().Get(ref.Namespace, ref.Name, metav1.GetOptions{})
+		case "DaemonSet":
+			_, err = wranglerContext.Apps.DaemonSet().Get(ref.Namespace, ref.Name, metav1.GetOptions{})
+		default:
+			err = fmt.Errorf("unsupported workload kind %s", ref.Kind)
+		}
+
+		return err
+	}
+}
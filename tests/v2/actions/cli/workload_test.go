@@ -0,0 +1,92 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testManifest = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  selector:
+    matchLabels:
+      app: web
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      containers:
+      - name: web
+        image: nginx
+---
+apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: agent
+  namespace: other
+`
+
+// fakeCluster records the workloads applied to it, so that they can be read back.
+type fakeCluster struct {
+	workloads map[workloadRef]bool
+	applyErr  error
+}
+
+func (f *fakeCluster) apply(namespace, manifestYAML string) (string, error) {
+	if f.applyErr != nil {
+		return "error: the server doesn't have a resource type \"deployments\"", f.applyErr
+	}
+
+	refs, err := parseWorkloads(namespace, manifestYAML)
+	if err != nil {
+		return "", err
+	}
+
+	for _, ref := range refs {
+		f.workloads[ref] = true
+	}
+
+	return "deployment.apps/web created\ndaemonset.apps/agent created\n", nil
+}
+
+func (f *fakeCluster) get(ref workloadRef) error {
+	if !f.workloads[ref] {
+		return fmt.Errorf("%s %q not found", ref.Kind, ref.Name)
+	}
+
+	return nil
+}
+
+func TestCreateWorkload(t *testing.T) {
+	cluster := &fakeCluster{workloads: map[workloadRef]bool{}}
+
+	err := createWorkload(cluster.apply, "c-m-abcde", "default", testManifest)
+	require.NoError(t, err)
+
+	assert.True(t, cluster.workloads[workloadRef{Kind: "Deployment", Namespace: "default", Name: "web"}])
+	assert.True(t, cluster.workloads[workloadRef{Kind: "DaemonSet", Namespace: "other", Name: "agent"}])
+	assert.NoError(t, verifyWorkloads(cluster.get, "default", testManifest))
+}
+
+func TestCreateWorkloadError(t *testing.T) {
+	cluster := &fakeCluster{workloads: map[workloadRef]bool{}, applyErr: errors.New("exit status 1")}
+
+	err := createWorkload(cluster.apply, "c-m-abcde", "default", testManifest)
+
+	var workloadErr *WorkloadError
+	require.ErrorAs(t, err, &workloadErr)
+	assert.Equal(t, "c-m-abcde", workloadErr.ClusterID)
+	assert.Equal(t, "default", workloadErr.Namespace)
+	assert.Contains(t, workloadErr.Output, "doesn't have a resource type")
+	assert.ErrorIs(t, err, cluster.applyErr)
+
+	err = verifyWorkloads(cluster.get, "default", testManifest)
+	assert.ErrorContains(t, err, "Deployment default/web was not created")
+}
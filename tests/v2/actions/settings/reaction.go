@@ -0,0 +1,46 @@
+package settings
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	kwait "k8s.io/apimachinery/pkg/util/wait"
+)
+
+const reactionPollInterval = 500 * time.Millisecond
+
+// WaitForControllerReaction verifies that a controller reacts to a setting change. setBefore puts the setting into
+// its initial state, in which observe must not report the effect yet. setAfter then changes the setting and observe
+// is polled until it reports the effect or the timeout expires.
+func WaitForControllerReaction(setBefore, setAfter func() error, observe func() (bool, error), timeout time.Duration) error {
+	return waitForControllerReaction(setBefore, setAfter, observe, reactionPollInterval, timeout)
+}
+
+func waitForControllerReaction(setBefore, setAfter func() error, observe func() (bool, error), interval, timeout time.Duration) error {
+	if err := setBefore(); err != nil {
+		return fmt.Errorf("error setting initial value: %w", err)
+	}
+
+	observed, err := observe()
+	if err != nil {
+		return err
+	}
+	if observed {
+		return errors.New("effect was observed before the setting changed")
+	}
+
+	if err := setAfter(); err != nil {
+		return fmt.Errorf("error changing value: %w", err)
+	}
+
+	err = kwait.PollUntilContextTimeout(context.TODO(), interval, timeout, true, func(ctx context.Context) (bool, error) {
+		return observe()
+	})
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("timed out after %s waiting for the controller to react to the setting change", timeout)
+	}
+
+	return err
+}
@@ -0,0 +1,62 @@
+package settings
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeReaction simulates a controller that reacts to the setting a fixed number of observations after it changed.
+type fakeReaction struct {
+	value        string
+	reactAfter   int
+	observations int
+}
+
+func (f *fakeReaction) set(value string) func() error {
+	return func() error {
+		f.value = value
+		f.observations = 0
+		return nil
+	}
+}
+
+func (f *fakeReaction) observe() (bool, error) {
+	f.observations++
+	return f.value == "true" && f.observations > f.reactAfter, nil
+}
+
+func TestWaitForControllerReaction(t *testing.T) {
+	fake := &fakeReaction{reactAfter: 3}
+
+	err := waitForControllerReaction(fake.set("false"), fake.set("true"), fake.observe, time.Millisecond, time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, fake.observations)
+}
+
+func TestWaitForControllerReactionTimeout(t *testing.T) {
+	fake := &fakeReaction{}
+
+	err := waitForControllerReaction(fake.set("false"), fake.set("false"), fake.observe, time.Millisecond, 20*time.Millisecond)
+	assert.ErrorContains(t, err, "timed out after 20ms waiting for the controller to react")
+}
+
+func TestWaitForControllerReactionObservedBeforeChange(t *testing.T) {
+	fake := &fakeReaction{}
+
+	err := waitForControllerReaction(fake.set("true"), fake.set("true"), fake.observe, time.Millisecond, time.Second)
+	assert.EqualError(t, err, "effect was observed before the setting changed")
+}
+
+func TestWaitForControllerReactionSetError(t *testing.T) {
+	fake := &fakeReaction{}
+	failing := func() error { return errors.New("forbidden") }
+
+	err := waitForControllerReaction(failing, fake.set("true"), fake.observe, time.Millisecond, time.Second)
+	assert.EqualError(t, err, "error setting initial value: forbidden")
+
+	err = waitForControllerReaction(fake.set("false"), failing, fake.observe, time.Millisecond, time.Second)
+	assert.EqualError(t, err, "error changing value: forbidden")
+}
@@ -0,0 +1,12 @@
+package settings
+
+import (
+	"github.com/rancher/shepherd/clients/rancher"
+	managementClient "github.com/rancher/shepherd/clients/rancher/generated/management/v3"
+)
+
+// GetViaAPI reads the setting through the Rancher management API instead of the Kubernetes API, so that tests
+// exercise the full API path, including RBAC, that users of the setting go through.
+func GetViaAPI(client *rancher.Client, name string) (*managementClient.Setting, error) {
+	return client.Management.Setting.ByID(name)
+}
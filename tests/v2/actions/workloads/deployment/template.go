@@ -0,0 +1,77 @@
+package deployment
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/rancher/shepherd/extensions/workloads"
+	namegen "github.com/rancher/shepherd/pkg/namegenerator"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const defaultReplicas = int32(1)
+
+// DeploymentOpts are the parameters used by NewTestDeployment to build a deployment. Only Namespace is required,
+// every other field falls back to a default when unset.
+type DeploymentOpts struct {
+	Name           string
+	Namespace      string
+	Replicas       *int32
+	Image          string
+	Labels         map[string]string
+	Resources      corev1.ResourceRequirements
+	LivenessProbe  *corev1.Probe
+	ReadinessProbe *corev1.Probe
+	Strategy       *appv1.DeploymentStrategy
+}
+
+// NewTestDeployment is a helper to build a deployment from the given options. The deployment defaults to a single
+// nginx replica with a random name and the rolling update strategy.
+func NewTestDeployment(opts DeploymentOpts) (*appv1.Deployment, error) {
+	if opts.Namespace == "" {
+		return nil, errors.New("namespace is required")
+	}
+
+	replicas := defaultReplicas
+	if opts.Replicas != nil {
+		if *opts.Replicas < 0 {
+			return nil, fmt.Errorf("replicas must not be negative, got %d", *opts.Replicas)
+		}
+		replicas = *opts.Replicas
+	}
+
+	name := opts.Name
+	if name == "" {
+		name = namegen.AppendRandomString("testdeployment")
+	}
+
+	image := opts.Image
+	if image == "" {
+		image = imageName
+	}
+
+	strategy := appv1.DeploymentStrategy{Type: appv1.RollingUpdateDeploymentStrategyType}
+	if opts.Strategy != nil {
+		strategy = *opts.Strategy
+	}
+
+	podLabels := map[string]string{}
+	for key, value := range opts.Labels {
+		podLabels[key] = value
+	}
+
+	container := workloads.NewContainer(namegen.AppendRandomString("testcontainer"), image, corev1.PullAlways, nil, nil, nil, nil, nil)
+	container.Resources = opts.Resources
+	container.LivenessProbe = opts.LivenessProbe
+	container.ReadinessProbe = opts.ReadinessProbe
+
+	podTemplate := workloads.NewPodTemplate([]corev1.Container{container}, nil, nil, podLabels, nil)
+
+	deployment := workloads.NewDeploymentTemplate(name, opts.Namespace, podTemplate, true, nil)
+	deployment.Labels = opts.Labels
+	deployment.Spec.Replicas = &replicas
+	deployment.Spec.Strategy = strategy
+
+	return deployment, nil
+}
@@ -0,0 +1,78 @@
+package deployment
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/utils/pointer"
+)
+
+func TestNewTestDeploymentDefaults(t *testing.T) {
+	deployment, err := NewTestDeployment(DeploymentOpts{Namespace: "test-ns"})
+	require.NoError(t, err)
+
+	assert.NotEmpty(t, deployment.Name)
+	assert.Equal(t, "test-ns", deployment.Namespace)
+	assert.Equal(t, int32(1), *deployment.Spec.Replicas)
+	assert.Equal(t, appv1.RollingUpdateDeploymentStrategyType, deployment.Spec.Strategy.Type)
+	require.Len(t, deployment.Spec.Template.Spec.Containers, 1)
+	assert.Equal(t, imageName, deployment.Spec.Template.Spec.Containers[0].Image)
+
+	selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	require.NoError(t, err)
+	assert.True(t, selector.Matches(labels.Set(deployment.Spec.Template.Labels)), "selector must match the pod template")
+}
+
+func TestNewTestDeploymentOptions(t *testing.T) {
+	resources := corev1.ResourceRequirements{
+		Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("128Mi")},
+	}
+	probe := &corev1.Probe{
+		ProbeHandler: corev1.ProbeHandler{HTTPGet: &corev1.HTTPGetAction{Path: "/healthz"}},
+	}
+	strategy := &appv1.DeploymentStrategy{Type: appv1.RecreateDeploymentStrategyType}
+
+	deployment, err := NewTestDeployment(DeploymentOpts{
+		Name:           "custom",
+		Namespace:      "test-ns",
+		Replicas:       pointer.Int32(3),
+		Image:          "redis",
+		Labels:         map[string]string{"team": "qa"},
+		Resources:      resources,
+		LivenessProbe:  probe,
+		ReadinessProbe: probe,
+		Strategy:       strategy,
+	})
+	require.NoError(t, err)
+
+	container := deployment.Spec.Template.Spec.Containers[0]
+	assert.Equal(t, "custom", deployment.Name)
+	assert.Equal(t, int32(3), *deployment.Spec.Replicas)
+	assert.Equal(t, "redis", container.Image)
+	assert.Equal(t, "qa", deployment.Labels["team"])
+	assert.Equal(t, "qa", deployment.Spec.Template.Labels["team"])
+	assert.Equal(t, resources, container.Resources)
+	assert.Equal(t, probe, container.LivenessProbe)
+	assert.Equal(t, probe, container.ReadinessProbe)
+	assert.Equal(t, *strategy, deployment.Spec.Strategy)
+}
+
+func TestNewTestDeploymentZeroReplicas(t *testing.T) {
+	deployment, err := NewTestDeployment(DeploymentOpts{Namespace: "test-ns", Replicas: pointer.Int32(0)})
+	require.NoError(t, err)
+	assert.Equal(t, int32(0), *deployment.Spec.Replicas)
+}
+
+func TestNewTestDeploymentValidation(t *testing.T) {
+	_, err := NewTestDeployment(DeploymentOpts{})
+	assert.Error(t, err, "namespace is required")
+
+	_, err = NewTestDeployment(DeploymentOpts{Namespace: "test-ns", Replicas: pointer.Int32(-1)})
+	assert.Error(t, err, "negative replicas are invalid")
+}
@@ -0,0 +1,55 @@
+package integration
+
+import (
+	"testing"
+
+	"github.com/rancher/rancher/tests/v2/actions/settings"
+	"github.com/rancher/shepherd/clients/rancher"
+	"github.com/rancher/shepherd/pkg/session"
+	"github.com/stretchr/testify/suite"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type SettingsSuite struct {
+	suite.Suite
+	client  *rancher.Client
+	session *session.Session
+}
+
+func (s *SettingsSuite) TearDownSuite() {
+	s.session.Cleanup()
+}
+
+func (s *SettingsSuite) SetupSuite() {
+	testSession := session.NewSession()
+	s.session = testSession
+
+	client, err := rancher.NewClient("", testSession)
+	s.Require().NoError(err)
+	s.client = client
+}
+
+func (s *SettingsSuite) TestGetViaAPIMatchesReconciledValue() {
+	for _, name := range []string{"server-url", "server-version", "ui-index"} {
+		s.Run(name, func() {
+			reconciled, err := s.client.WranglerContext.Mgmt.Setting().Get(name, metav1.GetOptions{})
+			s.Require().NoError(err)
+
+			setting, err := settings.GetViaAPI(s.client, name)
+			s.Require().NoError(err)
+
+			s.Equal(reconciled.Value, setting.Value)
+			s.Equal(reconciled.Default, setting.Default)
+			s.Equal(reconciled.Source, setting.Source)
+		})
+	}
+}
+
+func (s *SettingsSuite) TestGetViaAPIUnknownSetting() {
+	_, err := settings.GetViaAPI(s.client, "does-not-exist")
+	s.Error(err)
+}
+
+func TestSettingsSuite(t *testing.T) {
+	suite.Run(t, new(SettingsSuite))
+}
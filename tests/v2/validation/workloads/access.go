@@ -0,0 +1,72 @@
+package workloads
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	"github.com/stretchr/testify/require"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	authorizationclientv1 "k8s.io/client-go/kubernetes/typed/authorization/v1"
+)
+
+// workloadAccess lists the resources and verbs the workload validation needs in the test namespace.
+var workloadAccess = []authorizationv1.ResourceAttributes{
+	{Group: "apps", Resource: "deployments", Verb: "create"},
+	{Group: "apps", Resource: "deployments", Verb: "get"},
+	{Group: "apps", Resource: "deployments", Verb: "list"},
+	{Group: "apps", Resource: "deployments", Verb: "watch"},
+	{Group: "apps", Resource: "deployments", Verb: "update"},
+	{Group: "apps", Resource: "deployments", Verb: "delete"},
+	{Group: "apps", Resource: "replicasets", Verb: "list"},
+	{Group: "", Resource: "pods", Verb: "get"},
+	{Group: "", Resource: "pods", Verb: "list"},
+	{Group: "", Resource: "pods", Verb: "watch"},
+}
+
+// RequireWorkloadAccess skips the test if the client's user is not allowed to manage workloads in the namespace of the
+// cluster, instead of failing deep in the validation with a Forbidden error.
+func RequireWorkloadAccess(t *testing.T, client *rancher.Client, clusterID, namespaceName string) {
+	t.Helper()
+
+	wranglerContext, err := getWranglerContext(client, clusterID)
+	require.NoError(t, err)
+
+	clientset, err := kubernetes.NewForConfig(wranglerContext.RESTConfig)
+	require.NoError(t, err)
+
+	requireWorkloadAccess(t, clientset.AuthorizationV1().SelfSubjectAccessReviews(), namespaceName)
+}
+
+func requireWorkloadAccess(t *testing.T, reviews authorizationclientv1.SelfSubjectAccessReviewInterface, namespaceName string) {
+	t.Helper()
+
+	var denied []string
+	for _, attributes := range workloadAccess {
+		attributes.Namespace = namespaceName
+		review, err := reviews.Create(context.TODO(), &authorizationv1.SelfSubjectAccessReview{
+			Spec: authorizationv1.SelfSubjectAccessReviewSpec{ResourceAttributes: &attributes},
+		}, metav1.CreateOptions{})
+		require.NoError(t, err)
+
+		if !review.Status.Allowed {
+			denied = append(denied, fmt.Sprintf("%s %s", attributes.Verb, qualifiedResource(attributes)))
+		}
+	}
+
+	if len(denied) > 0 {
+		t.Skipf("skipping: user is not allowed to %s in namespace %s", strings.Join(denied, ", "), namespaceName)
+	}
+}
+
+func qualifiedResource(attributes authorizationv1.ResourceAttributes) string {
+	if attributes.Group == "" {
+		return attributes.Resource
+	}
+
+	return attributes.Resource + "." + attributes.Group
+}
@@ -0,0 +1,45 @@
+package workloads
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// newAccessReviewClientset returns a fake clientset answering access reviews with allowed unless the verb and resource
+// are in forbidden.
+func newAccessReviewClientset(forbidden map[string]bool) *fake.Clientset {
+	clientset := fake.NewSimpleClientset()
+	clientset.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+		attributes := review.Spec.ResourceAttributes
+		review.Status.Allowed = !forbidden[attributes.Verb+" "+attributes.Resource]
+		return true, review, nil
+	})
+
+	return clientset
+}
+
+func runRequireWorkloadAccess(t *testing.T, forbidden map[string]bool) bool {
+	var skipped bool
+	t.Run("workload access", func(st *testing.T) {
+		defer func() { skipped = st.Skipped() }()
+
+		reviews := newAccessReviewClientset(forbidden).AuthorizationV1().SelfSubjectAccessReviews()
+		requireWorkloadAccess(st, reviews, "default")
+	})
+
+	return skipped
+}
+
+func TestRequireWorkloadAccessAllowed(t *testing.T) {
+	assert.False(t, runRequireWorkloadAccess(t, nil))
+}
+
+func TestRequireWorkloadAccessForbidden(t *testing.T) {
+	assert.True(t, runRequireWorkloadAccess(t, map[string]bool{"create deployments": true}))
+}
@@ -0,0 +1,116 @@
+package workloads
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	"github.com/rancher/shepherd/extensions/defaults"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const progressDeadlineExceededReason = "ProgressDeadlineExceeded"
+
+// verifyAutoRollbackOnFailure updates the deployment to the bad image and waits for the rollout to exceed its progress
+// deadline. Kubernetes does not roll back automatically, so it then verifies that the bad rollout was halted: no pod
+// with the bad image became ready and enough pods of the previous revision are still ready to honor maxUnavailable.
+func verifyAutoRollbackOnFailure(client *rancher.Client, clusterID, namespaceName string, deployment *appv1.Deployment, badImage string) error {
+	wranglerContext, err := getWranglerContext(client, clusterID)
+	if err != nil {
+		return err
+	}
+
+	return verifyBadRolloutHalted(wranglerContext.Apps.Deployment(), wranglerContext.Core.Pod(), namespaceName, deployment, badImage, rolloutPollInterval, progressDeadline(deployment)+defaults.OneMinuteTimeout)
+}
+
+func verifyBadRolloutHalted(deployments deploymentClient, pods podLister, namespaceName string, deployment *appv1.Deployment, badImage string, interval, timeout time.Duration) error {
+	latestDeployment, err := deployments.Get(namespaceName, deployment.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	for i := range latestDeployment.Spec.Template.Spec.Containers {
+		latestDeployment.Spec.Template.Spec.Containers[i].Image = badImage
+	}
+
+	if _, err := deployments.Update(latestDeployment); err != nil {
+		return err
+	}
+
+	err = waitForDeploymentCondition(deployments, namespaceName, deployment.Name, appv1.DeploymentProgressing, corev1.ConditionFalse, interval, timeout)
+	if err != nil {
+		return err
+	}
+
+	haltedDeployment, err := deployments.Get(namespaceName, deployment.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	progressing := getDeploymentCondition(haltedDeployment.Status, appv1.DeploymentProgressing)
+	if progressing == nil || progressing.Reason != progressDeadlineExceededReason {
+		return fmt.Errorf("expected deployment %s to report %s, got %+v", deployment.Name, progressDeadlineExceededReason, progressing)
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(haltedDeployment.Spec.Selector)
+	if err != nil {
+		return err
+	}
+
+	podList, err := pods.List(namespaceName, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return err
+	}
+
+	return checkBadRolloutHalted(haltedDeployment, podList.Items, badImage)
+}
+
+// checkBadRolloutHalted returns an error if a pod running the bad image is ready, or if fewer pods of the previous
+// revision are ready than the deployment's maxUnavailable allows.
+func checkBadRolloutHalted(deployment *appv1.Deployment, pods []corev1.Pod, badImage string) error {
+	readyOldPods := 0
+	for _, pod := range pods {
+		if pod.DeletionTimestamp != nil || !isPodReady(pod) {
+			continue
+		}
+
+		if podRunsImage(pod, badImage) {
+			return fmt.Errorf("pod %s with bad image %s is ready", pod.Name, badImage)
+		}
+		readyOldPods++
+	}
+
+	replicas := 1
+	if deployment.Spec.Replicas != nil {
+		replicas = int(*deployment.Spec.Replicas)
+	}
+
+	maxUnavailable := intstr.FromString("25%")
+	if deployment.Spec.Strategy.RollingUpdate != nil && deployment.Spec.Strategy.RollingUpdate.MaxUnavailable != nil {
+		maxUnavailable = *deployment.Spec.Strategy.RollingUpdate.MaxUnavailable
+	}
+
+	unavailable, err := intstr.GetScaledValueFromIntOrPercent(&maxUnavailable, replicas, false)
+	if err != nil {
+		return err
+	}
+
+	if want := replicas - unavailable; readyOldPods < want {
+		return fmt.Errorf("expected at least %d ready pods of the previous revision, found %d", want, readyOldPods)
+	}
+
+	return nil
+}
+
+func podRunsImage(pod corev1.Pod, image string) bool {
+	for _, container := range pod.Spec.Containers {
+		if container.Image == image {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,109 @@
+package workloads
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+)
+
+const badImageName = "nginx:does-not-exist"
+
+// fakeBadRollout simulates a rollout to an image that never becomes ready: after the update, one pod of the old
+// revision is replaced by a pod that is not ready, and the progress deadline is exceeded.
+type fakeBadRollout struct {
+	*fakeDeployments
+	updated bool
+	oldPods int
+}
+
+func newFakeBadRollout(deployment *appv1.Deployment, oldPods int) *fakeBadRollout {
+	f := &fakeBadRollout{fakeDeployments: newFakeDeployments(deployment), oldPods: oldPods}
+	f.onGet = func(deployment *appv1.Deployment) {
+		if f.updated {
+			deployment.Status.Conditions = []appv1.DeploymentCondition{{
+				Type:   appv1.DeploymentProgressing,
+				Status: corev1.ConditionFalse,
+				Reason: progressDeadlineExceededReason,
+			}}
+		}
+	}
+	f.onUpdate = func(*appv1.Deployment) {
+		f.updated = true
+	}
+
+	return f
+}
+
+func (f *fakeBadRollout) List(namespace string, opts metav1.ListOptions) (*corev1.PodList, error) {
+	ready := []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}
+
+	var pods []corev1.Pod
+	for i := 0; i < f.oldPods; i++ {
+		pods = append(pods, corev1.Pod{
+			Spec:   corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: nginxImageName}}},
+			Status: corev1.PodStatus{Conditions: ready},
+		})
+	}
+	pods = append(pods, corev1.Pod{
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: badImageName}}},
+	})
+
+	return &corev1.PodList{Items: pods}, nil
+}
+
+func TestVerifyBadRolloutHalted(t *testing.T) {
+	tests := []struct {
+		name    string
+		oldPods int
+		wantErr bool
+	}{
+		{
+			name:    "old pods intact",
+			oldPods: 3,
+		},
+		{
+			name:    "old pods lost",
+			oldPods: 1,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deployment := newTestDeploymentWithImage("web", nginxImageName)
+			deployment.Spec.Replicas = pointer.Int32(4)
+			deployment.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}
+
+			rollout := newFakeBadRollout(deployment, tt.oldPods)
+
+			err := verifyBadRolloutHalted(rollout, rollout, "default", deployment, badImageName, time.Millisecond, time.Second)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			require.True(t, rollout.updated)
+			assert.Equal(t, badImageName, rollout.deployment.Spec.Template.Spec.Containers[0].Image)
+		})
+	}
+}
+
+func TestCheckBadRolloutHaltedBadPodReady(t *testing.T) {
+	deployment := newTestDeploymentWithImage("web", nginxImageName)
+	deployment.Spec.Replicas = pointer.Int32(1)
+
+	pods := []corev1.Pod{{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-bad"},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: badImageName}}},
+		Status:     corev1.PodStatus{Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}},
+	}}
+
+	err := checkBadRolloutHalted(deployment, pods, badImageName)
+	assert.ErrorContains(t, err, "pod web-bad with bad image")
+}
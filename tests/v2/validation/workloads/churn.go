@@ -0,0 +1,67 @@
+package workloads
+
+import (
+	"github.com/rancher/shepherd/clients/rancher"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// podWatcher is the subset of the wrangler Pod client needed to watch pods from a consistent starting point.
+type podWatcher interface {
+	List(namespace string, opts metav1.ListOptions) (*corev1.PodList, error)
+	Watch(namespace string, opts metav1.ListOptions) (watch.Interface, error)
+}
+
+// CountPodChurn runs during and returns how many pods of the deployment were created or deleted while it ran, e.g.
+// to detect a rollout strategy that replaces more pods than needed. A replaced pod counts twice.
+func CountPodChurn(client *rancher.Client, clusterID, namespaceName string, deployment *appv1.Deployment, during func() error) (int, error) {
+	wranglerContext, err := getWranglerContext(client, clusterID)
+	if err != nil {
+		return 0, err
+	}
+
+	return countPodChurn(wranglerContext.Core.Pod(), namespaceName, deployment, during)
+}
+
+func countPodChurn(pods podWatcher, namespaceName string, deployment *appv1.Deployment, during func() error) (int, error) {
+	selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		return 0, err
+	}
+
+	// Start watching at the resource version of the list, so that the pods that already exist are not reported as added.
+	podList, err := pods.List(namespaceName, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return 0, err
+	}
+
+	watchInterface, err := pods.Watch(namespaceName, metav1.ListOptions{
+		LabelSelector:   selector.String(),
+		ResourceVersion: podList.ResourceVersion,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	churn := 0
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for event := range watchInterface.ResultChan() {
+			if event.Type == watch.Added || event.Type == watch.Deleted {
+				churn++
+			}
+		}
+	}()
+
+	err = during()
+	watchInterface.Stop()
+	<-done
+	if err != nil {
+		return 0, err
+	}
+
+	return churn, nil
+}
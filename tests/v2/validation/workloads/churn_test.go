@@ -0,0 +1,78 @@
+package workloads
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// fakePodChurn serves a fixed list of existing pods and a fake watch the test emits pod events on.
+type fakePodChurn struct {
+	existing []corev1.Pod
+	watcher  *watch.FakeWatcher
+	watchRV  string
+}
+
+func (f *fakePodChurn) List(namespace string, opts metav1.ListOptions) (*corev1.PodList, error) {
+	return &corev1.PodList{ListMeta: metav1.ListMeta{ResourceVersion: "42"}, Items: f.existing}, nil
+}
+
+func (f *fakePodChurn) Watch(namespace string, opts metav1.ListOptions) (watch.Interface, error) {
+	f.watchRV = opts.ResourceVersion
+	return f.watcher, nil
+}
+
+func TestCountPodChurn(t *testing.T) {
+	deployment := newTestDeploymentWithImage("web", nginxImageName)
+	deployment.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "test"}}
+
+	fake := &fakePodChurn{
+		existing: []corev1.Pod{newTestPod("web-old-0", "node-1"), newTestPod("web-old-1", "node-1")},
+		watcher:  watch.NewFake(),
+	}
+
+	// Replace both pods and modify one of the new pods, which is not churn.
+	during := func() error {
+		for i := range fake.existing {
+			newPod := newTestPod(fmt.Sprintf("web-new-%d", i), "node-2")
+			fake.watcher.Add(&newPod)
+			fake.watcher.Delete(&fake.existing[i])
+		}
+		modified := newTestPod("web-new-0", "node-2")
+		fake.watcher.Modify(&modified)
+		return nil
+	}
+
+	churn, err := countPodChurn(fake, "default", deployment, during)
+	require.NoError(t, err)
+	assert.Equal(t, 4, churn)
+	assert.Equal(t, "42", fake.watchRV, "the watch should start at the resource version of the list")
+}
+
+func TestCountPodChurnNoChurn(t *testing.T) {
+	deployment := newTestDeploymentWithImage("web", nginxImageName)
+	deployment.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "test"}}
+
+	fake := &fakePodChurn{existing: []corev1.Pod{newTestPod("web-0", "node-1")}, watcher: watch.NewFake()}
+
+	churn, err := countPodChurn(fake, "default", deployment, func() error { return nil })
+	require.NoError(t, err)
+	assert.Equal(t, 0, churn)
+}
+
+func TestCountPodChurnOperationError(t *testing.T) {
+	deployment := newTestDeploymentWithImage("web", nginxImageName)
+	deployment.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "test"}}
+
+	fake := &fakePodChurn{watcher: watch.NewFake()}
+
+	_, err := countPodChurn(fake, "default", deployment, func() error { return errors.New("upgrade failed") })
+	assert.EqualError(t, err, "upgrade failed")
+	assert.True(t, fake.watcher.IsStopped())
+}
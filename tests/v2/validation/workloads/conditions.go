@@ -0,0 +1,59 @@
+package workloads
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kwait "k8s.io/apimachinery/pkg/util/wait"
+)
+
+// WaitForDeploymentCondition waits until the deployment reports the condition with the given status. On timeout, the
+// returned error includes the condition as it was last observed, e.g. Progressing=False with reason
+// ProgressDeadlineExceeded.
+func WaitForDeploymentCondition(client *rancher.Client, clusterID, namespaceName, name string, condType appv1.DeploymentConditionType, status corev1.ConditionStatus, timeout time.Duration) error {
+	wranglerContext, err := getWranglerContext(client, clusterID)
+	if err != nil {
+		return err
+	}
+
+	return waitForDeploymentCondition(wranglerContext.Apps.Deployment(), namespaceName, name, condType, status, rolloutPollInterval, timeout)
+}
+
+func waitForDeploymentCondition(deployments deploymentClient, namespaceName, name string, condType appv1.DeploymentConditionType, status corev1.ConditionStatus, interval, timeout time.Duration) error {
+	var observed *appv1.DeploymentCondition
+	err := kwait.PollUntilContextTimeout(context.TODO(), interval, timeout, true, func(ctx context.Context) (bool, error) {
+		deployment, err := deployments.Get(namespaceName, name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+
+		observed = getDeploymentCondition(deployment.Status, condType)
+		return observed != nil && observed.Status == status, nil
+	})
+	if errors.Is(err, context.DeadlineExceeded) {
+		if observed == nil {
+			return fmt.Errorf("timed out waiting for deployment %s to have condition %s=%s, the condition was not reported", name, condType, status)
+		}
+		return fmt.Errorf("timed out waiting for deployment %s to have condition %s=%s, last observed %s=%s with reason %q: %s",
+			name, condType, status, observed.Type, observed.Status, observed.Reason, observed.Message)
+	}
+
+	return err
+}
+
+// getDeploymentCondition returns the condition of the given type, or nil if the deployment does not report it.
+func getDeploymentCondition(status appv1.DeploymentStatus, condType appv1.DeploymentConditionType) *appv1.DeploymentCondition {
+	for i := range status.Conditions {
+		if status.Conditions[i].Type == condType {
+			return &status.Conditions[i]
+		}
+	}
+
+	return nil
+}
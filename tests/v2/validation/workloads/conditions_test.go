@@ -0,0 +1,68 @@
+package workloads
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestWaitForDeploymentCondition(t *testing.T) {
+	progressing := appv1.DeploymentCondition{Type: appv1.DeploymentProgressing, Status: corev1.ConditionTrue, Reason: "ReplicaSetUpdated"}
+	unavailable := appv1.DeploymentCondition{Type: appv1.DeploymentAvailable, Status: corev1.ConditionFalse, Reason: "MinimumReplicasUnavailable"}
+	available := appv1.DeploymentCondition{Type: appv1.DeploymentAvailable, Status: corev1.ConditionTrue, Reason: "MinimumReplicasAvailable"}
+	deadlineExceeded := appv1.DeploymentCondition{
+		Type:    appv1.DeploymentProgressing,
+		Status:  corev1.ConditionFalse,
+		Reason:  "ProgressDeadlineExceeded",
+		Message: `ReplicaSet "web-abc" has timed out progressing.`,
+	}
+
+	tests := []struct {
+		name       string
+		conditions [][]appv1.DeploymentCondition
+		condType   appv1.DeploymentConditionType
+		status     corev1.ConditionStatus
+		wantErr    string
+	}{
+		{
+			name:       "condition reached",
+			conditions: [][]appv1.DeploymentCondition{{progressing, unavailable}, {progressing, available}},
+			condType:   appv1.DeploymentAvailable,
+			status:     corev1.ConditionTrue,
+		},
+		{
+			name:       "progress deadline exceeded",
+			conditions: [][]appv1.DeploymentCondition{{deadlineExceeded, unavailable}},
+			condType:   appv1.DeploymentProgressing,
+			status:     corev1.ConditionTrue,
+			wantErr:    `last observed Progressing=False with reason "ProgressDeadlineExceeded": ReplicaSet "web-abc" has timed out progressing.`,
+		},
+		{
+			name:       "condition not reported",
+			conditions: [][]appv1.DeploymentCondition{{progressing}},
+			condType:   appv1.DeploymentAvailable,
+			status:     corev1.ConditionTrue,
+			wantErr:    "the condition was not reported",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Report the condition lists in order, repeating the last one once exhausted.
+			deployments := newFakeDeployments(newTestDeploymentWithImage("web", nginxImageName))
+			deployments.onGet = func(deployment *appv1.Deployment) {
+				deployment.Status.Conditions = nthGet(deployments, tt.conditions)
+			}
+
+			err := waitForDeploymentCondition(deployments, "default", "web", tt.condType, tt.status, time.Millisecond, 50*time.Millisecond)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package workloads
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	"github.com/rancher/shepherd/extensions/defaults"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	kwait "k8s.io/apimachinery/pkg/util/wait"
+)
+
+// configChecksumAnnotation is the pod template annotation holding the checksum of the configmap, which apps, e.g.
+// deployed by Helm charts, update to roll their pods when the config changes.
+const configChecksumAnnotation = "checksum/config"
+
+// verifyConfigReload verifies that the deployment reloads its config: after mutate changed the configmap, the checksum
+// annotation of the pod template must change and all pods must be replaced.
+func verifyConfigReload(client *rancher.Client, clusterID, namespaceName string, deployment *appv1.Deployment, configMapName string, mutate func() error) error {
+	wranglerContext, err := getWranglerContext(client, clusterID)
+	if err != nil {
+		return err
+	}
+
+	return configReload(wranglerContext.Apps.Deployment(), wranglerContext.Core.Pod(), namespaceName, deployment, configMapName, mutate, rolloutPollInterval, defaults.FiveMinuteTimeout)
+}
+
+func configReload(deployments deploymentClient, pods podLister, namespaceName string, deployment *appv1.Deployment, configMapName string, mutate func() error, interval, timeout time.Duration) error {
+	before, err := deployments.Get(namespaceName, deployment.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	if !referencesConfigMap(before.Spec.Template.Spec, configMapName) {
+		return fmt.Errorf("deployment %s does not use configmap %s", deployment.Name, configMapName)
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(before.Spec.Selector)
+	if err != nil {
+		return err
+	}
+
+	podList, err := pods.List(namespaceName, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return err
+	}
+
+	beforeUIDs := map[types.UID]bool{}
+	for _, pod := range podList.Items {
+		beforeUIDs[pod.UID] = true
+	}
+
+	if err := mutate(); err != nil {
+		return fmt.Errorf("failed to change configmap %s: %w", configMapName, err)
+	}
+
+	beforeChecksum := before.Spec.Template.Annotations[configChecksumAnnotation]
+	var waitErr error
+	err = kwait.PollUntilContextTimeout(context.TODO(), interval, timeout, true, func(ctx context.Context) (bool, error) {
+		current, err := deployments.Get(namespaceName, deployment.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+
+		if current.Spec.Template.Annotations[configChecksumAnnotation] == beforeChecksum {
+			waitErr = fmt.Errorf("annotation %s of deployment %s was not updated", configChecksumAnnotation, deployment.Name)
+			return false, nil
+		}
+		if !isRolloutComplete(current) {
+			waitErr = fmt.Errorf("rollout of deployment %s is not complete", deployment.Name)
+			return false, nil
+		}
+
+		podList, err := pods.List(namespaceName, metav1.ListOptions{LabelSelector: selector.String()})
+		if err != nil {
+			return false, err
+		}
+
+		replicas := int32(1)
+		if current.Spec.Replicas != nil {
+			replicas = *current.Spec.Replicas
+		}
+
+		waitErr = checkPodsRecreated(podList.Items, beforeUIDs, int(replicas))
+		return waitErr == nil, nil
+	})
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("timed out waiting for deployment %s to reload configmap %s: %w", deployment.Name, configMapName, waitErr)
+	}
+
+	return err
+}
+
+// referencesConfigMap returns true if the pod spec mounts the configmap or reads env vars from it.
+func referencesConfigMap(podSpec corev1.PodSpec, configMapName string) bool {
+	for _, volume := range podSpec.Volumes {
+		if volume.ConfigMap != nil && volume.ConfigMap.Name == configMapName {
+			return true
+		}
+	}
+
+	containers := append(append([]corev1.Container{}, podSpec.InitContainers...), podSpec.Containers...)
+	for _, container := range containers {
+		for _, envFrom := range container.EnvFrom {
+			if envFrom.ConfigMapRef != nil && envFrom.ConfigMapRef.Name == configMapName {
+				return true
+			}
+		}
+		for _, env := range container.Env {
+			if env.ValueFrom != nil && env.ValueFrom.ConfigMapKeyRef != nil && env.ValueFrom.ConfigMapKeyRef.Name == configMapName {
+				return true
+			}
+		}
+	}
+
+	return false
+}
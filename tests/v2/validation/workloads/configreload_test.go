@@ -0,0 +1,106 @@
+package workloads
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/pointer"
+)
+
+// fakeConfigReload simulates an app that updates the checksum annotation of its pod template, which rolls its pods,
+// when its configmap changes. With ignoreChanges set, configmap changes are not picked up.
+type fakeConfigReload struct {
+	*fakeDeployments
+	config        string
+	ignoreChanges bool
+}
+
+func newFakeConfigReload() *fakeConfigReload {
+	deployment := newTestDeploymentWithImage("web", nginxImageName)
+	deployment.Spec.Replicas = pointer.Int32(2)
+	deployment.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}
+	deployment.Spec.Template.Annotations = map[string]string{configChecksumAnnotation: "v1"}
+	deployment.Spec.Template.Spec.Volumes = []corev1.Volume{{
+		Name:         "config",
+		VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: "web-config"}}},
+	}}
+
+	return &fakeConfigReload{fakeDeployments: newFakeDeployments(deployment), config: "v1"}
+}
+
+func (f *fakeConfigReload) mutate() error {
+	f.config = "v2"
+	if !f.ignoreChanges {
+		f.deployment.Spec.Template.Annotations[configChecksumAnnotation] = f.config
+		f.deployment.Generation++
+	}
+
+	return nil
+}
+
+func (f *fakeConfigReload) List(namespace string, opts metav1.ListOptions) (*corev1.PodList, error) {
+	checksum := f.deployment.Spec.Template.Annotations[configChecksumAnnotation]
+
+	var pods []corev1.Pod
+	for i := int32(0); i < *f.deployment.Spec.Replicas; i++ {
+		name := fmt.Sprintf("web-%s-%d", checksum, i)
+		pods = append(pods, corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, UID: types.UID(name)},
+			Status: corev1.PodStatus{
+				Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+			},
+		})
+	}
+
+	return &corev1.PodList{Items: pods}, nil
+}
+
+func TestConfigReload(t *testing.T) {
+	fake := newFakeConfigReload()
+
+	err := configReload(fake, fake, "default", fake.deployment, "web-config", fake.mutate, time.Millisecond, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "v2", fake.deployment.Spec.Template.Annotations[configChecksumAnnotation])
+}
+
+func TestConfigReloadNotPickedUp(t *testing.T) {
+	fake := newFakeConfigReload()
+	fake.ignoreChanges = true
+
+	err := configReload(fake, fake, "default", fake.deployment, "web-config", fake.mutate, time.Millisecond, 20*time.Millisecond)
+	assert.EqualError(t, err, "timed out waiting for deployment web to reload configmap web-config: annotation checksum/config of deployment web was not updated")
+}
+
+func TestConfigReloadUnusedConfigMap(t *testing.T) {
+	fake := newFakeConfigReload()
+
+	mutated := false
+	mutate := func() error {
+		mutated = true
+		return nil
+	}
+
+	err := configReload(fake, fake, "default", fake.deployment, "other-config", mutate, time.Millisecond, time.Second)
+	assert.EqualError(t, err, "deployment web does not use configmap other-config")
+	assert.False(t, mutated)
+}
+
+func TestReferencesConfigMap(t *testing.T) {
+	envFrom := corev1.PodSpec{Containers: []corev1.Container{{
+		EnvFrom: []corev1.EnvFromSource{{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "web-config"}}}},
+	}}}
+	envKey := corev1.PodSpec{InitContainers: []corev1.Container{{
+		Env: []corev1.EnvVar{{Name: "MODE", ValueFrom: &corev1.EnvVarSource{ConfigMapKeyRef: &corev1.ConfigMapKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "web-config"}, Key: "mode"}}}},
+	}}}
+
+	assert.True(t, referencesConfigMap(envFrom, "web-config"))
+	assert.True(t, referencesConfigMap(envKey, "web-config"))
+	assert.False(t, referencesConfigMap(envKey, "other-config"))
+	assert.False(t, referencesConfigMap(corev1.PodSpec{}, "web-config"))
+}
@@ -0,0 +1,101 @@
+package workloads
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	"github.com/rancher/shepherd/extensions/defaults"
+	"github.com/stretchr/testify/require"
+	appv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+// controllerDowntime is how long the simulated controller restart keeps the deployment from being reconciled.
+const controllerDowntime = 10 * time.Second
+
+// restartController restarts the controller that reconciles the deployment. Actually restarting the
+// kube-controller-manager depends on the environment, so by default a restart is simulated by pausing the rollout for
+// the controller's downtime. Tests for environments that can restart the controller can replace it.
+var restartController = func(client *rancher.Client, clusterID, namespaceName, deploymentName string) error {
+	wranglerContext, err := getWranglerContext(client, clusterID)
+	if err != nil {
+		return err
+	}
+
+	return simulateControllerRestart(wranglerContext.Apps.Deployment(), namespaceName, deploymentName, controllerDowntime)
+}
+
+// validateRolloutSurvivesControllerRestart starts an image upgrade of the deployment, restarts the controller while
+// the rollout is in progress and validates that the rollout still completes.
+func validateRolloutSurvivesControllerRestart(t *testing.T, client *rancher.Client, clusterID, namespaceName string, deployment *appv1.Deployment) {
+	wranglerContext, err := getWranglerContext(client, clusterID)
+	require.NoError(t, err)
+
+	newImage := upgradeImage(deployment)
+
+	restart := func() error {
+		return restartController(client, clusterID, namespaceName, deployment.Name)
+	}
+
+	err = rolloutSurvivesRestart(wranglerContext.Apps.Deployment(), wranglerContext.Core.Pod(), namespaceName, deployment, newImage, restart, rolloutPollInterval, defaults.TenMinuteTimeout)
+	require.NoError(t, err)
+}
+
+// upgradeImage returns an image to upgrade the deployment to, which differs from the image of its first container.
+func upgradeImage(deployment *appv1.Deployment) string {
+	if deployment.Spec.Template.Spec.Containers[0].Image == redisImageName {
+		return nginxImageName
+	}
+
+	return redisImageName
+}
+
+func rolloutSurvivesRestart(deployments deploymentClient, pods podLister, namespaceName string, deployment *appv1.Deployment, newImage string, restart func() error, interval, timeout time.Duration) error {
+	latestDeployment, err := deployments.Get(namespaceName, deployment.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	for i := range latestDeployment.Spec.Template.Spec.Containers {
+		latestDeployment.Spec.Template.Spec.Containers[i].Image = newImage
+	}
+
+	updatedDeployment, err := deployments.Update(latestDeployment)
+	if err != nil {
+		return err
+	}
+
+	if err := restart(); err != nil {
+		return fmt.Errorf("failed to restart the controller during the rollout: %w", err)
+	}
+
+	return waitForRolloutWithImage(deployments, pods, namespaceName, updatedDeployment, newImage, interval, timeout)
+}
+
+// simulateControllerRestart pauses the rollout of the deployment for the given downtime and resumes it, as the
+// deployment is not reconciled while its controller is down.
+func simulateControllerRestart(deployments deploymentClient, namespaceName, deploymentName string, downtime time.Duration) error {
+	if err := setDeploymentPaused(deployments, namespaceName, deploymentName, true); err != nil {
+		return err
+	}
+
+	time.Sleep(downtime)
+
+	return setDeploymentPaused(deployments, namespaceName, deploymentName, false)
+}
+
+func setDeploymentPaused(deployments deploymentClient, namespaceName, deploymentName string, paused bool) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		deployment, err := deployments.Get(namespaceName, deploymentName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		deployment.Spec.Paused = paused
+		_, err = deployments.Update(deployment)
+		return err
+	})
+}
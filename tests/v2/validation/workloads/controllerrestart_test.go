@@ -0,0 +1,46 @@
+package workloads
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+)
+
+func TestRolloutSurvivesRestart(t *testing.T) {
+	deployment := newTestDeploymentWithImage("web", nginxImageName)
+	deployment.Spec.Replicas = pointer.Int32(2)
+	deployment.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}
+
+	rollout := newFakeRollout(deployment, 50*time.Millisecond)
+
+	restarted := false
+	restart := func() error {
+		restarted = true
+		assert.Equal(t, redisImageName, rollout.deployment.Spec.Template.Spec.Containers[0].Image, "the controller should be restarted during the rollout")
+		return simulateControllerRestart(rollout, "default", "web", 10*time.Millisecond)
+	}
+
+	err := rolloutSurvivesRestart(rollout, rollout, "default", deployment, redisImageName, restart, 10*time.Millisecond, 5*time.Second)
+	require.NoError(t, err)
+	assert.True(t, restarted)
+	assert.False(t, rollout.deployment.Spec.Paused, "the rollout should be resumed after the restart")
+}
+
+func TestRolloutSurvivesRestartHookError(t *testing.T) {
+	deployment := newTestDeploymentWithImage("web", nginxImageName)
+	deployment.Spec.Replicas = pointer.Int32(1)
+	deployment.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}
+
+	rollout := newFakeRollout(deployment, 0)
+	restart := func() error {
+		return errors.New("controller-manager not found")
+	}
+
+	err := rolloutSurvivesRestart(rollout, rollout, "default", deployment, redisImageName, restart, 10*time.Millisecond, time.Second)
+	assert.ErrorContains(t, err, "controller-manager not found")
+}
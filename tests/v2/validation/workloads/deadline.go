@@ -0,0 +1,92 @@
+package workloads
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	"github.com/rancher/shepherd/extensions/defaults"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kwait "k8s.io/apimachinery/pkg/util/wait"
+)
+
+// defaultProgressDeadlineSeconds is the progress deadline Kubernetes defaults deployments to.
+const defaultProgressDeadlineSeconds = 600
+
+// verifyProgressDeadline verifies that the deployment's progressDeadlineSeconds is wantSeconds, so that rollout
+// failure tests run with the intended deadline.
+func verifyProgressDeadline(client *rancher.Client, clusterID, namespaceName, name string, wantSeconds int32) error {
+	wranglerContext, err := getWranglerContext(client, clusterID)
+	if err != nil {
+		return err
+	}
+
+	deployment, err := wranglerContext.Apps.Deployment().Get(namespaceName, name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	return checkProgressDeadline(deployment, wantSeconds)
+}
+
+// checkProgressDeadline returns an error if the deployment's progress deadline is not wantSeconds.
+func checkProgressDeadline(deployment *appv1.Deployment, wantSeconds int32) error {
+	if got := progressDeadline(deployment); got != time.Duration(wantSeconds)*time.Second {
+		return fmt.Errorf("deployment %s has a progress deadline of %s, expected %s", deployment.Name, got, time.Duration(wantSeconds)*time.Second)
+	}
+
+	return nil
+}
+
+// progressDeadline returns how long the deployment may take to make progress before its rollout is considered failed.
+func progressDeadline(deployment *appv1.Deployment) time.Duration {
+	if deployment.Spec.ProgressDeadlineSeconds != nil {
+		return time.Duration(*deployment.Spec.ProgressDeadlineSeconds) * time.Second
+	}
+
+	return defaultProgressDeadlineSeconds * time.Second
+}
+
+// WaitForRolloutWithinProgressDeadline waits for the rollout of the deployment to complete, for no longer than its
+// progress deadline allows. It fails as soon as the deployment reports that the deadline was exceeded.
+func WaitForRolloutWithinProgressDeadline(client *rancher.Client, clusterID, namespaceName, name string) error {
+	wranglerContext, err := getWranglerContext(client, clusterID)
+	if err != nil {
+		return err
+	}
+
+	return waitForRolloutWithinProgressDeadline(wranglerContext.Apps.Deployment(), namespaceName, name, rolloutPollInterval, defaults.OneMinuteTimeout)
+}
+
+// waitForRolloutWithinProgressDeadline waits up to the progress deadline plus margin, which leaves the controller time
+// to report the exceeded deadline.
+func waitForRolloutWithinProgressDeadline(deployments deploymentClient, namespaceName, name string, interval, margin time.Duration) error {
+	deployment, err := deployments.Get(namespaceName, name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	timeout := progressDeadline(deployment) + margin
+	err = kwait.PollUntilContextTimeout(context.TODO(), interval, timeout, true, func(ctx context.Context) (bool, error) {
+		current, err := deployments.Get(namespaceName, name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+
+		progressing := getDeploymentCondition(current.Status, appv1.DeploymentProgressing)
+		if progressing != nil && progressing.Status == corev1.ConditionFalse && progressing.Reason == progressDeadlineExceededReason {
+			return false, fmt.Errorf("rollout of deployment %s exceeded its progress deadline of %s: %s", name, progressDeadline(current), progressing.Message)
+		}
+
+		return isRolloutComplete(current), nil
+	})
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("timed out waiting %s for the rollout of deployment %s to complete", timeout, name)
+	}
+
+	return err
+}
@@ -0,0 +1,80 @@
+package workloads
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/pointer"
+)
+
+func TestCheckProgressDeadline(t *testing.T) {
+	deployment := newTestDeploymentWithImage("web", nginxImageName)
+	assert.NoError(t, checkProgressDeadline(deployment, defaultProgressDeadlineSeconds))
+
+	deployment.Spec.ProgressDeadlineSeconds = pointer.Int32(30)
+	assert.NoError(t, checkProgressDeadline(deployment, 30))
+	assert.EqualError(t, checkProgressDeadline(deployment, 60), "deployment web has a progress deadline of 30s, expected 1m0s")
+}
+
+func TestWaitForRolloutWithinProgressDeadline(t *testing.T) {
+	progressing := appv1.DeploymentStatus{Replicas: 1}
+	complete := appv1.DeploymentStatus{Replicas: 1, UpdatedReplicas: 1, AvailableReplicas: 1}
+	exceeded := appv1.DeploymentStatus{
+		Replicas: 1,
+		Conditions: []appv1.DeploymentCondition{{
+			Type:    appv1.DeploymentProgressing,
+			Status:  corev1.ConditionFalse,
+			Reason:  progressDeadlineExceededReason,
+			Message: `ReplicaSet "web-abc" has timed out progressing.`,
+		}},
+	}
+
+	tests := []struct {
+		name     string
+		deadline int32
+		statuses []appv1.DeploymentStatus
+		wantErr  string
+	}{
+		{
+			name:     "rollout completes",
+			deadline: 1,
+			statuses: []appv1.DeploymentStatus{progressing, progressing, complete},
+		},
+		{
+			name:     "progress deadline exceeded",
+			deadline: 1,
+			statuses: []appv1.DeploymentStatus{progressing, exceeded},
+			wantErr:  `rollout of deployment web exceeded its progress deadline of 1s: ReplicaSet "web-abc" has timed out progressing.`,
+		},
+		{
+			name:     "deadline not reported",
+			deadline: 0,
+			statuses: []appv1.DeploymentStatus{progressing},
+			wantErr:  "timed out waiting 50ms for the rollout of deployment web to complete",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deployment := newTestDeploymentWithImage("web", nginxImageName)
+			deployment.Spec.Replicas = pointer.Int32(1)
+			deployment.Spec.ProgressDeadlineSeconds = pointer.Int32(tt.deadline)
+
+			// Report the statuses in order, repeating the last one once exhausted.
+			deployments := newFakeDeployments(deployment)
+			deployments.onGet = func(deployment *appv1.Deployment) {
+				deployment.Status = nthGet(deployments, tt.statuses)
+			}
+
+			err := waitForRolloutWithinProgressDeadline(deployments, "default", "web", time.Millisecond, 50*time.Millisecond)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package workloads
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	"github.com/rancher/shepherd/extensions/defaults"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kwait "k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+// ephemeralContainerClient is the subset of the typed Pod client needed to add ephemeral containers to a pod. The
+// wrangler Pod client does not support the ephemeralcontainers subresource.
+type ephemeralContainerClient interface {
+	Get(ctx context.Context, name string, opts metav1.GetOptions) (*corev1.Pod, error)
+	UpdateEphemeralContainers(ctx context.Context, podName string, pod *corev1.Pod, opts metav1.UpdateOptions) (*corev1.Pod, error)
+}
+
+// AttachEphemeralContainer injects the ephemeral container, e.g. a debug container, into the running pod through the
+// ephemeralcontainers subresource and waits until it runs. An ephemeral container that ran to completion successfully
+// is also accepted, as debug containers running a single command may exit before they are observed running.
+func AttachEphemeralContainer(client *rancher.Client, clusterID, namespaceName, podName string, spec corev1.EphemeralContainer) error {
+	wranglerContext, err := getWranglerContext(client, clusterID)
+	if err != nil {
+		return err
+	}
+
+	clientset, err := kubernetes.NewForConfig(wranglerContext.RESTConfig)
+	if err != nil {
+		return err
+	}
+
+	return attachEphemeralContainer(clientset.CoreV1().Pods(namespaceName), podName, spec, rolloutPollInterval, defaults.FiveMinuteTimeout)
+}
+
+func attachEphemeralContainer(pods ephemeralContainerClient, podName string, spec corev1.EphemeralContainer, interval, timeout time.Duration) error {
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		pod, err := pods.Get(context.TODO(), podName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		for _, container := range pod.Spec.EphemeralContainers {
+			if container.Name == spec.Name {
+				return fmt.Errorf("pod %s already has an ephemeral container %s", podName, spec.Name)
+			}
+		}
+
+		pod.Spec.EphemeralContainers = append(pod.Spec.EphemeralContainers, spec)
+		_, err = pods.UpdateEphemeralContainers(context.TODO(), podName, pod, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	var state corev1.ContainerState
+	err = kwait.PollUntilContextTimeout(context.TODO(), interval, timeout, true, func(ctx context.Context) (bool, error) {
+		pod, err := pods.Get(ctx, podName, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+
+		for _, status := range pod.Status.EphemeralContainerStatuses {
+			if status.Name != spec.Name {
+				continue
+			}
+
+			state = status.State
+			if state.Terminated != nil && state.Terminated.ExitCode != 0 {
+				return false, fmt.Errorf("ephemeral container %s of pod %s exited with code %d: %s", spec.Name, podName, state.Terminated.ExitCode, state.Terminated.Reason)
+			}
+
+			return state.Running != nil || state.Terminated != nil, nil
+		}
+
+		return false, nil
+	})
+	if errors.Is(err, context.DeadlineExceeded) {
+		reason := "no status reported"
+		if state.Waiting != nil {
+			reason = state.Waiting.Reason
+		}
+		return fmt.Errorf("timed out waiting for ephemeral container %s of pod %s to run: %s", spec.Name, podName, reason)
+	}
+
+	return err
+}
@@ -0,0 +1,107 @@
+package workloads
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// fakeEphemeralPod simulates a pod whose ephemeral containers reach the given state a few gets after they were added.
+type fakeEphemeralPod struct {
+	pod        *corev1.Pod
+	state      corev1.ContainerState
+	startAfter int
+	gets       int
+}
+
+func (f *fakeEphemeralPod) Get(ctx context.Context, name string, opts metav1.GetOptions) (*corev1.Pod, error) {
+	pod := f.pod.DeepCopy()
+	if len(pod.Spec.EphemeralContainers) == 0 {
+		return pod, nil
+	}
+
+	f.gets++
+	for _, container := range pod.Spec.EphemeralContainers {
+		state := corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ContainerCreating"}}
+		if f.gets > f.startAfter {
+			state = f.state
+		}
+		pod.Status.EphemeralContainerStatuses = append(pod.Status.EphemeralContainerStatuses, corev1.ContainerStatus{Name: container.Name, State: state})
+	}
+
+	return pod, nil
+}
+
+func (f *fakeEphemeralPod) UpdateEphemeralContainers(ctx context.Context, podName string, pod *corev1.Pod, opts metav1.UpdateOptions) (*corev1.Pod, error) {
+	f.pod.Spec.EphemeralContainers = pod.Spec.EphemeralContainers
+	return f.pod, nil
+}
+
+func newDebugContainer() corev1.EphemeralContainer {
+	return corev1.EphemeralContainer{
+		EphemeralContainerCommon: corev1.EphemeralContainerCommon{Name: "debugger", Image: "busybox", Stdin: true, TTY: true},
+		TargetContainerName:      "app",
+	}
+}
+
+func TestAttachEphemeralContainer(t *testing.T) {
+	tests := []struct {
+		name    string
+		state   corev1.ContainerState
+		timeout time.Duration
+		wantErr string
+	}{
+		{
+			name:    "running",
+			state:   corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+			timeout: time.Second,
+		},
+		{
+			name:    "completed",
+			state:   corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 0, Reason: "Completed"}},
+			timeout: time.Second,
+		},
+		{
+			name:    "failed",
+			state:   corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 1, Reason: "Error"}},
+			timeout: time.Second,
+			wantErr: "ephemeral container debugger of pod web exited with code 1: Error",
+		},
+		{
+			name:    "image pull failure",
+			state:   corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff"}},
+			timeout: 20 * time.Millisecond,
+			wantErr: "timed out waiting for ephemeral container debugger of pod web to run: ImagePullBackOff",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeEphemeralPod{pod: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web"}}, state: tt.state, startAfter: 2}
+
+			err := attachEphemeralContainer(fake, "web", newDebugContainer(), time.Millisecond, tt.timeout)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Len(t, fake.pod.Spec.EphemeralContainers, 1)
+			assert.Equal(t, "debugger", fake.pod.Spec.EphemeralContainers[0].Name)
+		})
+	}
+}
+
+func TestAttachEphemeralContainerAlreadyExists(t *testing.T) {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web"}}
+	pod.Spec.EphemeralContainers = []corev1.EphemeralContainer{newDebugContainer()}
+	fake := &fakeEphemeralPod{pod: pod}
+
+	err := attachEphemeralContainer(fake, "web", newDebugContainer(), time.Millisecond, time.Second)
+	assert.EqualError(t, err, "pod web already has an ephemeral container debugger")
+}
@@ -0,0 +1,169 @@
+package workloads
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	"github.com/rancher/shepherd/extensions/defaults"
+	log "github.com/sirupsen/logrus"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kwait "k8s.io/apimachinery/pkg/util/wait"
+)
+
+// CollectDeploymentEvents returns the events of the deployment, its ReplicaSets and its pods, oldest first. They
+// often explain why a validation failed, e.g. FailedScheduling or FailedMount.
+func CollectDeploymentEvents(client *rancher.Client, clusterID, namespaceName string, deployment *appv1.Deployment) ([]corev1.Event, error) {
+	wranglerContext, err := getWranglerContext(client, clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	eventList, err := wranglerContext.Core.Event().List(namespaceName, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	replicaSetList, err := wranglerContext.Apps.ReplicaSet().List(namespaceName, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	deploymentPods, err := listDeploymentPods(wranglerContext, namespaceName, deployment)
+	if err != nil {
+		return nil, err
+	}
+
+	return filterDeploymentEvents(eventList.Items, deployment, replicaSetList.Items, deploymentPods), nil
+}
+
+// filterDeploymentEvents returns the events involving the deployment, the ReplicaSets it owns or the given pods,
+// sorted by the time they last occurred.
+func filterDeploymentEvents(events []corev1.Event, deployment *appv1.Deployment, replicaSets []appv1.ReplicaSet, pods []corev1.Pod) []corev1.Event {
+	involved := map[string]map[string]bool{
+		"Deployment": {deployment.Name: true},
+		"ReplicaSet": {},
+		"Pod":        {},
+	}
+	for _, replicaSet := range replicaSets {
+		if isOwnedByDeployment(replicaSet, deployment.Name) {
+			involved["ReplicaSet"][replicaSet.Name] = true
+		}
+	}
+	for _, pod := range pods {
+		involved["Pod"][pod.Name] = true
+	}
+
+	var filtered []corev1.Event
+	for _, event := range events {
+		if involved[event.InvolvedObject.Kind][event.InvolvedObject.Name] {
+			filtered = append(filtered, event)
+		}
+	}
+
+	sort.SliceStable(filtered, func(i, j int) bool {
+		return eventTime(filtered[i]).Before(eventTime(filtered[j]))
+	})
+
+	return filtered
+}
+
+// eventTime returns when the event last occurred, falling back to the fields set by older event sources.
+func eventTime(event corev1.Event) time.Time {
+	switch {
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	case !event.FirstTimestamp.IsZero():
+		return event.FirstTimestamp.Time
+	default:
+		return event.CreationTimestamp.Time
+	}
+}
+
+// logDeploymentEvents logs the events of the deployment, so that a failing validation reports what Kubernetes
+// observed instead of only timing out.
+func logDeploymentEvents(client *rancher.Client, clusterID, namespaceName string, deployment *appv1.Deployment) {
+	events, err := CollectDeploymentEvents(client, clusterID, namespaceName, deployment)
+	if err != nil {
+		log.Warnf("Unable to collect the events of deployment %s: %v", deployment.Name, err)
+		return
+	}
+
+	for _, event := range events {
+		log.Infof("%s %s/%s %s: %s", event.Type, event.InvolvedObject.Kind, event.InvolvedObject.Name, event.Reason, event.Message)
+	}
+}
+
+// verifyRolloutEventSequence waits for the rollout of the deployment to complete and verifies that the events of the
+// deployment and its ReplicaSets include the wanted reasons in order, e.g. ScalingReplicaSet followed by
+// SuccessfulCreate. Other events may occur in between.
+func verifyRolloutEventSequence(client *rancher.Client, clusterID, namespaceName string, deployment *appv1.Deployment, wantReasons []string) error {
+	wranglerContext, err := getWranglerContext(client, clusterID)
+	if err != nil {
+		return err
+	}
+
+	return rolloutEventSequence(wranglerContext.Apps.Deployment(), wranglerContext.Apps.ReplicaSet(), wranglerContext.Core.Event(), namespaceName, deployment, wantReasons, rolloutPollInterval, defaults.FiveMinuteTimeout)
+}
+
+func rolloutEventSequence(deployments deploymentClient, replicaSets replicaSetLister, events eventLister, namespaceName string, deployment *appv1.Deployment, wantReasons []string, interval, timeout time.Duration) error {
+	var waitErr error
+	err := kwait.PollUntilContextTimeout(context.TODO(), interval, timeout, true, func(ctx context.Context) (bool, error) {
+		current, err := deployments.Get(namespaceName, deployment.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+
+		if !isRolloutComplete(current) {
+			waitErr = fmt.Errorf("rollout of deployment %s is not complete", deployment.Name)
+			return false, nil
+		}
+
+		eventList, err := events.List(namespaceName, metav1.ListOptions{})
+		if err != nil {
+			return false, err
+		}
+
+		replicaSetList, err := replicaSets.List(namespaceName, metav1.ListOptions{})
+		if err != nil {
+			return false, err
+		}
+
+		// Events are recorded asynchronously, so the sequence may still be incomplete right after the rollout.
+		waitErr = checkEventSequence(filterDeploymentEvents(eventList.Items, current, replicaSetList.Items, nil), wantReasons)
+		return waitErr == nil, nil
+	})
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("timed out waiting for the rollout events of deployment %s: %w", deployment.Name, waitErr)
+	}
+
+	return err
+}
+
+// checkEventSequence returns an error unless the reasons of the events, which must be sorted oldest first, contain
+// the wanted reasons in order.
+func checkEventSequence(events []corev1.Event, wantReasons []string) error {
+	var reasons []string
+	for _, event := range events {
+		reasons = append(reasons, event.Reason)
+	}
+
+	matched := 0
+	for _, reason := range reasons {
+		if matched < len(wantReasons) && reason == wantReasons[matched] {
+			matched++
+		}
+	}
+	if matched < len(wantReasons) {
+		return fmt.Errorf("event %s of %v not found in order in %v", wantReasons[matched], wantReasons, reasons)
+	}
+
+	return nil
+}
@@ -0,0 +1,169 @@
+package workloads
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+)
+
+func newTestEvent(kind, name, reason string, lastTimestamp time.Time) corev1.Event {
+	return corev1.Event{
+		ObjectMeta:     metav1.ObjectMeta{Name: name + "." + reason},
+		InvolvedObject: corev1.ObjectReference{Kind: kind, Name: name},
+		Reason:         reason,
+		LastTimestamp:  metav1.NewTime(lastTimestamp),
+	}
+}
+
+// fakeRolloutEvents returns a deployment whose rollout is complete unless inProgress is set, and lists the events.
+type fakeRolloutEvents struct {
+	*fakeDeployments
+	events     []corev1.Event
+	inProgress bool
+}
+
+func newFakeRolloutEvents(events []corev1.Event) *fakeRolloutEvents {
+	deployment := newTestDeploymentWithImage("web", nginxImageName)
+	deployment.Spec.Replicas = pointer.Int32(2)
+
+	f := &fakeRolloutEvents{fakeDeployments: newFakeDeployments(deployment), events: events}
+	f.onGet = func(deployment *appv1.Deployment) {
+		deployment.Status = newRolledOutStatus(deployment)
+		if f.inProgress {
+			deployment.Status.UpdatedReplicas = 1
+		}
+	}
+
+	return f
+}
+
+func (f *fakeRolloutEvents) List(namespace string, opts metav1.ListOptions) (*corev1.EventList, error) {
+	return &corev1.EventList{Items: f.events}, nil
+}
+
+func newTestRolloutEvents(start time.Time) []corev1.Event {
+	return []corev1.Event{
+		newTestEvent("Deployment", "web", "ScalingReplicaSet", start),
+		newTestEvent("ReplicaSet", "web-5d8f7", "SuccessfulCreate", start.Add(time.Second)),
+		newTestEvent("Deployment", "other", "ScalingReplicaSet", start.Add(2*time.Second)),
+		newTestEvent("Deployment", "web", "ScalingReplicaSet", start.Add(3*time.Second)),
+		newTestEvent("ReplicaSet", "web-5d8f7", "SuccessfulCreate", start.Add(4*time.Second)),
+	}
+}
+
+func TestFilterDeploymentEvents(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	deployment := newTestDeploymentWithImage("web", nginxImageName)
+	replicaSets := []appv1.ReplicaSet{
+		newTestReplicaSet("web", "1", nginxImageName),
+		newTestReplicaSet("other", "1", nginxImageName),
+	}
+	pods := []corev1.Pod{newTestPod("web-1", "node-1")}
+
+	eventTimeOnly := newTestEvent("Pod", "web-1", "FailedMount", time.Time{})
+	eventTimeOnly.EventTime = metav1.NewMicroTime(start.Add(2 * time.Minute))
+
+	events := []corev1.Event{
+		newTestEvent("Pod", "web-1", "FailedScheduling", start.Add(3*time.Minute)),
+		newTestEvent("Deployment", "web", "ScalingReplicaSet", start),
+		newTestEvent("Pod", "other-1", "FailedScheduling", start),
+		newTestEvent("Deployment", "other", "ScalingReplicaSet", start),
+		newTestEvent("ReplicaSet", replicaSets[0].Name, "SuccessfulCreate", start.Add(time.Minute)),
+		newTestEvent("ReplicaSet", replicaSets[1].Name, "SuccessfulCreate", start),
+		eventTimeOnly,
+	}
+
+	filtered := filterDeploymentEvents(events, deployment, replicaSets, pods)
+
+	var reasons []string
+	for _, event := range filtered {
+		reasons = append(reasons, event.InvolvedObject.Kind+"/"+event.Reason)
+	}
+	assert.Equal(t, []string{
+		"Deployment/ScalingReplicaSet",
+		"ReplicaSet/SuccessfulCreate",
+		"Pod/FailedMount",
+		"Pod/FailedScheduling",
+	}, reasons)
+}
+
+func TestCheckEventSequence(t *testing.T) {
+	events := newTestRolloutEvents(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	tests := []struct {
+		name        string
+		wantReasons []string
+		wantErr     string
+	}{
+		{
+			name:        "in order",
+			wantReasons: []string{"ScalingReplicaSet", "SuccessfulCreate"},
+		},
+		{
+			name:        "in order with events in between",
+			wantReasons: []string{"ScalingReplicaSet", "ScalingReplicaSet", "SuccessfulCreate"},
+		},
+		{
+			name:        "no wanted reasons",
+			wantReasons: nil,
+		},
+		{
+			name:        "wrong order",
+			wantReasons: []string{"SuccessfulCreate", "ScalingReplicaSet", "SuccessfulCreate", "ScalingReplicaSet"},
+			wantErr:     "event ScalingReplicaSet of [SuccessfulCreate ScalingReplicaSet SuccessfulCreate ScalingReplicaSet] not found in order in [ScalingReplicaSet SuccessfulCreate ScalingReplicaSet ScalingReplicaSet SuccessfulCreate]",
+		},
+		{
+			name:        "missing reason",
+			wantReasons: []string{"ScalingReplicaSet", "SuccessfulDelete"},
+			wantErr:     "event SuccessfulDelete of [ScalingReplicaSet SuccessfulDelete] not found in order in [ScalingReplicaSet SuccessfulCreate ScalingReplicaSet ScalingReplicaSet SuccessfulCreate]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkEventSequence(events, tt.wantReasons)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestRolloutEventSequence(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	deployment := newTestDeploymentWithImage("web", nginxImageName)
+
+	t.Run("expected order", func(t *testing.T) {
+		// The events are listed unsorted, as the API server does not guarantee their order.
+		events := newTestRolloutEvents(start)
+		events[0], events[4] = events[4], events[0]
+		fake := newFakeRolloutEvents(events)
+
+		err := rolloutEventSequence(fake, fakeQuotaReplicaSets{}, fake, "default", deployment, []string{"ScalingReplicaSet", "SuccessfulCreate", "ScalingReplicaSet"}, time.Millisecond, time.Second)
+		assert.NoError(t, err)
+	})
+
+	t.Run("unexpected order", func(t *testing.T) {
+		fake := newFakeRolloutEvents(newTestRolloutEvents(start))
+
+		err := rolloutEventSequence(fake, fakeQuotaReplicaSets{}, fake, "default", deployment, []string{"SuccessfulCreate", "ScalingReplicaSet", "SuccessfulCreate", "SuccessfulCreate"}, time.Millisecond, 20*time.Millisecond)
+		assert.ErrorContains(t, err, "timed out waiting for the rollout events of deployment web: event SuccessfulCreate of")
+	})
+
+	t.Run("rollout in progress", func(t *testing.T) {
+		fake := newFakeRolloutEvents(newTestRolloutEvents(start))
+		fake.inProgress = true
+
+		err := rolloutEventSequence(fake, fakeQuotaReplicaSets{}, fake, "default", deployment, []string{"ScalingReplicaSet"}, time.Millisecond, 20*time.Millisecond)
+		assert.EqualError(t, err, "timed out waiting for the rollout events of deployment web: rollout of deployment web is not complete")
+	})
+}
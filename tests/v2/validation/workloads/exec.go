@@ -0,0 +1,65 @@
+package workloads
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	"github.com/rancher/shepherd/extensions/kubeconfig"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	restclient "k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+// newExecutor creates the executor used to stream exec sessions, it is replaced in tests.
+var newExecutor = remotecommand.NewSPDYExecutor
+
+// ExecInPod runs the command in the container of the pod and returns its stdout and stderr. Unlike
+// kubeconfig.KubectlExec, it targets a specific container and keeps stderr separate from stdout.
+func ExecInPod(client *rancher.Client, clusterID, namespaceName, podName, containerName string, cmd []string) (stdout, stderr string, err error) {
+	kubeConfig, err := kubeconfig.GetKubeconfig(client, clusterID)
+	if err != nil {
+		return "", "", err
+	}
+
+	restConfig, err := (*kubeConfig).ClientConfig()
+	if err != nil {
+		return "", "", err
+	}
+
+	return execInPod(restConfig, namespaceName, podName, containerName, cmd)
+}
+
+func execInPod(restConfig *restclient.Config, namespaceName, podName, containerName string, cmd []string) (string, string, error) {
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return "", "", err
+	}
+
+	req := clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Name(podName).
+		Namespace(namespaceName).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: containerName,
+			Command:   cmd,
+			Stdout:    true,
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+
+	executor, err := newExecutor(restConfig, "POST", req.URL())
+	if err != nil {
+		return "", "", err
+	}
+
+	var stdout, stderr bytes.Buffer
+	err = executor.StreamWithContext(context.TODO(), remotecommand.StreamOptions{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	})
+
+	return stdout.String(), stderr.String(), err
+}
@@ -0,0 +1,65 @@
+package workloads
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	restclient "k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+// fakeExecutor writes canned output to the streams instead of connecting to a pod.
+type fakeExecutor struct {
+	stdout string
+	stderr string
+	err    error
+}
+
+func (f *fakeExecutor) Stream(options remotecommand.StreamOptions) error {
+	return f.StreamWithContext(context.Background(), options)
+}
+
+func (f *fakeExecutor) StreamWithContext(_ context.Context, options remotecommand.StreamOptions) error {
+	_, _ = io.WriteString(options.Stdout, f.stdout)
+	_, _ = io.WriteString(options.Stderr, f.stderr)
+	return f.err
+}
+
+func useFakeExecutor(t *testing.T, executor *fakeExecutor) *url.URL {
+	requestURL := &url.URL{}
+	original := newExecutor
+	newExecutor = func(_ *restclient.Config, method string, u *url.URL) (remotecommand.Executor, error) {
+		*requestURL = *u
+		return executor, nil
+	}
+	t.Cleanup(func() { newExecutor = original })
+
+	return requestURL
+}
+
+func TestExecInPod(t *testing.T) {
+	requestURL := useFakeExecutor(t, &fakeExecutor{stdout: "worker_processes 4;\n", stderr: "warning\n"})
+
+	stdout, stderr, err := execInPod(&restclient.Config{Host: "https://rancher.example.com"}, "default", "web-1", "nginx", []string{"cat", "/etc/nginx/nginx.conf"})
+	require.NoError(t, err)
+	assert.Equal(t, "worker_processes 4;\n", stdout)
+	assert.Equal(t, "warning\n", stderr)
+
+	assert.Equal(t, "/api/v1/namespaces/default/pods/web-1/exec", requestURL.Path)
+	query := requestURL.Query()
+	assert.Equal(t, "nginx", query.Get("container"))
+	assert.Equal(t, []string{"cat", "/etc/nginx/nginx.conf"}, query["command"])
+}
+
+func TestExecInPodError(t *testing.T) {
+	useFakeExecutor(t, &fakeExecutor{stderr: "cat: can't open 'missing'", err: errors.New("command terminated with exit code 1")})
+
+	_, stderr, err := execInPod(&restclient.Config{Host: "https://rancher.example.com"}, "default", "web-1", "nginx", []string{"cat", "missing"})
+	assert.EqualError(t, err, "command terminated with exit code 1")
+	assert.Equal(t, "cat: can't open 'missing'", stderr)
+}
@@ -0,0 +1,81 @@
+package workloads
+
+import (
+	"sync"
+
+	appv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// fakeDeployments is a deploymentClient storing a single deployment, shared by the fakes simulating how a deployment
+// behaves in a cluster. Get returns a copy of the stored deployment adjusted by onGet, e.g. to report the status of a
+// rollout, and Update stores the deployment with its generation bumped, as the API server does, and calls onUpdate.
+type fakeDeployments struct {
+	mu         sync.Mutex
+	deployment *appv1.Deployment
+	// gets is the number of calls to Get so far, e.g. to report a different status on every call.
+	gets     int
+	onGet    func(deployment *appv1.Deployment)
+	onUpdate func(deployment *appv1.Deployment)
+}
+
+// newFakeDeployments returns a fakeDeployments storing the deployment, for which Get reports a completed rollout of
+// its current generation.
+func newFakeDeployments(deployment *appv1.Deployment) *fakeDeployments {
+	return &fakeDeployments{
+		deployment: deployment,
+		onGet: func(deployment *appv1.Deployment) {
+			deployment.Status = newRolledOutStatus(deployment)
+		},
+	}
+}
+
+func (f *fakeDeployments) Get(namespace, name string, opts metav1.GetOptions) (*appv1.Deployment, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	deployment := f.deployment.DeepCopy()
+	if f.onGet != nil {
+		f.onGet(deployment)
+	}
+	f.gets++
+
+	return deployment, nil
+}
+
+func (f *fakeDeployments) Update(deployment *appv1.Deployment) (*appv1.Deployment, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.deployment = deployment.DeepCopy()
+	f.deployment.Generation++
+	if f.onUpdate != nil {
+		f.onUpdate(f.deployment)
+	}
+
+	return deployment, nil
+}
+
+// nthGet returns the element of values for the current call to Get, repeating the last one once exhausted.
+func nthGet[T any](f *fakeDeployments, values []T) T {
+	if f.gets >= len(values) {
+		return values[len(values)-1]
+	}
+
+	return values[f.gets]
+}
+
+// newRolledOutStatus returns the status of the deployment once all its replicas were updated and are available.
+func newRolledOutStatus(deployment *appv1.Deployment) appv1.DeploymentStatus {
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+
+	return appv1.DeploymentStatus{
+		ObservedGeneration: deployment.Generation,
+		Replicas:           replicas,
+		UpdatedReplicas:    replicas,
+		AvailableReplicas:  replicas,
+	}
+}
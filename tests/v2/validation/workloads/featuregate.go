@@ -0,0 +1,60 @@
+package workloads
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/shepherd/clients/rancher"
+	"github.com/rancher/shepherd/extensions/defaults"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kwait "k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+)
+
+const featureGatePollInterval = 5 * time.Second
+
+// settingClient is the subset of the wrangler Setting client needed to read and update settings.
+type settingClient interface {
+	Get(name string, opts metav1.GetOptions) (*v3.Setting, error)
+	Update(setting *v3.Setting) (*v3.Setting, error)
+}
+
+// verifyFeatureGateEffect sets the setting to value and waits until the check passes on the cluster, e.g. that a
+// workload the setting enables was deployed. The check is retried, as Rancher reconciles the affected components
+// asynchronously. Settings are global, so the setting is always set in the local cluster.
+func verifyFeatureGateEffect(client *rancher.Client, clusterID string, settingName, value string, check func() error) error {
+	return verifySettingEffect(client.WranglerContext.Mgmt.Setting(), clusterID, settingName, value, check, featureGatePollInterval, defaults.FiveMinuteTimeout)
+}
+
+func verifySettingEffect(settings settingClient, clusterID, settingName, value string, check func() error, interval, timeout time.Duration) error {
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		setting, err := settings.Get(settingName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if setting.Source == "env" {
+			return fmt.Errorf("setting %s can not be set because it is from environment variable", settingName)
+		}
+
+		setting.Value = value
+		_, err = settings.Update(setting)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	var checkErr error
+	err = kwait.PollUntilContextTimeout(context.TODO(), interval, timeout, true, func(ctx context.Context) (bool, error) {
+		checkErr = check()
+		return checkErr == nil, nil
+	})
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("setting %s=%q did not take effect on cluster %s: %w", settingName, value, clusterID, checkErr)
+	}
+
+	return err
+}
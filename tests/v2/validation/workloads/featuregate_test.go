@@ -0,0 +1,69 @@
+package workloads
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// fakeSettings stores settings by name.
+type fakeSettings map[string]v3.Setting
+
+func (f fakeSettings) Get(name string, opts metav1.GetOptions) (*v3.Setting, error) {
+	setting, ok := f[name]
+	if !ok {
+		return nil, apierrors.NewNotFound(schema.GroupResource{}, name)
+	}
+
+	return setting.DeepCopy(), nil
+}
+
+func (f fakeSettings) Update(setting *v3.Setting) (*v3.Setting, error) {
+	f[setting.Name] = *setting.DeepCopy()
+	return setting, nil
+}
+
+func TestVerifySettingEffect(t *testing.T) {
+	newSettings := func() fakeSettings {
+		return fakeSettings{
+			"feature":     {ObjectMeta: metav1.ObjectMeta{Name: "feature"}, Default: "false"},
+			"env-feature": {ObjectMeta: metav1.ObjectMeta{Name: "env-feature"}, Value: "false", Source: "env"},
+		}
+	}
+
+	// The workload appears a few polls after the setting was enabled.
+	newCheck := func(settings fakeSettings) func() error {
+		polls := 0
+		return func() error {
+			if settings["feature"].Value != "true" {
+				return errors.New("workload does not exist")
+			}
+			if polls++; polls < 3 {
+				return errors.New("workload does not exist")
+			}
+			return nil
+		}
+	}
+
+	settings := newSettings()
+	err := verifySettingEffect(settings, "local", "feature", "true", newCheck(settings), time.Millisecond, time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, "true", settings["feature"].Value)
+
+	settings = newSettings()
+	err = verifySettingEffect(settings, "local", "feature", "false", newCheck(settings), time.Millisecond, 20*time.Millisecond)
+	assert.ErrorContains(t, err, `setting feature="false" did not take effect on cluster local: workload does not exist`)
+
+	settings = newSettings()
+	err = verifySettingEffect(settings, "local", "env-feature", "true", newCheck(settings), time.Millisecond, time.Second)
+	assert.ErrorContains(t, err, "from environment variable")
+
+	err = verifySettingEffect(settings, "local", "missing", "true", newCheck(settings), time.Millisecond, time.Second)
+	assert.True(t, apierrors.IsNotFound(err))
+}
@@ -0,0 +1,79 @@
+package workloads
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	appv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kwait "k8s.io/apimachinery/pkg/util/wait"
+)
+
+// namespaceDeleter is the subset of the wrangler Namespace client needed to delete a namespace.
+type namespaceDeleter interface {
+	Delete(name string, opts *metav1.DeleteOptions) error
+}
+
+// deploymentLister is the subset of the wrangler Deployment client needed to list deployments.
+type deploymentLister interface {
+	List(namespace string, opts metav1.ListOptions) (*appv1.DeploymentList, error)
+}
+
+// VerifyWorkloadsGarbageCollected deletes the namespace and verifies that its deployments and their pods are removed
+// within the timeout. On timeout, the error lists the remaining resources with their finalizers, which are usually what
+// keeps them from being removed.
+func VerifyWorkloadsGarbageCollected(client *rancher.Client, clusterID, namespaceName string, timeout time.Duration) error {
+	wranglerContext, err := getWranglerContext(client, clusterID)
+	if err != nil {
+		return err
+	}
+
+	return verifyWorkloadsGarbageCollected(wranglerContext.Core.Namespace(), wranglerContext.Apps.Deployment(), wranglerContext.Core.Pod(), namespaceName, rolloutPollInterval, timeout)
+}
+
+func verifyWorkloadsGarbageCollected(namespaces namespaceDeleter, deployments deploymentLister, pods podLister, namespaceName string, interval, timeout time.Duration) error {
+	if err := namespaces.Delete(namespaceName, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("error deleting namespace %s: %w", namespaceName, err)
+	}
+
+	var lingering []string
+	err := kwait.PollUntilContextTimeout(context.TODO(), interval, timeout, true, func(ctx context.Context) (bool, error) {
+		deploymentList, err := deployments.List(namespaceName, metav1.ListOptions{})
+		if err != nil {
+			return false, err
+		}
+
+		podList, err := pods.List(namespaceName, metav1.ListOptions{})
+		if err != nil {
+			return false, err
+		}
+
+		lingering = nil
+		for _, deployment := range deploymentList.Items {
+			lingering = append(lingering, describeLingering("deployment", deployment.ObjectMeta))
+		}
+		for _, pod := range podList.Items {
+			lingering = append(lingering, describeLingering("pod", pod.ObjectMeta))
+		}
+
+		return len(lingering) == 0, nil
+	})
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("timed out waiting for workloads of namespace %s to be removed, remaining: %v", namespaceName, lingering)
+	}
+
+	return err
+}
+
+// describeLingering describes a resource that was not removed, e.g. "deployment web (finalizers: [example.com/hold])".
+func describeLingering(kind string, meta metav1.ObjectMeta) string {
+	if len(meta.Finalizers) == 0 {
+		return fmt.Sprintf("%s %s", kind, meta.Name)
+	}
+
+	return fmt.Sprintf("%s %s (finalizers: %v)", kind, meta.Name, meta.Finalizers)
+}
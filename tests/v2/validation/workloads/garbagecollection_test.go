@@ -0,0 +1,89 @@
+package workloads
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// fakeNamespaceDeletion simulates a namespace whose deployment and pod are removed removeAfter its deletion. A
+// finalizer on the deployment keeps it from being removed, as if its controller never released it.
+type fakeNamespaceDeletion struct {
+	deployment  *appv1.Deployment
+	pod         corev1.Pod
+	deletedAt   time.Time
+	removeAfter time.Duration
+	deleteErr   error
+}
+
+func newFakeNamespaceDeletion(removeAfter time.Duration) *fakeNamespaceDeletion {
+	return &fakeNamespaceDeletion{
+		deployment:  newTestDeploymentWithImage("web", nginxImageName),
+		pod:         newTestPod("web-a", "node-1"),
+		removeAfter: removeAfter,
+	}
+}
+
+func (f *fakeNamespaceDeletion) Delete(name string, opts *metav1.DeleteOptions) error {
+	if f.deleteErr != nil {
+		return f.deleteErr
+	}
+
+	f.deletedAt = time.Now()
+	return nil
+}
+
+func (f *fakeNamespaceDeletion) removed() bool {
+	return !f.deletedAt.IsZero() && time.Since(f.deletedAt) >= f.removeAfter
+}
+
+func (f *fakeNamespaceDeletion) List(namespace string, opts metav1.ListOptions) (*appv1.DeploymentList, error) {
+	if f.removed() && len(f.deployment.Finalizers) == 0 {
+		return &appv1.DeploymentList{}, nil
+	}
+
+	return &appv1.DeploymentList{Items: []appv1.Deployment{*f.deployment}}, nil
+}
+
+// fakeNamespacePods lists the pods of a fakeNamespaceDeletion, whose List already lists its deployments.
+type fakeNamespacePods struct {
+	*fakeNamespaceDeletion
+}
+
+func (f fakeNamespacePods) List(namespace string, opts metav1.ListOptions) (*corev1.PodList, error) {
+	if f.removed() {
+		return &corev1.PodList{}, nil
+	}
+
+	return &corev1.PodList{Items: []corev1.Pod{f.pod}}, nil
+}
+
+func TestVerifyWorkloadsGarbageCollected(t *testing.T) {
+	fake := newFakeNamespaceDeletion(20 * time.Millisecond)
+
+	err := verifyWorkloadsGarbageCollected(fake, fake, fakeNamespacePods{fake}, "default", time.Millisecond, time.Second)
+	require.NoError(t, err)
+	assert.False(t, fake.deletedAt.IsZero())
+}
+
+func TestVerifyWorkloadsGarbageCollectedStuckFinalizer(t *testing.T) {
+	fake := newFakeNamespaceDeletion(0)
+	fake.deployment.Finalizers = []string{"example.com/hold"}
+
+	err := verifyWorkloadsGarbageCollected(fake, fake, fakeNamespacePods{fake}, "default", time.Millisecond, 20*time.Millisecond)
+	assert.EqualError(t, err, "timed out waiting for workloads of namespace default to be removed, remaining: [deployment web (finalizers: [example.com/hold])]")
+}
+
+func TestVerifyWorkloadsGarbageCollectedDeleteError(t *testing.T) {
+	fake := newFakeNamespaceDeletion(0)
+	fake.deleteErr = errors.New("forbidden")
+
+	err := verifyWorkloadsGarbageCollected(fake, fake, fakeNamespacePods{fake}, "default", time.Millisecond, time.Second)
+	assert.EqualError(t, err, "error deleting namespace default: forbidden")
+}
@@ -0,0 +1,45 @@
+package workloads
+
+import (
+	"fmt"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// verifyImagePullPolicy verifies that the container of the running pods of the deployment uses the wanted image pull
+// policy. The API server defaults an unset policy depending on the image tag, Always for :latest and IfNotPresent
+// otherwise, so the pods are checked rather than the pod template.
+func verifyImagePullPolicy(client *rancher.Client, clusterID, namespaceName string, deployment *appv1.Deployment, containerName string, want corev1.PullPolicy) error {
+	return verifyDeploymentPods(client, clusterID, namespaceName, deployment, func(pods []corev1.Pod) error {
+		return checkRunningPods(pods, imagePullPolicyCheck(containerName, want))
+	})
+}
+
+// imagePullPolicyCheck returns a check that fails for a pod whose container is missing or does not use the wanted
+// image pull policy.
+func imagePullPolicyCheck(containerName string, want corev1.PullPolicy) func(corev1.Pod) error {
+	return func(pod corev1.Pod) error {
+		container := findContainer(pod.Spec.Containers, containerName)
+		if container == nil {
+			return fmt.Errorf("pod %s has no container %s", pod.Name, containerName)
+		}
+		if container.ImagePullPolicy != want {
+			return fmt.Errorf("container %s of pod %s has image pull policy %q, expected %q", containerName, pod.Name, container.ImagePullPolicy, want)
+		}
+
+		return nil
+	}
+}
+
+// findContainer returns the container with the given name, or nil if there is none.
+func findContainer(containers []corev1.Container, name string) *corev1.Container {
+	for i := range containers {
+		if containers[i].Name == name {
+			return &containers[i]
+		}
+	}
+
+	return nil
+}
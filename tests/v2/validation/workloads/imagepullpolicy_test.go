@@ -0,0 +1,54 @@
+package workloads
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestImagePullPolicyCheck(t *testing.T) {
+	withPullPolicy := func(pullPolicy corev1.PullPolicy) corev1.Pod {
+		return newRunningTestPod("pod-1", corev1.PodSpec{Containers: []corev1.Container{{Name: "web", Image: nginxImageName, ImagePullPolicy: pullPolicy}}})
+	}
+
+	tests := []struct {
+		name          string
+		pod           corev1.Pod
+		containerName string
+		want          corev1.PullPolicy
+		wantErr       string
+	}{
+		{
+			name:          "matching pull policy",
+			pod:           withPullPolicy(corev1.PullIfNotPresent),
+			containerName: "web",
+			want:          corev1.PullIfNotPresent,
+		},
+		{
+			name:          "mismatched pull policy",
+			pod:           withPullPolicy(corev1.PullIfNotPresent),
+			containerName: "web",
+			want:          corev1.PullAlways,
+			wantErr:       `container web of pod pod-1 has image pull policy "IfNotPresent", expected "Always"`,
+		},
+		{
+			name:          "missing container",
+			pod:           withPullPolicy(corev1.PullAlways),
+			containerName: "sidecar",
+			want:          corev1.PullAlways,
+			wantErr:       "pod pod-1 has no container sidecar",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := imagePullPolicyCheck(tt.containerName, tt.want)(tt.pod)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
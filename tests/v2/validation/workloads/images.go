@@ -0,0 +1,48 @@
+package workloads
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// verifyImagesFromRegistry verifies that every container image of the deployment's pods is pulled from the
+// registry with the given prefix, as expected when images are rewritten to a private mirror in air-gapped setups.
+func verifyImagesFromRegistry(client *rancher.Client, clusterID, namespaceName string, deployment *appv1.Deployment, registryPrefix string) error {
+	wranglerContext, err := getWranglerContext(client, clusterID)
+	if err != nil {
+		return err
+	}
+
+	pods, err := listDeploymentPods(wranglerContext, namespaceName, deployment)
+	if err != nil {
+		return err
+	}
+
+	return checkImagesFromRegistry(pods, registryPrefix)
+}
+
+// checkImagesFromRegistry returns an error listing every init and app container image of the pods that is not
+// prefixed with the registry.
+func checkImagesFromRegistry(pods []corev1.Pod, registryPrefix string) error {
+	prefix := strings.TrimSuffix(registryPrefix, "/") + "/"
+
+	var violations []string
+	for _, pod := range pods {
+		containers := append(append([]corev1.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...)
+		for _, container := range containers {
+			if !strings.HasPrefix(container.Image, prefix) {
+				violations = append(violations, fmt.Sprintf("%s/%s: %s", pod.Name, container.Name, container.Image))
+			}
+		}
+	}
+
+	if len(violations) > 0 {
+		return fmt.Errorf("images not pulled from registry %s: %s", registryPrefix, strings.Join(violations, ", "))
+	}
+
+	return nil
+}
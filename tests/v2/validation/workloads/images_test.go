@@ -0,0 +1,71 @@
+package workloads
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newTestPodWithImages(name string, initImages []string, images ...string) corev1.Pod {
+	pod := corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	for i, image := range initImages {
+		pod.Spec.InitContainers = append(pod.Spec.InitContainers, corev1.Container{Name: "init" + string(rune('a'+i)), Image: image})
+	}
+	for i, image := range images {
+		pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{Name: "app" + string(rune('a'+i)), Image: image})
+	}
+
+	return pod
+}
+
+func TestCheckImagesFromRegistry(t *testing.T) {
+	const registry = "registry.example.com:5000"
+
+	tests := []struct {
+		name    string
+		pods    []corev1.Pod
+		wantErr string
+	}{
+		{
+			name: "all images rewritten",
+			pods: []corev1.Pod{
+				newTestPodWithImages("pod-1", []string{registry + "/library/busybox"}, registry+"/library/nginx", registry+"/library/redis:7"),
+				newTestPodWithImages("pod-2", nil, registry+"/library/nginx"),
+			},
+		},
+		{
+			name: "mix of rewritten and non-rewritten images",
+			pods: []corev1.Pod{
+				newTestPodWithImages("pod-1", nil, registry+"/library/nginx", "docker.io/library/redis:7"),
+			},
+			wantErr: "pod-1/appb: docker.io/library/redis:7",
+		},
+		{
+			name: "init container not rewritten",
+			pods: []corev1.Pod{
+				newTestPodWithImages("pod-1", []string{"busybox"}, registry+"/library/nginx"),
+			},
+			wantErr: "pod-1/inita: busybox",
+		},
+		{
+			name: "registry prefix must match a full host",
+			pods: []corev1.Pod{
+				newTestPodWithImages("pod-1", nil, registry+"0/library/nginx"),
+			},
+			wantErr: "pod-1/appa",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkImagesFromRegistry(tt.pods, registry)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package workloads
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// verifyInitContainerOrder verifies that the init containers of every pod of the deployment ran in the expected order,
+// all succeeded, and that each finished before the next one and the app containers started.
+func verifyInitContainerOrder(client *rancher.Client, clusterID, namespaceName string, deployment *appv1.Deployment, expectedOrder []string) error {
+	wranglerContext, err := getWranglerContext(client, clusterID)
+	if err != nil {
+		return err
+	}
+
+	deploymentPods, err := listDeploymentPods(wranglerContext, namespaceName, deployment)
+	if err != nil {
+		return err
+	}
+
+	for _, pod := range deploymentPods {
+		if err := checkInitContainerOrder(pod, expectedOrder); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkInitContainerOrder checks the init and app container statuses of a single pod.
+func checkInitContainerOrder(pod corev1.Pod, expectedOrder []string) error {
+	var terminated []corev1.ContainerStatus
+	for _, status := range pod.Status.InitContainerStatuses {
+		state := status.State.Terminated
+		if state == nil {
+			return fmt.Errorf("init container %s of pod %s has not completed", status.Name, pod.Name)
+		}
+		if state.ExitCode != 0 {
+			return fmt.Errorf("init container %s of pod %s failed with exit code %d: %s", status.Name, pod.Name, state.ExitCode, state.Reason)
+		}
+		terminated = append(terminated, status)
+	}
+
+	sort.SliceStable(terminated, func(i, j int) bool {
+		return terminated[i].State.Terminated.StartedAt.Before(&terminated[j].State.Terminated.StartedAt)
+	})
+
+	var actualOrder []string
+	var previousFinish metav1.Time
+	for _, status := range terminated {
+		state := status.State.Terminated
+		if state.StartedAt.Before(&previousFinish) {
+			return fmt.Errorf("init container %s of pod %s started before the previous init container finished", status.Name, pod.Name)
+		}
+		previousFinish = state.FinishedAt
+		actualOrder = append(actualOrder, status.Name)
+	}
+
+	if strings.Join(actualOrder, ",") != strings.Join(expectedOrder, ",") {
+		return fmt.Errorf("init containers of pod %s ran in order [%s], expected [%s]", pod.Name, strings.Join(actualOrder, ", "), strings.Join(expectedOrder, ", "))
+	}
+
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.State.Running != nil && status.State.Running.StartedAt.Before(&previousFinish) {
+			return fmt.Errorf("container %s of pod %s started before the init containers finished", status.Name, pod.Name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,119 @@
+package workloads
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var initBaseTime = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func newInitContainerStatus(name string, start, finish int, exitCode int32) corev1.ContainerStatus {
+	return corev1.ContainerStatus{
+		Name: name,
+		State: corev1.ContainerState{
+			Terminated: &corev1.ContainerStateTerminated{
+				ExitCode:   exitCode,
+				StartedAt:  metav1.NewTime(initBaseTime.Add(time.Duration(start) * time.Second)),
+				FinishedAt: metav1.NewTime(initBaseTime.Add(time.Duration(finish) * time.Second)),
+			},
+		},
+	}
+}
+
+func newAppContainerStatus(name string, start int) corev1.ContainerStatus {
+	return corev1.ContainerStatus{
+		Name: name,
+		State: corev1.ContainerState{
+			Running: &corev1.ContainerStateRunning{StartedAt: metav1.NewTime(initBaseTime.Add(time.Duration(start) * time.Second))},
+		},
+	}
+}
+
+func TestCheckInitContainerOrder(t *testing.T) {
+	expectedOrder := []string{"migrate", "seed"}
+
+	tests := []struct {
+		name    string
+		status  corev1.PodStatus
+		wantErr string
+	}{
+		{
+			name: "init containers ran in order before the app",
+			status: corev1.PodStatus{
+				InitContainerStatuses: []corev1.ContainerStatus{
+					newInitContainerStatus("migrate", 0, 5, 0),
+					newInitContainerStatus("seed", 6, 8, 0),
+				},
+				ContainerStatuses: []corev1.ContainerStatus{newAppContainerStatus("app", 9)},
+			},
+		},
+		{
+			name: "init containers ran out of order",
+			status: corev1.PodStatus{
+				InitContainerStatuses: []corev1.ContainerStatus{
+					newInitContainerStatus("migrate", 6, 8, 0),
+					newInitContainerStatus("seed", 0, 5, 0),
+				},
+				ContainerStatuses: []corev1.ContainerStatus{newAppContainerStatus("app", 9)},
+			},
+			wantErr: "ran in order [seed, migrate]",
+		},
+		{
+			name: "init container failed",
+			status: corev1.PodStatus{
+				InitContainerStatuses: []corev1.ContainerStatus{
+					newInitContainerStatus("migrate", 0, 5, 1),
+					newInitContainerStatus("seed", 6, 8, 0),
+				},
+			},
+			wantErr: "init container migrate of pod pod-1 failed with exit code 1",
+		},
+		{
+			name: "init containers overlapped",
+			status: corev1.PodStatus{
+				InitContainerStatuses: []corev1.ContainerStatus{
+					newInitContainerStatus("migrate", 0, 5, 0),
+					newInitContainerStatus("seed", 3, 8, 0),
+				},
+			},
+			wantErr: "started before the previous init container finished",
+		},
+		{
+			name: "app started before init containers finished",
+			status: corev1.PodStatus{
+				InitContainerStatuses: []corev1.ContainerStatus{
+					newInitContainerStatus("migrate", 0, 5, 0),
+					newInitContainerStatus("seed", 6, 8, 0),
+				},
+				ContainerStatuses: []corev1.ContainerStatus{newAppContainerStatus("app", 7)},
+			},
+			wantErr: "container app of pod pod-1 started before the init containers finished",
+		},
+		{
+			name: "init container still running",
+			status: corev1.PodStatus{
+				InitContainerStatuses: []corev1.ContainerStatus{
+					newInitContainerStatus("migrate", 0, 5, 0),
+					{Name: "seed", State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}},
+				},
+			},
+			wantErr: "init container seed of pod pod-1 has not completed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod-1"}, Status: tt.status}
+			err := checkInitContainerOrder(pod, expectedOrder)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package workloads
+
+import (
+	"fmt"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	appv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/yaml"
+)
+
+// clusterAssignedFields are the metadata fields set by the cluster, which must not be part of an exported manifest.
+var clusterAssignedFields = []string{"uid", "resourceVersion", "generation", "creationTimestamp", "deletionTimestamp", "deletionGracePeriodSeconds", "managedFields", "ownerReferences", "selfLink"}
+
+// deploymentApplier is the subset of the wrangler Deployment client needed to create or update a deployment.
+type deploymentApplier interface {
+	Get(namespace, name string, opts metav1.GetOptions) (*appv1.Deployment, error)
+	Create(deployment *appv1.Deployment) (*appv1.Deployment, error)
+	Update(deployment *appv1.Deployment) (*appv1.Deployment, error)
+}
+
+// ExportDeploymentManifest returns the deployment as a YAML manifest suitable for re-applying it, e.g. from a GitOps
+// repository. The status, the revision annotation and the metadata assigned by the cluster are stripped.
+func ExportDeploymentManifest(client *rancher.Client, clusterID, namespaceName, name string) ([]byte, error) {
+	wranglerContext, err := getWranglerContext(client, clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	deployment, err := wranglerContext.Apps.Deployment().Get(namespaceName, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	return exportDeploymentManifest(deployment)
+}
+
+func exportDeploymentManifest(deployment *appv1.Deployment) ([]byte, error) {
+	deployment = deployment.DeepCopy()
+	deployment.APIVersion = appv1.SchemeGroupVersion.String()
+	deployment.Kind = "Deployment"
+	delete(deployment.Annotations, revisionAnnotation)
+
+	object, err := runtime.DefaultUnstructuredConverter.ToUnstructured(deployment)
+	if err != nil {
+		return nil, err
+	}
+
+	unstructured.RemoveNestedField(object, "status")
+	for _, field := range clusterAssignedFields {
+		unstructured.RemoveNestedField(object, "metadata", field)
+	}
+	if annotations, _, _ := unstructured.NestedMap(object, "metadata", "annotations"); len(annotations) == 0 {
+		unstructured.RemoveNestedField(object, "metadata", "annotations")
+	}
+
+	return yaml.Marshal(object)
+}
+
+// ApplyManifest creates the deployment of the manifest, e.g. one returned by ExportDeploymentManifest, or updates its
+// labels, annotations and spec if it exists. A deployment that already matches the manifest is not updated, so
+// re-applying an exported manifest does not start a rollout.
+func ApplyManifest(client *rancher.Client, clusterID string, manifest []byte) error {
+	wranglerContext, err := getWranglerContext(client, clusterID)
+	if err != nil {
+		return err
+	}
+
+	return applyManifest(wranglerContext.Apps.Deployment(), manifest)
+}
+
+func applyManifest(deployments deploymentApplier, manifest []byte) error {
+	var deployment appv1.Deployment
+	if err := yaml.Unmarshal(manifest, &deployment); err != nil {
+		return fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	if deployment.Kind != "Deployment" {
+		return fmt.Errorf("unsupported kind %q in manifest, expected Deployment", deployment.Kind)
+	}
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		existing, err := deployments.Get(deployment.Namespace, deployment.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			_, err = deployments.Create(deployment.DeepCopy())
+			return err
+		}
+		if err != nil {
+			return err
+		}
+
+		desired := deployment.DeepCopy()
+		updated := existing.DeepCopy()
+		updated.Labels = desired.Labels
+		updated.Annotations = desired.Annotations
+		if revision, ok := existing.Annotations[revisionAnnotation]; ok {
+			if updated.Annotations == nil {
+				updated.Annotations = map[string]string{}
+			}
+			updated.Annotations[revisionAnnotation] = revision
+		}
+		updated.Spec = desired.Spec
+
+		if equality.Semantic.DeepEqual(existing, updated) {
+			return nil
+		}
+
+		_, err = deployments.Update(updated)
+		return err
+	})
+}
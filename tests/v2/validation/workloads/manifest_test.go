@@ -0,0 +1,150 @@
+package workloads
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/utils/pointer"
+)
+
+// fakeDeploymentStore stores deployments by name and counts the writes.
+type fakeDeploymentStore struct {
+	deployments map[string]*appv1.Deployment
+	creates     int
+	updates     int
+}
+
+func (f *fakeDeploymentStore) Get(namespace, name string, opts metav1.GetOptions) (*appv1.Deployment, error) {
+	deployment, ok := f.deployments[name]
+	if !ok {
+		return nil, apierrors.NewNotFound(schema.GroupResource{Group: "apps", Resource: "deployments"}, name)
+	}
+
+	return deployment.DeepCopy(), nil
+}
+
+func (f *fakeDeploymentStore) Create(deployment *appv1.Deployment) (*appv1.Deployment, error) {
+	f.creates++
+	f.deployments[deployment.Name] = deployment.DeepCopy()
+	return deployment, nil
+}
+
+func (f *fakeDeploymentStore) Update(deployment *appv1.Deployment) (*appv1.Deployment, error) {
+	f.updates++
+	f.deployments[deployment.Name] = deployment.DeepCopy()
+	return deployment, nil
+}
+
+// newStoredTestDeployment returns a deployment as read from the cluster, with its status and the metadata assigned by
+// the cluster set.
+func newStoredTestDeployment() *appv1.Deployment {
+	deployment := newTestDeploymentWithImage("web", nginxImageName)
+	deployment.Namespace = "default"
+	deployment.Labels = map[string]string{"app": "web"}
+	deployment.Annotations = map[string]string{revisionAnnotation: "3", "team": "payments"}
+	deployment.UID = "0f3a6d52-7f2e-4c1b-9a57-6e5a3c4b2d10"
+	deployment.ResourceVersion = "1234"
+	deployment.Generation = 3
+	deployment.CreationTimestamp = metav1.NewTime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	deployment.ManagedFields = []metav1.ManagedFieldsEntry{{Manager: "kube-controller-manager", Operation: metav1.ManagedFieldsOperationUpdate}}
+	deployment.Spec.Replicas = pointer.Int32(2)
+	deployment.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}
+	deployment.Spec.Template.Labels = map[string]string{"app": "web"}
+	deployment.Status = appv1.DeploymentStatus{ObservedGeneration: 3, Replicas: 2, UpdatedReplicas: 2, AvailableReplicas: 2}
+
+	return deployment
+}
+
+func TestExportDeploymentManifest(t *testing.T) {
+	manifest, err := exportDeploymentManifest(newStoredTestDeployment())
+	require.NoError(t, err)
+
+	assert.YAMLEq(t, `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: default
+  labels:
+    app: web
+  annotations:
+    team: payments
+spec:
+  replicas: 2
+  selector:
+    matchLabels:
+      app: web
+  strategy: {}
+  template:
+    metadata:
+      creationTimestamp: null
+      labels:
+        app: web
+    spec:
+      containers:
+      - name: app
+        image: `+nginxImageName+`
+        resources: {}
+`, string(manifest))
+}
+
+func TestApplyManifestRoundTrip(t *testing.T) {
+	stored := newStoredTestDeployment()
+	store := &fakeDeploymentStore{deployments: map[string]*appv1.Deployment{"web": stored.DeepCopy()}}
+
+	manifest, err := exportDeploymentManifest(stored)
+	require.NoError(t, err)
+
+	require.NoError(t, applyManifest(store, manifest))
+	assert.Zero(t, store.creates)
+	assert.Zero(t, store.updates, "re-applying an exported manifest should not update the deployment")
+	assert.Equal(t, stored, store.deployments["web"])
+
+	reexported, err := exportDeploymentManifest(store.deployments["web"])
+	require.NoError(t, err)
+	assert.Equal(t, string(manifest), string(reexported))
+}
+
+func TestApplyManifestUpdatesChangedDeployment(t *testing.T) {
+	stored := newStoredTestDeployment()
+	store := &fakeDeploymentStore{deployments: map[string]*appv1.Deployment{"web": stored.DeepCopy()}}
+
+	changed := stored.DeepCopy()
+	changed.Spec.Template.Spec.Containers[0].Image = redisImageName
+	delete(changed.Annotations, "team")
+	manifest, err := exportDeploymentManifest(changed)
+	require.NoError(t, err)
+
+	require.NoError(t, applyManifest(store, manifest))
+	assert.Equal(t, 1, store.updates)
+	assert.Equal(t, redisImageName, store.deployments["web"].Spec.Template.Spec.Containers[0].Image)
+	assert.Equal(t, map[string]string{revisionAnnotation: "3"}, store.deployments["web"].Annotations, "the revision annotation should be preserved")
+	assert.Equal(t, stored.Status, store.deployments["web"].Status)
+}
+
+func TestApplyManifestCreatesMissingDeployment(t *testing.T) {
+	store := &fakeDeploymentStore{deployments: map[string]*appv1.Deployment{}}
+
+	manifest, err := exportDeploymentManifest(newStoredTestDeployment())
+	require.NoError(t, err)
+
+	require.NoError(t, applyManifest(store, manifest))
+	assert.Equal(t, 1, store.creates)
+	require.Contains(t, store.deployments, "web")
+	assert.Equal(t, "default", store.deployments["web"].Namespace)
+	assert.Empty(t, store.deployments["web"].UID)
+}
+
+func TestApplyManifestUnsupportedKind(t *testing.T) {
+	store := &fakeDeploymentStore{deployments: map[string]*appv1.Deployment{}}
+
+	err := applyManifest(store, []byte("apiVersion: apps/v1\nkind: StatefulSet\nmetadata:\n  name: web\n"))
+	assert.EqualError(t, err, `unsupported kind "StatefulSet" in manifest, expected Deployment`)
+	assert.Zero(t, store.creates)
+}
@@ -0,0 +1,103 @@
+package workloads
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// verifyMultiArchScheduling verifies that the pods of the deployment, which runs a multi-arch image, were scheduled
+// and are ready on nodes of any architecture. Pods failing to pull their image are reported by the architecture of
+// their node, as an image lacking the variant for an architecture only fails on nodes of that architecture.
+func verifyMultiArchScheduling(client *rancher.Client, clusterID, namespaceName string, deployment *appv1.Deployment) error {
+	wranglerContext, err := getWranglerContext(client, clusterID)
+	if err != nil {
+		return err
+	}
+
+	pods, err := listDeploymentPods(wranglerContext, namespaceName, deployment)
+	if err != nil {
+		return err
+	}
+
+	nodeList, err := wranglerContext.Core.Node().List(metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	return checkMultiArchPods(pods, nodeList.Items)
+}
+
+// checkMultiArchPods returns an error if a pod is not scheduled or not ready. Image pull failures are grouped by the
+// architecture of the node, so that a missing variant of the image shows up as a single architecture.
+func checkMultiArchPods(pods []corev1.Pod, nodes []corev1.Node) error {
+	archByNode := map[string]string{}
+	for _, node := range nodes {
+		arch := node.Labels[corev1.LabelArchStable]
+		if arch == "" {
+			arch = node.Status.NodeInfo.Architecture
+		}
+		archByNode[node.Name] = arch
+	}
+
+	active := 0
+	pullFailures := map[string][]string{}
+	for _, pod := range pods {
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+		active++
+
+		if pod.Spec.NodeName == "" {
+			return fmt.Errorf("pod %s is not scheduled", pod.Name)
+		}
+
+		arch := archByNode[pod.Spec.NodeName]
+		if reason, message, failed := imagePullFailure(pod); failed {
+			pullFailures[arch] = append(pullFailures[arch], fmt.Sprintf("pod %s on node %s is %s: %s", pod.Name, pod.Spec.NodeName, reason, message))
+			continue
+		}
+
+		if !isPodReady(pod) {
+			return fmt.Errorf("pod %s on %s node %s is not ready", pod.Name, arch, pod.Spec.NodeName)
+		}
+	}
+
+	if len(pullFailures) > 0 {
+		archs := make([]string, 0, len(pullFailures))
+		var failures []string
+		for arch := range pullFailures {
+			archs = append(archs, arch)
+		}
+		sort.Strings(archs)
+		for _, arch := range archs {
+			failures = append(failures, pullFailures[arch]...)
+		}
+
+		return fmt.Errorf("image can not be pulled on nodes with architecture %v, the image may lack a variant for it: %s", archs, strings.Join(failures, "; "))
+	}
+
+	if active == 0 {
+		return fmt.Errorf("no pods found")
+	}
+
+	return nil
+}
+
+// imagePullFailure returns the reason and message of the first container of the pod that is waiting because its image
+// can not be pulled.
+func imagePullFailure(pod corev1.Pod) (string, string, bool) {
+	for _, status := range pod.Status.ContainerStatuses {
+		waiting := status.State.Waiting
+		if waiting != nil && (waiting.Reason == "ErrImagePull" || waiting.Reason == "ImagePullBackOff") {
+			return waiting.Reason, waiting.Message, true
+		}
+	}
+
+	return "", "", false
+}
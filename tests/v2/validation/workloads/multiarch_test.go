@@ -0,0 +1,89 @@
+package workloads
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func newTestNodeWithArch(name, arch string) corev1.Node {
+	return newTestNode(name, map[string]string{corev1.LabelArchStable: arch})
+}
+
+func newReadyTestPod(name, nodeName string) corev1.Pod {
+	pod := newTestPod(name, nodeName)
+	pod.Status.Phase = corev1.PodRunning
+	pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}
+	return pod
+}
+
+func newPullFailingTestPod(name, nodeName, message string) corev1.Pod {
+	pod := newTestPod(name, nodeName)
+	pod.Status.Phase = corev1.PodPending
+	pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+		Name:  "app",
+		State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff", Message: message}},
+	}}
+	return pod
+}
+
+func TestCheckMultiArchPods(t *testing.T) {
+	nodes := []corev1.Node{newTestNodeWithArch("node-amd", "amd64"), newTestNodeWithArch("node-arm", "arm64")}
+	nodeInfoOnly := corev1.Node{}
+	nodeInfoOnly.Name = "node-s390x"
+	nodeInfoOnly.Status.NodeInfo.Architecture = "s390x"
+	nodes = append(nodes, nodeInfoOnly)
+
+	tests := []struct {
+		name    string
+		pods    []corev1.Pod
+		wantErr string
+	}{
+		{
+			name: "ready on every architecture",
+			pods: []corev1.Pod{newReadyTestPod("web-a", "node-amd"), newReadyTestPod("web-b", "node-arm")},
+		},
+		{
+			name: "missing arm64 variant",
+			pods: []corev1.Pod{
+				newReadyTestPod("web-a", "node-amd"),
+				newPullFailingTestPod("web-b", "node-arm", "no matching manifest for linux/arm64 in the manifest list entries"),
+			},
+			wantErr: "image can not be pulled on nodes with architecture [arm64], the image may lack a variant for it: pod web-b on node node-arm is ImagePullBackOff: no matching manifest for linux/arm64 in the manifest list entries",
+		},
+		{
+			name: "architecture from node info",
+			pods: []corev1.Pod{
+				newPullFailingTestPod("web-c", "node-s390x", "no match for platform in manifest"),
+				newPullFailingTestPod("web-b", "node-arm", "no match for platform in manifest"),
+			},
+			wantErr: "image can not be pulled on nodes with architecture [arm64 s390x], the image may lack a variant for it: pod web-b on node node-arm is ImagePullBackOff: no match for platform in manifest; pod web-c on node node-s390x is ImagePullBackOff: no match for platform in manifest",
+		},
+		{
+			name:    "not scheduled",
+			pods:    []corev1.Pod{newReadyTestPod("web-a", "node-amd"), newTestPod("web-b", "")},
+			wantErr: "pod web-b is not scheduled",
+		},
+		{
+			name:    "not ready",
+			pods:    []corev1.Pod{newTestPod("web-a", "node-arm")},
+			wantErr: "pod web-a on arm64 node node-arm is not ready",
+		},
+		{
+			name:    "no pods",
+			wantErr: "no pods found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkMultiArchPods(tt.pods, nodes)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
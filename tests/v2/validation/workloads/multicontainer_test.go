@@ -0,0 +1,76 @@
+package workloads
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newRunningContainerStatus(name, image string) corev1.ContainerStatus {
+	return corev1.ContainerStatus{
+		Name:  name,
+		Image: "docker.io/library/" + image + ":latest",
+		State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+	}
+}
+
+func newTwoContainerPod(name, webImage, cacheImage string) corev1.Pod {
+	return corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{
+				newRunningContainerStatus("web", webImage),
+				newRunningContainerStatus("cache", cacheImage),
+			},
+		},
+	}
+}
+
+func TestCheckContainersRunningImages(t *testing.T) {
+	containerImages := map[string]string{
+		"web":   nginxImageName,
+		"cache": redisImageName,
+	}
+
+	tests := []struct {
+		name    string
+		pods    []corev1.Pod
+		wantErr string
+	}{
+		{
+			name: "every container runs its image",
+			pods: []corev1.Pod{
+				newTwoContainerPod("pod-1", nginxImageName, redisImageName),
+				newTwoContainerPod("pod-2", nginxImageName, redisImageName),
+			},
+		},
+		{
+			name: "one container runs the wrong image",
+			pods: []corev1.Pod{
+				newTwoContainerPod("pod-1", nginxImageName, redisImageName),
+				newTwoContainerPod("pod-2", nginxImageName, ubuntuImageName),
+			},
+			wantErr: "container cache of pod pod-2",
+		},
+		{
+			name: "too few replicas",
+			pods: []corev1.Pod{
+				newTwoContainerPod("pod-1", nginxImageName, redisImageName),
+			},
+			wantErr: "expected 2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkContainersRunningImages(tt.pods, containerImages, 2)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package workloads
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	"github.com/rancher/shepherd/extensions/defaults"
+	"github.com/stretchr/testify/require"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kwait "k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	// podEvictionTimeout is how long pods tolerate a NotReady node by default before they are evicted.
+	podEvictionTimeout = 300 * time.Second
+
+	// simulatedNotReadyTaint is the taint used to simulate a NotReady node. Unlike the node.kubernetes.io/not-ready
+	// taint, it is not removed by the node lifecycle controller while the kubelet keeps reporting the node as Ready.
+	simulatedNotReadyTaint = "workloads.validation.cattle.io/not-ready"
+)
+
+// nodeClient is the subset of the wrangler Node client needed to update a node.
+type nodeClient interface {
+	Get(name string, opts metav1.GetOptions) (*corev1.Node, error)
+	Update(node *corev1.Node) (*corev1.Node, error)
+}
+
+// failNode makes the node NotReady and returns a function that makes it Ready again. Actually stopping the kubelet of a
+// node depends on the environment, so by default the node is cordoned and tainted with a NoExecute taint, which evicts
+// its pods like the node lifecycle controller does for a NotReady node. Tests for environments that can stop nodes can
+// replace it.
+var failNode = func(client *rancher.Client, clusterID, nodeName string) (func() error, error) {
+	wranglerContext, err := getWranglerContext(client, clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := wranglerContext.Core.Node()
+	if err := setNodeNotReady(nodes, nodeName, true); err != nil {
+		return nil, err
+	}
+
+	return func() error {
+		return setNodeNotReady(nodes, nodeName, false)
+	}, nil
+}
+
+// validateRescheduleOnNodeNotReady makes the node NotReady and validates that the pods of the deployment that ran on
+// it are rescheduled onto healthy nodes and the deployment is fully available again within the pod eviction timeout.
+func validateRescheduleOnNodeNotReady(t *testing.T, client *rancher.Client, clusterID, namespaceName string, deployment *appv1.Deployment, nodeName string) {
+	wranglerContext, err := getWranglerContext(client, clusterID)
+	require.NoError(t, err)
+
+	var recoverNode func() error
+	fail := func() error {
+		var err error
+		recoverNode, err = failNode(client, clusterID, nodeName)
+		return err
+	}
+	defer func() {
+		if recoverNode != nil {
+			require.NoError(t, recoverNode())
+		}
+	}()
+
+	err = rescheduleOnNodeNotReady(wranglerContext.Apps.Deployment(), wranglerContext.Core.Pod(), namespaceName, deployment, nodeName, fail, rolloutPollInterval, podEvictionTimeout+defaults.FiveMinuteTimeout)
+	require.NoError(t, err)
+}
+
+func rescheduleOnNodeNotReady(deployments deploymentClient, pods podLister, namespaceName string, deployment *appv1.Deployment, nodeName string, fail func() error, interval, timeout time.Duration) error {
+	selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		return err
+	}
+
+	podList, err := pods.List(namespaceName, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return err
+	}
+	if len(podsOnNode(podList.Items, nodeName)) == 0 {
+		return fmt.Errorf("no pods of deployment %s run on node %s", deployment.Name, nodeName)
+	}
+
+	if err := fail(); err != nil {
+		return fmt.Errorf("failed to make node %s NotReady: %w", nodeName, err)
+	}
+
+	var waitErr error
+	err = kwait.PollUntilContextTimeout(context.TODO(), interval, timeout, true, func(ctx context.Context) (bool, error) {
+		current, err := deployments.Get(namespaceName, deployment.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+
+		podList, err := pods.List(namespaceName, metav1.ListOptions{LabelSelector: selector.String()})
+		if err != nil {
+			return false, err
+		}
+
+		waitErr = checkRescheduled(current, podList.Items, nodeName)
+		return waitErr == nil, nil
+	})
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("timed out waiting for deployment %s to recover from node %s being NotReady: %w", deployment.Name, nodeName, waitErr)
+	}
+
+	return err
+}
+
+// checkRescheduled returns an error if a pod of the deployment is still on the failed node or the deployment is not
+// fully available on the remaining nodes.
+func checkRescheduled(deployment *appv1.Deployment, pods []corev1.Pod, failedNode string) error {
+	if remaining := podsOnNode(pods, failedNode); len(remaining) > 0 {
+		return fmt.Errorf("pods %v are still on node %s", remaining, failedNode)
+	}
+
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+
+	ready := int32(0)
+	for _, pod := range pods {
+		if pod.DeletionTimestamp == nil && isPodReady(pod) {
+			ready++
+		}
+	}
+	if ready != replicas || deployment.Status.AvailableReplicas != replicas {
+		return fmt.Errorf("deployment %s has %d ready pods and %d available replicas, expected %d", deployment.Name, ready, deployment.Status.AvailableReplicas, replicas)
+	}
+
+	return nil
+}
+
+// podsOnNode returns the names of the pods that are scheduled on the node and not terminating.
+func podsOnNode(pods []corev1.Pod, nodeName string) []string {
+	var names []string
+	for _, pod := range pods {
+		if pod.Spec.NodeName == nodeName && pod.DeletionTimestamp == nil {
+			names = append(names, pod.Name)
+		}
+	}
+
+	return names
+}
+
+// setNodeNotReady cordons the node and adds the simulated NotReady taint, or reverts both.
+func setNodeNotReady(nodes nodeClient, nodeName string, notReady bool) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		node, err := nodes.Get(nodeName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		var taints []corev1.Taint
+		for _, taint := range node.Spec.Taints {
+			if taint.Key != simulatedNotReadyTaint {
+				taints = append(taints, taint)
+			}
+		}
+		if notReady {
+			taints = append(taints, corev1.Taint{Key: simulatedNotReadyTaint, Effect: corev1.TaintEffectNoExecute})
+		}
+
+		node.Spec.Taints = taints
+		node.Spec.Unschedulable = notReady
+		_, err = nodes.Update(node)
+		return err
+	})
+}
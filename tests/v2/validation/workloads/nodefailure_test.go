@@ -0,0 +1,127 @@
+package workloads
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+)
+
+// fakeNodeFailure simulates a deployment whose pods are evicted from a failed node and rescheduled onto node-3, one
+// pod per listing. With stuck set, the pods are never evicted.
+type fakeNodeFailure struct {
+	*fakeDeployments
+	pods       []corev1.Pod
+	failedNode string
+	stuck      bool
+}
+
+func newFakeNodeFailure() *fakeNodeFailure {
+	deployment := newTestDeploymentWithImage("web", nginxImageName)
+	deployment.Spec.Replicas = pointer.Int32(3)
+	deployment.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "test"}}
+
+	fake := &fakeNodeFailure{fakeDeployments: newFakeDeployments(deployment)}
+	fake.onGet = func(deployment *appv1.Deployment) {
+		deployment.Status.AvailableReplicas = fake.available()
+	}
+	for _, pod := range []corev1.Pod{newTestPod("web-a", "node-1"), newTestPod("web-b", "node-1"), newTestPod("web-c", "node-2")} {
+		pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}
+		fake.pods = append(fake.pods, pod)
+	}
+
+	return fake
+}
+
+func (f *fakeNodeFailure) fail() error {
+	f.failedNode = "node-1"
+	return nil
+}
+
+func (f *fakeNodeFailure) available() int32 {
+	available := int32(0)
+	for _, pod := range f.pods {
+		if pod.Spec.NodeName != f.failedNode {
+			available++
+		}
+	}
+
+	return available
+}
+
+func (f *fakeNodeFailure) List(namespace string, opts metav1.ListOptions) (*corev1.PodList, error) {
+	pods := append([]corev1.Pod{}, f.pods...)
+
+	if f.failedNode != "" && !f.stuck {
+		for i := range f.pods {
+			if f.pods[i].Spec.NodeName == f.failedNode {
+				f.pods[i].Name += "-rescheduled"
+				f.pods[i].Spec.NodeName = "node-3"
+				break
+			}
+		}
+	}
+
+	return &corev1.PodList{Items: pods}, nil
+}
+
+// fakeNodes stores nodes by name.
+type fakeNodes map[string]*corev1.Node
+
+func (f fakeNodes) Get(name string, opts metav1.GetOptions) (*corev1.Node, error) {
+	return f[name].DeepCopy(), nil
+}
+
+func (f fakeNodes) Update(node *corev1.Node) (*corev1.Node, error) {
+	f[node.Name] = node.DeepCopy()
+	return node, nil
+}
+
+func TestRescheduleOnNodeNotReady(t *testing.T) {
+	fake := newFakeNodeFailure()
+
+	err := rescheduleOnNodeNotReady(fake, fake, "default", fake.deployment, "node-1", fake.fail, time.Millisecond, time.Second)
+	require.NoError(t, err)
+	assert.Empty(t, podsOnNode(fake.pods, "node-1"))
+	assert.Len(t, podsOnNode(fake.pods, "node-3"), 2)
+}
+
+func TestRescheduleOnNodeNotReadyPodsStuck(t *testing.T) {
+	fake := newFakeNodeFailure()
+	fake.stuck = true
+
+	err := rescheduleOnNodeNotReady(fake, fake, "default", fake.deployment, "node-1", fake.fail, time.Millisecond, 20*time.Millisecond)
+	assert.ErrorContains(t, err, "timed out waiting for deployment web to recover from node node-1 being NotReady: pods [web-a web-b] are still on node node-1")
+}
+
+func TestRescheduleOnNodeNotReadyNoPodsOnNode(t *testing.T) {
+	fake := newFakeNodeFailure()
+
+	failed := false
+	fail := func() error {
+		failed = true
+		return nil
+	}
+
+	err := rescheduleOnNodeNotReady(fake, fake, "default", fake.deployment, "node-9", fail, time.Millisecond, time.Second)
+	assert.EqualError(t, err, "no pods of deployment web run on node node-9")
+	assert.False(t, failed, "the node should not be failed if no pods run on it")
+}
+
+func TestSetNodeNotReady(t *testing.T) {
+	otherTaint := corev1.Taint{Key: "dedicated", Value: "gpu", Effect: corev1.TaintEffectNoSchedule}
+	nodes := fakeNodes{"node-1": {ObjectMeta: metav1.ObjectMeta{Name: "node-1"}, Spec: corev1.NodeSpec{Taints: []corev1.Taint{otherTaint}}}}
+
+	require.NoError(t, setNodeNotReady(nodes, "node-1", true))
+	assert.True(t, nodes["node-1"].Spec.Unschedulable)
+	assert.Equal(t, []corev1.Taint{otherTaint, {Key: simulatedNotReadyTaint, Effect: corev1.TaintEffectNoExecute}}, nodes["node-1"].Spec.Taints)
+
+	require.NoError(t, setNodeNotReady(nodes, "node-1", false))
+	assert.False(t, nodes["node-1"].Spec.Unschedulable)
+	assert.Equal(t, []corev1.Taint{otherTaint}, nodes["node-1"].Spec.Taints)
+}
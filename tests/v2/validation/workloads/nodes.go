@@ -0,0 +1,68 @@
+package workloads
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kwait "k8s.io/apimachinery/pkg/util/wait"
+)
+
+const nodePollInterval = 5 * time.Second
+
+// nodeLister is the subset of the wrangler Node client needed to list nodes.
+type nodeLister interface {
+	List(opts metav1.ListOptions) (*corev1.NodeList, error)
+}
+
+// WaitForReadyNodes waits until the cluster has at least want nodes that are Ready and schedulable. On timeout, the
+// returned error includes the number of ready nodes that was last observed.
+func WaitForReadyNodes(client *rancher.Client, clusterID string, want int, timeout time.Duration) error {
+	wranglerContext, err := getWranglerContext(client, clusterID)
+	if err != nil {
+		return err
+	}
+
+	return waitForReadyNodes(wranglerContext.Core.Node(), want, nodePollInterval, timeout)
+}
+
+func waitForReadyNodes(nodes nodeLister, want int, interval, timeout time.Duration) error {
+	observed := 0
+	err := kwait.PollUntilContextTimeout(context.TODO(), interval, timeout, true, func(ctx context.Context) (bool, error) {
+		nodeList, err := nodes.List(metav1.ListOptions{})
+		if err != nil {
+			return false, err
+		}
+
+		observed = countReadyNodes(nodeList.Items)
+		return observed >= want, nil
+	})
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("timed out waiting for %d ready nodes, observed %d", want, observed)
+	}
+
+	return err
+}
+
+// countReadyNodes counts the nodes that report the Ready condition and are not cordoned.
+func countReadyNodes(nodes []corev1.Node) int {
+	count := 0
+	for _, node := range nodes {
+		if node.Spec.Unschedulable {
+			continue
+		}
+
+		for _, condition := range node.Status.Conditions {
+			if condition.Type == corev1.NodeReady && condition.Status == corev1.ConditionTrue {
+				count++
+				break
+			}
+		}
+	}
+
+	return count
+}
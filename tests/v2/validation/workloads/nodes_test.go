@@ -0,0 +1,73 @@
+package workloads
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// fakeNodeLister returns the node lists in order, repeating the last one once exhausted.
+type fakeNodeLister struct {
+	mu    sync.Mutex
+	lists [][]corev1.Node
+	calls int
+}
+
+func (f *fakeNodeLister) List(opts metav1.ListOptions) (*corev1.NodeList, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	index := f.calls
+	if index >= len(f.lists) {
+		index = len(f.lists) - 1
+	}
+	f.calls++
+
+	return &corev1.NodeList{Items: f.lists[index]}, nil
+}
+
+func newReadyNode(name string, ready bool) corev1.Node {
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+
+	return corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: corev1.NodeStatus{
+			Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: status}},
+		},
+	}
+}
+
+func TestWaitForReadyNodes(t *testing.T) {
+	cordoned := newReadyNode("node-4", true)
+	cordoned.Spec.Unschedulable = true
+
+	lister := &fakeNodeLister{
+		lists: [][]corev1.Node{
+			{newReadyNode("node-1", true), newReadyNode("node-2", false)},
+			{newReadyNode("node-1", true), newReadyNode("node-2", false), cordoned},
+			{newReadyNode("node-1", true), newReadyNode("node-2", true), newReadyNode("node-3", true)},
+		},
+	}
+
+	err := waitForReadyNodes(lister, 3, 10*time.Millisecond, 5*time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, lister.calls)
+}
+
+func TestWaitForReadyNodesTimeout(t *testing.T) {
+	lister := &fakeNodeLister{
+		lists: [][]corev1.Node{
+			{newReadyNode("node-1", true), newReadyNode("node-2", false)},
+		},
+	}
+
+	err := waitForReadyNodes(lister, 3, 10*time.Millisecond, 100*time.Millisecond)
+	assert.EqualError(t, err, "timed out waiting for 3 ready nodes, observed 1")
+}
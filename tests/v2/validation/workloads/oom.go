@@ -0,0 +1,70 @@
+package workloads
+
+import (
+	"github.com/rancher/shepherd/clients/rancher"
+	log "github.com/sirupsen/logrus"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const oomKilledReason = "OOMKilled"
+
+// PodContainer identifies a container of a pod.
+type PodContainer struct {
+	Pod       string
+	Container string
+}
+
+// detectOOMKills returns the containers of the deployment's pods that were terminated because they ran out of memory.
+func detectOOMKills(client *rancher.Client, clusterID, namespaceName string, deployment *appv1.Deployment) ([]PodContainer, error) {
+	wranglerContext, err := getWranglerContext(client, clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	deploymentPods, err := listDeploymentPods(wranglerContext, namespaceName, deployment)
+	if err != nil {
+		return nil, err
+	}
+
+	return findOOMKills(deploymentPods), nil
+}
+
+// findOOMKills returns the init and app containers whose current or last state was terminated with reason OOMKilled.
+func findOOMKills(pods []corev1.Pod) []PodContainer {
+	var oomKilled []PodContainer
+	for _, pod := range pods {
+		statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+		for _, status := range statuses {
+			if isOOMKilled(status.State) || isOOMKilled(status.LastTerminationState) {
+				oomKilled = append(oomKilled, PodContainer{Pod: pod.Name, Container: status.Name})
+			}
+		}
+	}
+
+	return oomKilled
+}
+
+func isOOMKilled(state corev1.ContainerState) bool {
+	return state.Terminated != nil && state.Terminated.Reason == oomKilledReason
+}
+
+// reportDeploymentFailure logs the OOMKilled containers and the events of the deployment when its validation failed.
+func reportDeploymentFailure(client *rancher.Client, clusterID, namespaceName string, deployment *appv1.Deployment) {
+	logOOMKills(client, clusterID, namespaceName, deployment)
+	logDeploymentEvents(client, clusterID, namespaceName, deployment)
+}
+
+// logOOMKills logs the OOMKilled containers of the deployment, so that a failing validation reports why pods didn't
+// come up instead of only timing out.
+func logOOMKills(client *rancher.Client, clusterID, namespaceName string, deployment *appv1.Deployment) {
+	oomKilled, err := detectOOMKills(client, clusterID, namespaceName, deployment)
+	if err != nil {
+		log.Warnf("Unable to check deployment %s for OOMKilled containers: %v", deployment.Name, err)
+		return
+	}
+
+	for _, podContainer := range oomKilled {
+		log.Errorf("Container %s of pod %s was OOMKilled", podContainer.Container, podContainer.Pod)
+	}
+}
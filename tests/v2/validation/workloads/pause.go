@@ -0,0 +1,83 @@
+package workloads
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	"github.com/rancher/shepherd/extensions/defaults"
+	appv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kwait "k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+)
+
+// pausedObservation is how long a paused deployment is watched for pods of the new image.
+const pausedObservation = 30 * time.Second
+
+// verifyPauseHaltsRollout pauses the deployment and changes its image, then verifies that no pods with the new image
+// are created while it is paused and that they are rolled out once it is resumed.
+func verifyPauseHaltsRollout(client *rancher.Client, clusterID, namespaceName string, deployment *appv1.Deployment) error {
+	wranglerContext, err := getWranglerContext(client, clusterID)
+	if err != nil {
+		return err
+	}
+
+	return pauseHaltsRollout(wranglerContext.Apps.Deployment(), wranglerContext.Core.Pod(), namespaceName, deployment, upgradeImage(deployment), rolloutPollInterval, pausedObservation, defaults.FiveMinuteTimeout)
+}
+
+func pauseHaltsRollout(deployments deploymentClient, pods podLister, namespaceName string, deployment *appv1.Deployment, newImage string, interval, observation, timeout time.Duration) error {
+	if err := setDeploymentPaused(deployments, namespaceName, deployment.Name, true); err != nil {
+		return err
+	}
+
+	var updatedDeployment *appv1.Deployment
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latestDeployment, err := deployments.Get(namespaceName, deployment.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		for i := range latestDeployment.Spec.Template.Spec.Containers {
+			latestDeployment.Spec.Template.Spec.Containers[i].Image = newImage
+		}
+
+		updatedDeployment, err = deployments.Update(latestDeployment)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(updatedDeployment.Spec.Selector)
+	if err != nil {
+		return err
+	}
+
+	// The poll only ends early if a pod of the new image shows up, which means the pause did not halt the rollout.
+	err = kwait.PollUntilContextTimeout(context.TODO(), interval, observation, true, func(ctx context.Context) (bool, error) {
+		podList, err := pods.List(namespaceName, metav1.ListOptions{LabelSelector: selector.String()})
+		if err != nil {
+			return false, err
+		}
+
+		for _, pod := range podList.Items {
+			if podRunsImage(pod, newImage) {
+				return false, fmt.Errorf("pod %s with image %s was created while deployment %s was paused", pod.Name, newImage, deployment.Name)
+			}
+		}
+
+		return false, nil
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+
+	if err := setDeploymentPaused(deployments, namespaceName, deployment.Name, false); err != nil {
+		return err
+	}
+
+	return waitForRolloutWithImage(deployments, pods, namespaceName, updatedDeployment, newImage, interval, timeout)
+}
@@ -0,0 +1,93 @@
+package workloads
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+)
+
+// fakePausableRollout simulates a deployment whose pods only run the image of its template while it is not paused,
+// unless ignorePause is set.
+type fakePausableRollout struct {
+	*fakeDeployments
+	rolledOut   string
+	ignorePause bool
+}
+
+func newFakePausableRollout(deployment *appv1.Deployment, ignorePause bool) *fakePausableRollout {
+	f := &fakePausableRollout{
+		fakeDeployments: newFakeDeployments(deployment),
+		rolledOut:       deployment.Spec.Template.Spec.Containers[0].Image,
+		ignorePause:     ignorePause,
+	}
+	f.onGet = func(deployment *appv1.Deployment) {
+		if f.rolledOut == deployment.Spec.Template.Spec.Containers[0].Image {
+			deployment.Status = newRolledOutStatus(deployment)
+		}
+	}
+	f.onUpdate = func(deployment *appv1.Deployment) {
+		if !deployment.Spec.Paused || f.ignorePause {
+			f.rolledOut = deployment.Spec.Template.Spec.Containers[0].Image
+		}
+	}
+
+	return f
+}
+
+func (f *fakePausableRollout) List(namespace string, opts metav1.ListOptions) (*corev1.PodList, error) {
+	var pods []corev1.Pod
+	for i := int32(0); i < *f.deployment.Spec.Replicas; i++ {
+		pods = append(pods, corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "web"},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: f.rolledOut}}},
+			Status: corev1.PodStatus{
+				Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+			},
+		})
+	}
+
+	return &corev1.PodList{Items: pods}, nil
+}
+
+func TestPauseHaltsRollout(t *testing.T) {
+	tests := []struct {
+		name        string
+		ignorePause bool
+		wantErr     string
+	}{
+		{
+			name: "pause halts the rollout",
+		},
+		{
+			name:        "pods created while paused",
+			ignorePause: true,
+			wantErr:     "was created while deployment web was paused",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deployment := newTestDeploymentWithImage("web", nginxImageName)
+			deployment.Spec.Replicas = pointer.Int32(2)
+			deployment.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}
+
+			rollout := newFakePausableRollout(deployment, tt.ignorePause)
+
+			err := pauseHaltsRollout(rollout, rollout, "default", deployment, redisImageName, time.Millisecond, 20*time.Millisecond, time.Second)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.False(t, rollout.deployment.Spec.Paused)
+			assert.Equal(t, redisImageName, rollout.rolledOut)
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package workloads
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	"github.com/rancher/shepherd/extensions/defaults"
+	appv1 "k8s.io/api/apps/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	kwait "k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+const pdbSampleInterval = 2 * time.Second
+
+// pdbGetter is the subset of the PodDisruptionBudget client needed to read a budget.
+type pdbGetter interface {
+	Get(ctx context.Context, name string, opts metav1.GetOptions) (*policyv1.PodDisruptionBudget, error)
+}
+
+// verifyPDBRespectedDuringRollout restarts the deployment, samples the status of the PodDisruptionBudget until the new
+// revision is fully rolled out and returns an error if the number of healthy pods ever dropped below the budget's
+// minAvailable.
+func verifyPDBRespectedDuringRollout(client *rancher.Client, clusterID, namespaceName, pdbName string, deployment *appv1.Deployment) error {
+	wranglerContext, err := getWranglerContext(client, clusterID)
+	if err != nil {
+		return err
+	}
+
+	clientset, err := kubernetes.NewForConfig(wranglerContext.RESTConfig)
+	if err != nil {
+		return err
+	}
+
+	return pdbRespectedDuringRollout(wranglerContext.Apps.Deployment(), clientset.PolicyV1().PodDisruptionBudgets(namespaceName), namespaceName, pdbName, deployment.Name, pdbSampleInterval, defaults.FiveMinuteTimeout)
+}
+
+func pdbRespectedDuringRollout(deployments deploymentClient, pdbs pdbGetter, namespaceName, pdbName, name string, interval, timeout time.Duration) error {
+	var samples []policyv1.PodDisruptionBudgetStatus
+	var minAvailable *intstr.IntOrString
+	sample := func(ctx context.Context) error {
+		pdb, err := pdbs.Get(ctx, pdbName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		minAvailable = pdb.Spec.MinAvailable
+		samples = append(samples, pdb.Status)
+		return nil
+	}
+
+	// The budget is sampled before the restart as well, so that a rollout finishing before the first poll is still
+	// compared against the state it started from.
+	if err := sample(context.TODO()); err != nil {
+		return err
+	}
+
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		current, err := deployments.Get(namespaceName, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		if current.Spec.Template.Annotations == nil {
+			current.Spec.Template.Annotations = map[string]string{}
+		}
+		current.Spec.Template.Annotations[restartedAtAnnotation] = time.Now().Format(time.RFC3339)
+		_, err = deployments.Update(current)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("error restarting deployment %s: %w", name, err)
+	}
+
+	var waitErr error
+	err = kwait.PollUntilContextTimeout(context.TODO(), interval, timeout, true, func(ctx context.Context) (bool, error) {
+		if err := sample(ctx); err != nil {
+			return false, err
+		}
+
+		// The deployment is read after the restart, so it is only complete once its new revision was rolled out.
+		current, err := deployments.Get(namespaceName, name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		if !isRolloutComplete(current) {
+			waitErr = fmt.Errorf("rollout of deployment %s is not complete", name)
+			return false, nil
+		}
+
+		return true, nil
+	})
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("timed out waiting for rollout of deployment %s: %w", name, waitErr)
+	} else if err != nil {
+		return err
+	}
+
+	return checkPDBSamples(samples, minAvailable)
+}
+
+// checkPDBSamples returns an error if any of the sampled PodDisruptionBudget statuses report fewer healthy pods than
+// minAvailable. A percentage is resolved against the expected pods of each sample, rounding up.
+func checkPDBSamples(samples []policyv1.PodDisruptionBudgetStatus, minAvailable *intstr.IntOrString) error {
+	if minAvailable == nil {
+		return fmt.Errorf("pod disruption budget does not set minAvailable")
+	}
+
+	for i, sample := range samples {
+		wantHealthy, err := intstr.GetScaledValueFromIntOrPercent(minAvailable, int(sample.ExpectedPods), true)
+		if err != nil {
+			return err
+		}
+
+		if int(sample.CurrentHealthy) < wantHealthy {
+			return fmt.Errorf("sample %d: current healthy pods %d dropped below minAvailable %d", i, sample.CurrentHealthy, wantHealthy)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,177 @@
+package workloads
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appv1 "k8s.io/api/apps/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/pointer"
+)
+
+// fakePDB reports the next of its statuses on every Get, repeating the last one once exhausted.
+type fakePDB struct {
+	minAvailable intstr.IntOrString
+	statuses     []policyv1.PodDisruptionBudgetStatus
+	gets         int
+}
+
+func (f *fakePDB) Get(ctx context.Context, name string, opts metav1.GetOptions) (*policyv1.PodDisruptionBudget, error) {
+	status := f.statuses[len(f.statuses)-1]
+	if f.gets < len(f.statuses) {
+		status = f.statuses[f.gets]
+	}
+	f.gets++
+
+	return &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       policyv1.PodDisruptionBudgetSpec{MinAvailable: &f.minAvailable},
+		Status:     status,
+	}, nil
+}
+
+// newFakePDBRollout returns a deployment of 3 replicas whose rollout is reported as complete once complete returns
+// true for the number of calls to Get so far.
+func newFakePDBRollout(complete func(gets int) bool) *fakeDeployments {
+	deployment := newTestDeploymentWithImage("web", nginxImageName)
+	deployment.Spec.Replicas = pointer.Int32(3)
+
+	fake := newFakeDeployments(deployment)
+	fake.onGet = func(deployment *appv1.Deployment) {
+		deployment.Status = newRolledOutStatus(deployment)
+		if !complete(fake.gets) {
+			deployment.Status.UpdatedReplicas = 1
+		}
+	}
+
+	return fake
+}
+
+func TestPDBRespectedDuringRollout(t *testing.T) {
+	tests := []struct {
+		name        string
+		statuses    []policyv1.PodDisruptionBudgetStatus
+		complete    func(gets int) bool
+		wantSamples int
+		wantErr     string
+	}{
+		{
+			name: "healthy pods never drop below minAvailable",
+			statuses: []policyv1.PodDisruptionBudgetStatus{
+				{CurrentHealthy: 3, ExpectedPods: 3},
+				{CurrentHealthy: 2, ExpectedPods: 3},
+				{CurrentHealthy: 3, ExpectedPods: 3},
+			},
+			// The first Get reads the deployment to restart it, so the rollout completes on the third poll, which takes
+			// the fourth sample after the one taken before the restart.
+			complete:    func(gets int) bool { return gets >= 3 },
+			wantSamples: 4,
+		},
+		{
+			name: "healthy pods drop below minAvailable before the rollout completes",
+			statuses: []policyv1.PodDisruptionBudgetStatus{
+				{CurrentHealthy: 3, ExpectedPods: 3},
+				{CurrentHealthy: 2, ExpectedPods: 3},
+				{CurrentHealthy: 2, ExpectedPods: 3},
+				{CurrentHealthy: 1, ExpectedPods: 3},
+				{CurrentHealthy: 3, ExpectedPods: 3},
+			},
+			complete:    func(gets int) bool { return gets >= 5 },
+			wantSamples: 6,
+			wantErr:     "sample 3: current healthy pods 1 dropped below minAvailable 2",
+		},
+		{
+			name:     "rollout does not complete",
+			statuses: []policyv1.PodDisruptionBudgetStatus{{CurrentHealthy: 3, ExpectedPods: 3}},
+			complete: func(int) bool { return false },
+			wantErr:  "timed out waiting for rollout of deployment web: rollout of deployment web is not complete",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deployments := newFakePDBRollout(tt.complete)
+			pdbs := &fakePDB{minAvailable: intstr.FromInt32(2), statuses: tt.statuses}
+
+			err := pdbRespectedDuringRollout(deployments, pdbs, "default", "web", "web", time.Millisecond, 50*time.Millisecond)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			assert.NotEmpty(t, deployments.deployment.Spec.Template.Annotations[restartedAtAnnotation], "the rollout should be started by the helper")
+			if tt.wantSamples != 0 {
+				assert.Equal(t, tt.wantSamples, pdbs.gets)
+			}
+		})
+	}
+}
+
+func TestCheckPDBSamples(t *testing.T) {
+	minAvailableCount := intstr.FromInt32(2)
+	minAvailablePercent := intstr.FromString("50%")
+
+	tests := []struct {
+		name         string
+		samples      []policyv1.PodDisruptionBudgetStatus
+		minAvailable *intstr.IntOrString
+		wantErr      bool
+	}{
+		{
+			name: "healthy pods never drop below minAvailable",
+			samples: []policyv1.PodDisruptionBudgetStatus{
+				{CurrentHealthy: 3, ExpectedPods: 3},
+				{CurrentHealthy: 2, ExpectedPods: 3},
+				{CurrentHealthy: 3, ExpectedPods: 3},
+			},
+			minAvailable: &minAvailableCount,
+		},
+		{
+			name: "healthy pods drop below minAvailable",
+			samples: []policyv1.PodDisruptionBudgetStatus{
+				{CurrentHealthy: 3, ExpectedPods: 3},
+				{CurrentHealthy: 1, ExpectedPods: 3},
+				{CurrentHealthy: 3, ExpectedPods: 3},
+			},
+			minAvailable: &minAvailableCount,
+			wantErr:      true,
+		},
+		{
+			name: "percentage is rounded up against expected pods",
+			samples: []policyv1.PodDisruptionBudgetStatus{
+				{CurrentHealthy: 2, ExpectedPods: 3},
+			},
+			minAvailable: &minAvailablePercent,
+		},
+		{
+			name: "percentage violated",
+			samples: []policyv1.PodDisruptionBudgetStatus{
+				{CurrentHealthy: 1, ExpectedPods: 3},
+			},
+			minAvailable: &minAvailablePercent,
+			wantErr:      true,
+		},
+		{
+			name:    "minAvailable not set",
+			samples: []policyv1.PodDisruptionBudgetStatus{{CurrentHealthy: 3, ExpectedPods: 3}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkPDBSamples(tt.samples, tt.minAvailable)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package workloads
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	"github.com/rancher/shepherd/pkg/wrangler"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+const localCluster = "local"
+
+// getWranglerContext returns the wrangler context of the local cluster or the downstream cluster with the given ID.
+func getWranglerContext(client *rancher.Client, clusterID string) (*wrangler.Context, error) {
+	if clusterID == localCluster {
+		return client.WranglerContext, nil
+	}
+
+	return client.WranglerContext.DownStreamClusterWranglerContext(clusterID)
+}
+
+// listDeploymentPods lists the pods selected by the deployment's label selector.
+func listDeploymentPods(wranglerContext *wrangler.Context, namespaceName string, deployment *appv1.Deployment) ([]corev1.Pod, error) {
+	selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		return nil, err
+	}
+
+	podList, err := wranglerContext.Core.Pod().List(namespaceName, metav1.ListOptions{
+		LabelSelector: selector.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return podList.Items, nil
+}
+
+// verifyPodPlacement verifies that the running pods of the deployment were scheduled on nodes respecting the
+// required node affinity and pod (anti-)affinity rules of wantAffinity.
+func verifyPodPlacement(client *rancher.Client, clusterID, namespaceName string, deployment *appv1.Deployment, wantAffinity *corev1.Affinity) error {
+	wranglerContext, err := getWranglerContext(client, clusterID)
+	if err != nil {
+		return err
+	}
+
+	pods, err := listDeploymentPods(wranglerContext, namespaceName, deployment)
+	if err != nil {
+		return err
+	}
+
+	nodeList, err := wranglerContext.Core.Node().List(metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	return checkPodPlacement(pods, nodeList.Items, wantAffinity)
+}
+
+// checkPodPlacement checks the node assignments of the given pods against the required scheduling terms of the affinity.
+func checkPodPlacement(pods []corev1.Pod, nodes []corev1.Node, affinity *corev1.Affinity) error {
+	if affinity == nil {
+		return nil
+	}
+
+	nodesByName := map[string]corev1.Node{}
+	for _, node := range nodes {
+		nodesByName[node.Name] = node
+	}
+
+	var scheduledPods []corev1.Pod
+	for _, pod := range pods {
+		if pod.Spec.NodeName == "" {
+			return fmt.Errorf("pod %s is not scheduled to a node", pod.Name)
+		}
+		if _, ok := nodesByName[pod.Spec.NodeName]; !ok {
+			return fmt.Errorf("pod %s is scheduled to unknown node %s", pod.Name, pod.Spec.NodeName)
+		}
+		scheduledPods = append(scheduledPods, pod)
+	}
+
+	if affinity.NodeAffinity != nil && affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution != nil {
+		nodeSelector := affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+		for _, pod := range scheduledPods {
+			matches, err := nodeMatchesSelector(nodesByName[pod.Spec.NodeName], nodeSelector)
+			if err != nil {
+				return err
+			}
+			if !matches {
+				return fmt.Errorf("pod %s is scheduled to node %s which does not match the required node affinity", pod.Name, pod.Spec.NodeName)
+			}
+		}
+	}
+
+	if affinity.PodAntiAffinity != nil {
+		for _, term := range affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution {
+			domains, err := topologyDomainsByPod(scheduledPods, nodesByName, term)
+			if err != nil {
+				return err
+			}
+
+			seen := map[string]string{}
+			for _, pod := range scheduledPods {
+				domain, ok := domains[pod.Name]
+				if !ok {
+					continue
+				}
+				if other, found := seen[domain]; found {
+					return fmt.Errorf("pods %s and %s share topology %s=%s, violating the required pod anti-affinity", other, pod.Name, term.TopologyKey, domain)
+				}
+				seen[domain] = pod.Name
+			}
+		}
+	}
+
+	if affinity.PodAffinity != nil {
+		for _, term := range affinity.PodAffinity.RequiredDuringSchedulingIgnoredDuringExecution {
+			domains, err := topologyDomainsByPod(scheduledPods, nodesByName, term)
+			if err != nil {
+				return err
+			}
+
+			distinct := sets.New[string]()
+			for _, domain := range domains {
+				distinct.Insert(domain)
+			}
+			if distinct.Len() > 1 {
+				return fmt.Errorf("pods are spread across topology %s values %s, violating the required pod affinity", term.TopologyKey, strings.Join(sets.List(distinct), ","))
+			}
+		}
+	}
+
+	return nil
+}
+
+// topologyDomainsByPod maps the name of each pod matched by the affinity term to the value of the term's
+// topology key on the node the pod is scheduled to.
+func topologyDomainsByPod(pods []corev1.Pod, nodesByName map[string]corev1.Node, term corev1.PodAffinityTerm) (map[string]string, error) {
+	selector, err := metav1.LabelSelectorAsSelector(term.LabelSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	domains := map[string]string{}
+	for _, pod := range pods {
+		if !selector.Matches(labels.Set(pod.Labels)) {
+			continue
+		}
+
+		node := nodesByName[pod.Spec.NodeName]
+		domain, ok := node.Labels[term.TopologyKey]
+		if !ok {
+			return nil, fmt.Errorf("node %s is missing topology label %s", node.Name, term.TopologyKey)
+		}
+		domains[pod.Name] = domain
+	}
+
+	return domains, nil
+}
+
+// nodeMatchesSelector returns true if the node matches at least one of the selector's terms.
+func nodeMatchesSelector(node corev1.Node, nodeSelector *corev1.NodeSelector) (bool, error) {
+	for _, term := range nodeSelector.NodeSelectorTerms {
+		if len(term.MatchExpressions) == 0 && len(term.MatchFields) == 0 {
+			continue
+		}
+
+		labelsMatch, err := requirementsMatch(term.MatchExpressions, labels.Set(node.Labels))
+		if err != nil {
+			return false, err
+		}
+
+		fieldsMatch, err := requirementsMatch(term.MatchFields, labels.Set{"metadata.name": node.Name})
+		if err != nil {
+			return false, err
+		}
+
+		if labelsMatch && fieldsMatch {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// requirementsMatch returns true if the given set satisfies all node selector requirements.
+func requirementsMatch(requirements []corev1.NodeSelectorRequirement, set labels.Set) (bool, error) {
+	selector := labels.NewSelector()
+	for _, requirement := range requirements {
+		var op selection.Operator
+		switch requirement.Operator {
+		case corev1.NodeSelectorOpIn:
+			op = selection.In
+		case corev1.NodeSelectorOpNotIn:
+			op = selection.NotIn
+		case corev1.NodeSelectorOpExists:
+			op = selection.Exists
+		case corev1.NodeSelectorOpDoesNotExist:
+			op = selection.DoesNotExist
+		case corev1.NodeSelectorOpGt:
+			op = selection.GreaterThan
+		case corev1.NodeSelectorOpLt:
+			op = selection.LessThan
+		default:
+			return false, fmt.Errorf("unsupported node selector operator %s", requirement.Operator)
+		}
+
+		parsed, err := labels.NewRequirement(requirement.Key, op, requirement.Values)
+		if err != nil {
+			return false, err
+		}
+		selector = selector.Add(*parsed)
+	}
+
+	return selector.Matches(set), nil
+}
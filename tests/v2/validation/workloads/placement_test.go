@@ -0,0 +1,113 @@
+package workloads
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const hostnameLabel = "kubernetes.io/hostname"
+
+func newTestNode(name string, nodeLabels map[string]string) corev1.Node {
+	if nodeLabels == nil {
+		nodeLabels = map[string]string{}
+	}
+	nodeLabels[hostnameLabel] = name
+
+	return corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: nodeLabels},
+	}
+}
+
+func newTestPod(name, nodeName string) corev1.Pod {
+	return corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"app": "test"}},
+		Spec:       corev1.PodSpec{NodeName: nodeName},
+	}
+}
+
+func TestCheckPodPlacement(t *testing.T) {
+	nodes := []corev1.Node{
+		newTestNode("node-1", map[string]string{"zone": "a"}),
+		newTestNode("node-2", map[string]string{"zone": "a"}),
+		newTestNode("node-3", map[string]string{"zone": "b"}),
+	}
+
+	antiAffinity := &corev1.Affinity{
+		PodAntiAffinity: &corev1.PodAntiAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{
+				{
+					LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "test"}},
+					TopologyKey:   hostnameLabel,
+				},
+			},
+		},
+	}
+
+	nodeAffinity := &corev1.Affinity{
+		NodeAffinity: &corev1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+				NodeSelectorTerms: []corev1.NodeSelectorTerm{
+					{
+						MatchExpressions: []corev1.NodeSelectorRequirement{
+							{Key: "zone", Operator: corev1.NodeSelectorOpIn, Values: []string{"a"}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		pods     []corev1.Pod
+		affinity *corev1.Affinity
+		wantErr  bool
+	}{
+		{
+			name:     "anti-affinity honored when pods are spread across nodes",
+			pods:     []corev1.Pod{newTestPod("pod-1", "node-1"), newTestPod("pod-2", "node-2"), newTestPod("pod-3", "node-3")},
+			affinity: antiAffinity,
+		},
+		{
+			name:     "anti-affinity violated when pods share a node",
+			pods:     []corev1.Pod{newTestPod("pod-1", "node-1"), newTestPod("pod-2", "node-1")},
+			affinity: antiAffinity,
+			wantErr:  true,
+		},
+		{
+			name:     "node affinity honored",
+			pods:     []corev1.Pod{newTestPod("pod-1", "node-1"), newTestPod("pod-2", "node-2")},
+			affinity: nodeAffinity,
+		},
+		{
+			name:     "node affinity violated",
+			pods:     []corev1.Pod{newTestPod("pod-1", "node-1"), newTestPod("pod-2", "node-3")},
+			affinity: nodeAffinity,
+			wantErr:  true,
+		},
+		{
+			name:     "unscheduled pod",
+			pods:     []corev1.Pod{newTestPod("pod-1", "")},
+			affinity: antiAffinity,
+			wantErr:  true,
+		},
+		{
+			name: "no affinity",
+			pods: []corev1.Pod{newTestPod("pod-1", "node-1"), newTestPod("pod-2", "node-1")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkPodPlacement(tt.pods, nodes, tt.affinity)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package workloads
+
+import (
+	"fmt"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// verifyDeploymentPods lists the pods of the deployment and returns the error of check for them, if any.
+func verifyDeploymentPods(client *rancher.Client, clusterID, namespaceName string, deployment *appv1.Deployment, check func(pods []corev1.Pod) error) error {
+	wranglerContext, err := getWranglerContext(client, clusterID)
+	if err != nil {
+		return err
+	}
+
+	pods, err := listDeploymentPods(wranglerContext, namespaceName, deployment)
+	if err != nil {
+		return err
+	}
+
+	return check(pods)
+}
+
+// checkPods returns the first error of check for the pods that are not being deleted, or an error if there is no such
+// pod.
+func checkPods(pods []corev1.Pod, check func(pod corev1.Pod) error) error {
+	active := 0
+	for _, pod := range pods {
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+		active++
+
+		if err := check(pod); err != nil {
+			return err
+		}
+	}
+
+	if active == 0 {
+		return fmt.Errorf("no pods found")
+	}
+
+	return nil
+}
+
+// checkRunningPods returns the first error of check for the running pods that are not being deleted, or an error if
+// there is no such pod. Pods of an old revision that already terminated and pods that are still starting are ignored.
+func checkRunningPods(pods []corev1.Pod, check func(pod corev1.Pod) error) error {
+	var running []corev1.Pod
+	for _, pod := range pods {
+		if pod.Status.Phase == corev1.PodRunning && pod.DeletionTimestamp == nil {
+			running = append(running, pod)
+		}
+	}
+
+	if len(running) == 0 {
+		return fmt.Errorf("no running pods found")
+	}
+
+	return checkPods(running, check)
+}
@@ -0,0 +1,142 @@
+package workloads
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// newRunningTestPod returns a pod with the spec running on node-1, unless the spec sets another node.
+func newRunningTestPod(name string, spec corev1.PodSpec) corev1.Pod {
+	pod := newTestPod(name, "node-1")
+	if spec.NodeName == "" {
+		spec.NodeName = pod.Spec.NodeName
+	}
+	pod.Spec = spec
+	pod.Status.Phase = corev1.PodRunning
+	return pod
+}
+
+func newDeletedTestPod(name string) corev1.Pod {
+	pod := newRunningTestPod(name, corev1.PodSpec{})
+	pod.DeletionTimestamp = &metav1.Time{}
+	return pod
+}
+
+func newPendingTestPod(name string) corev1.Pod {
+	pod := newTestPod(name, "")
+	pod.Status.Phase = corev1.PodPending
+	return pod
+}
+
+// rejectPod returns a check that fails for the pod with the given name only.
+func rejectPod(name string) func(corev1.Pod) error {
+	return func(pod corev1.Pod) error {
+		if pod.Name == name {
+			return fmt.Errorf("pod %s rejected", pod.Name)
+		}
+		return nil
+	}
+}
+
+func TestCheckPods(t *testing.T) {
+	tests := []struct {
+		name    string
+		pods    []corev1.Pod
+		reject  string
+		wantErr string
+	}{
+		{
+			name: "all pods pass",
+			pods: []corev1.Pod{newRunningTestPod("pod-1", corev1.PodSpec{}), newPendingTestPod("pod-2")},
+		},
+		{
+			name:    "pending pods are checked",
+			pods:    []corev1.Pod{newRunningTestPod("pod-1", corev1.PodSpec{}), newPendingTestPod("pod-2")},
+			reject:  "pod-2",
+			wantErr: "pod pod-2 rejected",
+		},
+		{
+			name:   "deleted pods are ignored",
+			pods:   []corev1.Pod{newRunningTestPod("pod-1", corev1.PodSpec{}), newDeletedTestPod("pod-2")},
+			reject: "pod-2",
+		},
+		{
+			name:    "only deleted pods",
+			pods:    []corev1.Pod{newDeletedTestPod("pod-1")},
+			wantErr: "no pods found",
+		},
+		{
+			name:    "no pods",
+			wantErr: "no pods found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkPods(tt.pods, rejectPod(tt.reject))
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestCheckRunningPods(t *testing.T) {
+	succeeded := newRunningTestPod("pod-2", corev1.PodSpec{})
+	succeeded.Status.Phase = corev1.PodSucceeded
+
+	tests := []struct {
+		name    string
+		pods    []corev1.Pod
+		reject  string
+		wantErr string
+	}{
+		{
+			name: "all pods pass",
+			pods: []corev1.Pod{newRunningTestPod("pod-1", corev1.PodSpec{}), newRunningTestPod("pod-2", corev1.PodSpec{})},
+		},
+		{
+			name:    "running pod rejected",
+			pods:    []corev1.Pod{newRunningTestPod("pod-1", corev1.PodSpec{}), newRunningTestPod("pod-2", corev1.PodSpec{})},
+			reject:  "pod-2",
+			wantErr: "pod pod-2 rejected",
+		},
+		{
+			name:   "terminated pods of the old revision are ignored",
+			pods:   []corev1.Pod{newRunningTestPod("pod-1", corev1.PodSpec{}), succeeded},
+			reject: "pod-2",
+		},
+		{
+			name:   "pending pods are ignored",
+			pods:   []corev1.Pod{newRunningTestPod("pod-1", corev1.PodSpec{}), newPendingTestPod("pod-2")},
+			reject: "pod-2",
+		},
+		{
+			name:   "deleted pods are ignored",
+			pods:   []corev1.Pod{newRunningTestPod("pod-1", corev1.PodSpec{}), newDeletedTestPod("pod-2")},
+			reject: "pod-2",
+		},
+		{
+			name:    "no running pods",
+			pods:    []corev1.Pod{newPendingTestPod("pod-1"), newDeletedTestPod("pod-2")},
+			wantErr: "no running pods found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkRunningPods(tt.pods, rejectPod(tt.reject))
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package workloads
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	"github.com/rancher/shepherd/extensions/defaults"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	kwait "k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	// podSecurityEnforceLabel is the namespace label setting the Pod Security Standards level enforced by the
+	// PodSecurity admission controller.
+	podSecurityEnforceLabel = "pod-security.kubernetes.io/enforce"
+
+	// podSecurityViolationMessage is part of the message of the FailedCreate event a ReplicaSet records when a pod is
+	// rejected by the PodSecurity admission controller.
+	podSecurityViolationMessage = "violates PodSecurity"
+)
+
+// podSecurityLevels are the levels of the Pod Security Standards.
+var podSecurityLevels = []string{"privileged", "baseline", "restricted"}
+
+// namespaceClient is the subset of the wrangler Namespace client needed to update a namespace.
+type namespaceClient interface {
+	Get(name string, opts metav1.GetOptions) (*corev1.Namespace, error)
+	Update(namespace *corev1.Namespace) (*corev1.Namespace, error)
+}
+
+// podSecurityClients are the clients underPodSecurity needs.
+type podSecurityClients struct {
+	namespaces  namespaceClient
+	deployments deploymentClient
+	replicaSets replicaSetLister
+	pods        podLister
+	events      eventLister
+}
+
+// validateUnderPodSecurity enforces the Pod Security Standards level on the namespace, restarts the deployment so
+// that its pods are admitted again, and validates that its pods are either all admitted and ready, if the deployment
+// complies with the level, or rejected with a PodSecurity admission error, if it does not. A deployment whose pods
+// neither become ready nor are rejected within the timeout fails the validation. The label is left on the namespace.
+func validateUnderPodSecurity(t *testing.T, client *rancher.Client, clusterID, namespaceName string, level string, deployment *appv1.Deployment) {
+	wranglerContext, err := getWranglerContext(client, clusterID)
+	require.NoError(t, err)
+
+	clients := podSecurityClients{
+		namespaces:  wranglerContext.Core.Namespace(),
+		deployments: wranglerContext.Apps.Deployment(),
+		replicaSets: wranglerContext.Apps.ReplicaSet(),
+		pods:        wranglerContext.Core.Pod(),
+		events:      wranglerContext.Core.Event(),
+	}
+	rejection, err := underPodSecurity(clients, namespaceName, level, deployment, rolloutPollInterval, defaults.FiveMinuteTimeout)
+	require.NoError(t, err)
+
+	if rejection != "" {
+		log.Infof("Pods of deployment %s were rejected under the %s level: %s", deployment.Name, level, rejection)
+		return
+	}
+	log.Infof("Pods of deployment %s were admitted under the %s level", deployment.Name, level)
+}
+
+// underPodSecurity returns the admission error the pods of the deployment were rejected with, or an empty string if
+// they were admitted.
+func underPodSecurity(clients podSecurityClients, namespaceName, level string, deployment *appv1.Deployment, interval, timeout time.Duration) (string, error) {
+	if !isPodSecurityLevel(level) {
+		return "", fmt.Errorf("invalid Pod Security Standards level %q, expected one of %v", level, podSecurityLevels)
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		return "", err
+	}
+
+	podList, err := clients.pods.List(namespaceName, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return "", err
+	}
+
+	beforeUIDs := map[types.UID]bool{}
+	for _, pod := range podList.Items {
+		beforeUIDs[pod.UID] = true
+	}
+
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		namespace, err := clients.namespaces.Get(namespaceName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		if namespace.Labels == nil {
+			namespace.Labels = map[string]string{}
+		}
+		namespace.Labels[podSecurityEnforceLabel] = level
+		_, err = clients.namespaces.Update(namespace)
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("error enforcing the %s level on namespace %s: %w", level, namespaceName, err)
+	}
+
+	// The admission controller only checks pods when they are created, so the pods are replaced.
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		current, err := clients.deployments.Get(namespaceName, deployment.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		if current.Spec.Template.Annotations == nil {
+			current.Spec.Template.Annotations = map[string]string{}
+		}
+		current.Spec.Template.Annotations[restartedAtAnnotation] = time.Now().Format(time.RFC3339)
+		_, err = clients.deployments.Update(current)
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("error restarting deployment %s: %w", deployment.Name, err)
+	}
+
+	var rejection string
+	var waitErr error
+	err = kwait.PollUntilContextTimeout(context.TODO(), interval, timeout, true, func(ctx context.Context) (bool, error) {
+		eventList, err := clients.events.List(namespaceName, metav1.ListOptions{})
+		if err != nil {
+			return false, err
+		}
+
+		replicaSetList, err := clients.replicaSets.List(namespaceName, metav1.ListOptions{})
+		if err != nil {
+			return false, err
+		}
+
+		if rejection = podSecurityRejection(filterDeploymentEvents(eventList.Items, deployment, replicaSetList.Items, nil)); rejection != "" {
+			return true, nil
+		}
+
+		current, err := clients.deployments.Get(namespaceName, deployment.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		if !isRolloutComplete(current) {
+			waitErr = fmt.Errorf("rollout of deployment %s is not complete", deployment.Name)
+			return false, nil
+		}
+
+		podList, err := clients.pods.List(namespaceName, metav1.ListOptions{LabelSelector: selector.String()})
+		if err != nil {
+			return false, err
+		}
+
+		replicas := int32(1)
+		if current.Spec.Replicas != nil {
+			replicas = *current.Spec.Replicas
+		}
+
+		waitErr = checkPodsRecreated(podList.Items, beforeUIDs, int(replicas))
+		return waitErr == nil, nil
+	})
+	if errors.Is(err, context.DeadlineExceeded) {
+		return "", fmt.Errorf("timed out waiting for pods of deployment %s to be admitted or rejected under the %s level: %w", deployment.Name, level, waitErr)
+	}
+
+	return rejection, err
+}
+
+// podSecurityRejection returns the message of the first event reporting that a pod was rejected by the PodSecurity
+// admission controller, or an empty string if there is none.
+func podSecurityRejection(events []corev1.Event) string {
+	for _, event := range events {
+		if event.Reason == "FailedCreate" && strings.Contains(event.Message, podSecurityViolationMessage) {
+			return event.Message
+		}
+	}
+
+	return ""
+}
+
+func isPodSecurityLevel(level string) bool {
+	for _, known := range podSecurityLevels {
+		if level == known {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,156 @@
+package workloads
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/pointer"
+)
+
+// fakePodSecurity simulates the PodSecurity admission controller for a single namespace. Under the restricted level,
+// only pods that run as non-root are admitted; rejected pods are reported by a FailedCreate event of the ReplicaSet.
+// With stuck set, restarted pods are neither admitted nor rejected.
+type fakePodSecurity struct {
+	*fakeDeployments
+	namespace  *corev1.Namespace
+	generation int
+	events     []corev1.Event
+	stuck      bool
+}
+
+func newFakePodSecurity(runAsNonRoot bool) *fakePodSecurity {
+	deployment := newTestDeploymentWithImage("web", nginxImageName)
+	deployment.Spec.Replicas = pointer.Int32(2)
+	deployment.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}
+	deployment.Spec.Template.Spec.SecurityContext = &corev1.PodSecurityContext{RunAsNonRoot: pointer.Bool(runAsNonRoot)}
+
+	f := &fakePodSecurity{
+		fakeDeployments: newFakeDeployments(deployment),
+		namespace:       &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+	}
+	f.onGet = func(deployment *appv1.Deployment) {
+		deployment.Status = newRolledOutStatus(deployment)
+		if f.generation != int(deployment.Generation) {
+			deployment.Status.UpdatedReplicas = 0
+		}
+	}
+	f.onUpdate = func(*appv1.Deployment) {
+		switch {
+		case f.stuck:
+		case f.admits():
+			f.generation = int(f.deployment.Generation)
+		default:
+			f.events = append(f.events, corev1.Event{
+				InvolvedObject: corev1.ObjectReference{Kind: "ReplicaSet", Name: "web-5d8f7"},
+				Reason:         "FailedCreate",
+				Message:        `Error creating: pods "web-5d8f7-x2k4q" is forbidden: violates PodSecurity "restricted:latest": runAsNonRoot != true (pod must not set securityContext.runAsNonRoot=false)`,
+			})
+		}
+	}
+
+	return f
+}
+
+func (f *fakePodSecurity) admits() bool {
+	if f.namespace.Labels[podSecurityEnforceLabel] != "restricted" {
+		return true
+	}
+
+	securityContext := f.deployment.Spec.Template.Spec.SecurityContext
+	return securityContext != nil && securityContext.RunAsNonRoot != nil && *securityContext.RunAsNonRoot
+}
+
+func (f *fakePodSecurity) clients() podSecurityClients {
+	return podSecurityClients{
+		namespaces:  fakePodSecurityNamespaces{f},
+		deployments: f,
+		replicaSets: fakeQuotaReplicaSets{},
+		pods:        fakePodSecurityPods{f},
+		events:      fakePodSecurityEvents{f},
+	}
+}
+
+// fakePodSecurityNamespaces updates the namespace of a fakePodSecurity.
+type fakePodSecurityNamespaces struct {
+	*fakePodSecurity
+}
+
+func (f fakePodSecurityNamespaces) Get(name string, opts metav1.GetOptions) (*corev1.Namespace, error) {
+	return f.namespace.DeepCopy(), nil
+}
+
+func (f fakePodSecurityNamespaces) Update(namespace *corev1.Namespace) (*corev1.Namespace, error) {
+	f.namespace = namespace.DeepCopy()
+	return namespace, nil
+}
+
+// fakePodSecurityPods lists the pods of the latest admitted generation of a fakePodSecurity.
+type fakePodSecurityPods struct {
+	*fakePodSecurity
+}
+
+func (f fakePodSecurityPods) List(namespace string, opts metav1.ListOptions) (*corev1.PodList, error) {
+	var pods []corev1.Pod
+	for i := int32(0); i < *f.deployment.Spec.Replicas; i++ {
+		name := fmt.Sprintf("web-%d-%d", f.generation, i)
+		pods = append(pods, corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, UID: types.UID(name)},
+			Status: corev1.PodStatus{
+				Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+			},
+		})
+	}
+
+	return &corev1.PodList{Items: pods}, nil
+}
+
+// fakePodSecurityEvents lists the events of a fakePodSecurity.
+type fakePodSecurityEvents struct {
+	*fakePodSecurity
+}
+
+func (f fakePodSecurityEvents) List(namespace string, opts metav1.ListOptions) (*corev1.EventList, error) {
+	return &corev1.EventList{Items: append([]corev1.Event{}, f.events...)}, nil
+}
+
+func TestUnderPodSecurityCompliant(t *testing.T) {
+	fake := newFakePodSecurity(true)
+
+	rejection, err := underPodSecurity(fake.clients(), "default", "restricted", fake.deployment, time.Millisecond, time.Second)
+	require.NoError(t, err)
+	assert.Empty(t, rejection)
+	assert.Equal(t, "restricted", fake.namespace.Labels[podSecurityEnforceLabel])
+	assert.Equal(t, 1, fake.generation, "the pods should have been replaced")
+}
+
+func TestUnderPodSecurityNonCompliant(t *testing.T) {
+	fake := newFakePodSecurity(false)
+
+	rejection, err := underPodSecurity(fake.clients(), "default", "restricted", fake.deployment, time.Millisecond, time.Second)
+	require.NoError(t, err)
+	assert.Contains(t, rejection, `violates PodSecurity "restricted:latest": runAsNonRoot != true`)
+	assert.Equal(t, 0, fake.generation, "the rejected pods should not have replaced the running ones")
+}
+
+func TestUnderPodSecurityNeitherAdmittedNorRejected(t *testing.T) {
+	fake := newFakePodSecurity(true)
+	fake.stuck = true
+
+	_, err := underPodSecurity(fake.clients(), "default", "restricted", fake.deployment, time.Millisecond, 20*time.Millisecond)
+	assert.EqualError(t, err, "timed out waiting for pods of deployment web to be admitted or rejected under the restricted level: rollout of deployment web is not complete")
+}
+
+func TestUnderPodSecurityInvalidLevel(t *testing.T) {
+	fake := newFakePodSecurity(true)
+
+	_, err := underPodSecurity(fake.clients(), "default", "strict", fake.deployment, time.Millisecond, time.Second)
+	assert.EqualError(t, err, `invalid Pod Security Standards level "strict", expected one of [privileged baseline restricted]`)
+	assert.Empty(t, fake.namespace.Labels)
+}
@@ -0,0 +1,31 @@
+package workloads
+
+import (
+	"fmt"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// verifyPodPriority verifies that the running pods of the deployment use the wanted priority class and that the
+// priority admission resolved it to a priority. An empty wantPriorityClass stands for pods without a priority class.
+func verifyPodPriority(client *rancher.Client, clusterID, namespaceName string, deployment *appv1.Deployment, wantPriorityClass string) error {
+	return verifyDeploymentPods(client, clusterID, namespaceName, deployment, func(pods []corev1.Pod) error {
+		return checkRunningPods(pods, podPriorityCheck(wantPriorityClass))
+	})
+}
+
+// podPriorityCheck returns a check that fails for a pod that does not use the wanted priority class.
+func podPriorityCheck(wantPriorityClass string) func(corev1.Pod) error {
+	return func(pod corev1.Pod) error {
+		if pod.Spec.PriorityClassName != wantPriorityClass {
+			return fmt.Errorf("pod %s has priority class %q, expected %q", pod.Name, pod.Spec.PriorityClassName, wantPriorityClass)
+		}
+		if wantPriorityClass != "" && pod.Spec.Priority == nil {
+			return fmt.Errorf("priority class %s of pod %s was not resolved to a priority", wantPriorityClass, pod.Name)
+		}
+
+		return nil
+	}
+}
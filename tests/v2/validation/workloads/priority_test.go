@@ -0,0 +1,51 @@
+package workloads
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/pointer"
+)
+
+func TestPodPriorityCheck(t *testing.T) {
+	tests := []struct {
+		name              string
+		pod               corev1.Pod
+		wantPriorityClass string
+		wantErr           string
+	}{
+		{
+			name:              "matching priority class",
+			pod:               newRunningTestPod("pod-1", corev1.PodSpec{PriorityClassName: "high", Priority: pointer.Int32(1000)}),
+			wantPriorityClass: "high",
+		},
+		{
+			name: "no priority class",
+			pod:  newRunningTestPod("pod-1", corev1.PodSpec{Priority: pointer.Int32(0)}),
+		},
+		{
+			name:              "mismatched priority class",
+			pod:               newRunningTestPod("pod-1", corev1.PodSpec{PriorityClassName: "low", Priority: pointer.Int32(10)}),
+			wantPriorityClass: "high",
+			wantErr:           `pod pod-1 has priority class "low", expected "high"`,
+		},
+		{
+			name:              "priority not resolved",
+			pod:               newRunningTestPod("pod-1", corev1.PodSpec{PriorityClassName: "high"}),
+			wantPriorityClass: "high",
+			wantErr:           "priority class high of pod pod-1 was not resolved to a priority",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := podPriorityCheck(tt.wantPriorityClass)(tt.pod)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
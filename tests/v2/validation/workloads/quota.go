@@ -0,0 +1,194 @@
+package workloads
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	"github.com/rancher/shepherd/extensions/defaults"
+	"github.com/stretchr/testify/require"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	kwait "k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+// quotaExceededMessage is part of the message of the FailedCreate event a ReplicaSet records when a pod is rejected by
+// a ResourceQuota.
+const quotaExceededMessage = "exceeded quota"
+
+// resourceQuotaCreator is the subset of the typed ResourceQuota client needed to create a quota. The wrangler core
+// clients do not include ResourceQuotas.
+type resourceQuotaCreator interface {
+	Create(ctx context.Context, quota *corev1.ResourceQuota, opts metav1.CreateOptions) (*corev1.ResourceQuota, error)
+}
+
+// eventLister is the subset of the wrangler Event client needed to list events.
+type eventLister interface {
+	List(namespace string, opts metav1.ListOptions) (*corev1.EventList, error)
+}
+
+// replicaSetLister is the subset of the wrangler ReplicaSet client needed to list ReplicaSets.
+type replicaSetLister interface {
+	List(namespace string, opts metav1.ListOptions) (*appv1.ReplicaSetList, error)
+}
+
+// validateUnderQuota applies the quota, which must limit the number of pods, to the namespace and scales the
+// deployment to one replica more than the quota allows. It validates that the extra pods are rejected with a
+// quota exceeded event while the pods that existed before keep running. The quota and the scaled deployment are left
+// in place.
+func validateUnderQuota(t *testing.T, client *rancher.Client, clusterID, namespaceName string, deployment *appv1.Deployment, quota *corev1.ResourceQuota) {
+	wranglerContext, err := getWranglerContext(client, clusterID)
+	require.NoError(t, err)
+
+	clientset, err := kubernetes.NewForConfig(wranglerContext.RESTConfig)
+	require.NoError(t, err)
+
+	clients := underQuotaClients{
+		quotas:      clientset.CoreV1().ResourceQuotas(namespaceName),
+		deployments: wranglerContext.Apps.Deployment(),
+		replicaSets: wranglerContext.Apps.ReplicaSet(),
+		pods:        wranglerContext.Core.Pod(),
+		events:      wranglerContext.Core.Event(),
+	}
+	err = underQuota(clients, namespaceName, deployment, quota, rolloutPollInterval, defaults.FiveMinuteTimeout)
+	require.NoError(t, err)
+}
+
+// underQuotaClients are the clients underQuota needs.
+type underQuotaClients struct {
+	quotas      resourceQuotaCreator
+	deployments deploymentClient
+	replicaSets replicaSetLister
+	pods        podLister
+	events      eventLister
+}
+
+func underQuota(clients underQuotaClients, namespaceName string, deployment *appv1.Deployment, quota *corev1.ResourceQuota, interval, timeout time.Duration) error {
+	podLimit, ok := quota.Spec.Hard[corev1.ResourcePods]
+	if !ok {
+		return fmt.Errorf("quota %s does not limit the number of pods", quota.Name)
+	}
+	maxPods := int(podLimit.Value())
+
+	selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		return err
+	}
+
+	podList, err := clients.pods.List(namespaceName, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return err
+	}
+
+	existing := map[types.UID]string{}
+	for _, pod := range podList.Items {
+		if pod.DeletionTimestamp == nil && isPodReady(pod) {
+			existing[pod.UID] = pod.Name
+		}
+	}
+
+	quota = quota.DeepCopy()
+	quota.Namespace = namespaceName
+	if _, err := clients.quotas.Create(context.TODO(), quota, metav1.CreateOptions{}); err != nil {
+		return fmt.Errorf("error creating quota %s: %w", quota.Name, err)
+	}
+
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		current, err := clients.deployments.Get(namespaceName, deployment.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		replicas := int32(maxPods + 1)
+		current.Spec.Replicas = &replicas
+		_, err = clients.deployments.Update(current)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("error scaling deployment %s beyond quota %s: %w", deployment.Name, quota.Name, err)
+	}
+
+	var waitErr error
+	err = kwait.PollUntilContextTimeout(context.TODO(), interval, timeout, true, func(ctx context.Context) (bool, error) {
+		eventList, err := clients.events.List(namespaceName, metav1.ListOptions{})
+		if err != nil {
+			return false, err
+		}
+
+		replicaSetList, err := clients.replicaSets.List(namespaceName, metav1.ListOptions{})
+		if err != nil {
+			return false, err
+		}
+
+		podList, err := clients.pods.List(namespaceName, metav1.ListOptions{LabelSelector: selector.String()})
+		if err != nil {
+			return false, err
+		}
+
+		if waitErr = checkExistingPodsHealthy(podList.Items, existing, maxPods); waitErr != nil {
+			return false, nil
+		}
+
+		events := filterDeploymentEvents(eventList.Items, deployment, replicaSetList.Items, nil)
+		if !hasQuotaExceededEvent(events) {
+			waitErr = fmt.Errorf("no event reports that pods of deployment %s exceeded the quota", deployment.Name)
+			return false, nil
+		}
+
+		return true, nil
+	})
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("timed out waiting for quota %s to reject pods of deployment %s: %w", quota.Name, deployment.Name, waitErr)
+	}
+
+	return err
+}
+
+// checkExistingPodsHealthy returns an error if a pod that existed before is gone or no longer ready, or if there are
+// more pods than the quota allows.
+func checkExistingPodsHealthy(pods []corev1.Pod, existing map[types.UID]string, maxPods int) error {
+	active := 0
+	ready := map[types.UID]bool{}
+	for _, pod := range pods {
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+		active++
+		ready[pod.UID] = isPodReady(pod)
+	}
+
+	if active > maxPods {
+		return fmt.Errorf("found %d pods, exceeding the quota of %d pods", active, maxPods)
+	}
+
+	for uid, name := range existing {
+		isReady, found := ready[uid]
+		if !found {
+			return fmt.Errorf("pod %s was removed", name)
+		}
+		if !isReady {
+			return fmt.Errorf("pod %s is no longer ready", name)
+		}
+	}
+
+	return nil
+}
+
+// hasQuotaExceededEvent returns true if one of the events reports that a pod was rejected by a quota.
+func hasQuotaExceededEvent(events []corev1.Event) bool {
+	for _, event := range events {
+		if event.Reason == "FailedCreate" && strings.Contains(event.Message, quotaExceededMessage) {
+			return true
+		}
+	}
+
+	return false
+}
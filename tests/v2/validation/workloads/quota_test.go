@@ -0,0 +1,197 @@
+package workloads
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/pointer"
+)
+
+// fakeQuota simulates a namespace that enforces the pod limit of the created quota: scaling the deployment beyond it
+// creates pods up to the limit and records a FailedCreate event for its ReplicaSet. With silent set, no event is
+// recorded, and with unhealthy set, the pods that existed before become unready.
+type fakeQuota struct {
+	*fakeDeployments
+	pods      []corev1.Pod
+	quotas    []*corev1.ResourceQuota
+	events    []corev1.Event
+	silent    bool
+	unhealthy bool
+}
+
+func newFakeQuota() *fakeQuota {
+	deployment := newTestDeploymentWithImage("web", nginxImageName)
+	deployment.Spec.Replicas = pointer.Int32(2)
+	deployment.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "test"}}
+
+	fake := &fakeQuota{fakeDeployments: newFakeDeployments(deployment)}
+	fake.onGet = nil
+	fake.onUpdate = fake.scale
+	fake.addPods(2)
+
+	return fake
+}
+
+func newTestQuota(pods string) *corev1.ResourceQuota {
+	return &corev1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{Name: "pods"},
+		Spec:       corev1.ResourceQuotaSpec{Hard: corev1.ResourceList{corev1.ResourcePods: resource.MustParse(pods)}},
+	}
+}
+
+func (f *fakeQuota) addPods(count int) {
+	for i := 0; i < count; i++ {
+		pod := newTestPod(fmt.Sprintf("web-%d", len(f.pods)), "node-1")
+		pod.UID = types.UID(pod.Name)
+		pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}
+		f.pods = append(f.pods, pod)
+	}
+}
+
+func (f *fakeQuota) Create(ctx context.Context, quota *corev1.ResourceQuota, opts metav1.CreateOptions) (*corev1.ResourceQuota, error) {
+	f.quotas = append(f.quotas, quota.DeepCopy())
+	return quota, nil
+}
+
+// scale creates pods for the replicas of the deployment, up to the pod limit of the quotas.
+func (f *fakeQuota) scale(deployment *appv1.Deployment) {
+	replicas := int(*deployment.Spec.Replicas)
+	maxPods := replicas
+	for _, quota := range f.quotas {
+		limit := quota.Spec.Hard[corev1.ResourcePods]
+		maxPods = int(limit.Value())
+	}
+
+	if replicas > maxPods {
+		f.addPods(maxPods - len(f.pods))
+		if !f.silent {
+			f.events = append(f.events, corev1.Event{
+				InvolvedObject: corev1.ObjectReference{Kind: "ReplicaSet", Name: "web-5d8f7"},
+				Reason:         "FailedCreate",
+				Message:        `Error creating: pods "web-5d8f7-x2k4q" is forbidden: exceeded quota: pods, requested: pods=1, used: pods=3, limited: pods=3`,
+			})
+		}
+	} else {
+		f.addPods(replicas - len(f.pods))
+	}
+
+	if f.unhealthy {
+		f.pods[0].Status.Conditions = nil
+	}
+}
+
+func (f *fakeQuota) clients() underQuotaClients {
+	return underQuotaClients{
+		quotas:      f,
+		deployments: f,
+		replicaSets: fakeQuotaReplicaSets{},
+		pods:        fakeQuotaPods{f},
+		events:      fakeQuotaEvents{f},
+	}
+}
+
+// fakeQuotaPods lists the pods of a fakeQuota.
+type fakeQuotaPods struct {
+	*fakeQuota
+}
+
+func (f fakeQuotaPods) List(namespace string, opts metav1.ListOptions) (*corev1.PodList, error) {
+	return &corev1.PodList{Items: append([]corev1.Pod{}, f.pods...)}, nil
+}
+
+// fakeQuotaEvents lists the events of a fakeQuota.
+type fakeQuotaEvents struct {
+	*fakeQuota
+}
+
+func (f fakeQuotaEvents) List(namespace string, opts metav1.ListOptions) (*corev1.EventList, error) {
+	return &corev1.EventList{Items: append([]corev1.Event{}, f.events...)}, nil
+}
+
+// fakeQuotaReplicaSets lists the ReplicaSet of the deployment of a fakeQuota.
+type fakeQuotaReplicaSets struct{}
+
+func (fakeQuotaReplicaSets) List(namespace string, opts metav1.ListOptions) (*appv1.ReplicaSetList, error) {
+	replicaSet := newTestReplicaSet("web", "5d8f7", nginxImageName)
+	return &appv1.ReplicaSetList{Items: []appv1.ReplicaSet{replicaSet}}, nil
+}
+
+func TestUnderQuota(t *testing.T) {
+	fake := newFakeQuota()
+
+	err := underQuota(fake.clients(), "default", fake.deployment, newTestQuota("3"), time.Millisecond, time.Second)
+	require.NoError(t, err)
+	require.Len(t, fake.quotas, 1)
+	assert.Equal(t, "default", fake.quotas[0].Namespace)
+	assert.Equal(t, int32(4), *fake.deployment.Spec.Replicas)
+	assert.Len(t, fake.pods, 3)
+}
+
+func TestUnderQuotaNoRejectionEvent(t *testing.T) {
+	fake := newFakeQuota()
+	fake.silent = true
+
+	err := underQuota(fake.clients(), "default", fake.deployment, newTestQuota("3"), time.Millisecond, 20*time.Millisecond)
+	assert.EqualError(t, err, "timed out waiting for quota pods to reject pods of deployment web: no event reports that pods of deployment web exceeded the quota")
+}
+
+func TestUnderQuotaExistingPodUnhealthy(t *testing.T) {
+	fake := newFakeQuota()
+	fake.unhealthy = true
+
+	err := underQuota(fake.clients(), "default", fake.deployment, newTestQuota("3"), time.Millisecond, 20*time.Millisecond)
+	assert.EqualError(t, err, "timed out waiting for quota pods to reject pods of deployment web: pod web-0 is no longer ready")
+}
+
+func TestUnderQuotaWithoutPodLimit(t *testing.T) {
+	fake := newFakeQuota()
+	quota := &corev1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{Name: "cpu"},
+		Spec:       corev1.ResourceQuotaSpec{Hard: corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse("1")}},
+	}
+
+	err := underQuota(fake.clients(), "default", fake.deployment, quota, time.Millisecond, time.Second)
+	assert.EqualError(t, err, "quota cpu does not limit the number of pods")
+	assert.Empty(t, fake.quotas)
+}
+
+func TestUnderQuotaCreateError(t *testing.T) {
+	fake := newFakeQuota()
+	clients := fake.clients()
+	clients.quotas = failingQuotaCreator{}
+
+	err := underQuota(clients, "default", fake.deployment, newTestQuota("3"), time.Millisecond, time.Second)
+	assert.EqualError(t, err, "error creating quota pods: forbidden")
+	assert.Equal(t, int32(2), *fake.deployment.Spec.Replicas)
+}
+
+// failingQuotaCreator fails to create any quota.
+type failingQuotaCreator struct{}
+
+func (failingQuotaCreator) Create(ctx context.Context, quota *corev1.ResourceQuota, opts metav1.CreateOptions) (*corev1.ResourceQuota, error) {
+	return nil, errors.New("forbidden")
+}
+
+func TestCheckExistingPodsHealthy(t *testing.T) {
+	ready := func(name string) corev1.Pod {
+		pod := newTestPod(name, "node-1")
+		pod.UID = types.UID(name)
+		pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}
+		return pod
+	}
+	existing := map[types.UID]string{"web-0": "web-0"}
+
+	assert.NoError(t, checkExistingPodsHealthy([]corev1.Pod{ready("web-0"), ready("web-1")}, existing, 2))
+	assert.EqualError(t, checkExistingPodsHealthy([]corev1.Pod{ready("web-0"), ready("web-1"), ready("web-2")}, existing, 2), "found 3 pods, exceeding the quota of 2 pods")
+	assert.EqualError(t, checkExistingPodsHealthy([]corev1.Pod{ready("web-1")}, existing, 2), "pod web-0 was removed")
+}
@@ -0,0 +1,147 @@
+package workloads
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	"github.com/rancher/shepherd/extensions/defaults"
+	"github.com/stretchr/testify/require"
+	appv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kwait "k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+)
+
+// unreachableRegistry is a registry host that never resolves, as the .invalid top-level domain is reserved.
+const unreachableRegistry = "registry.invalid"
+
+// failImagePulls makes pulls of the image by the pods of the deployment fail until `failures` pulls have failed.
+// Making a registry fail on demand depends on the environment, so by default the deployment is rolled out with the
+// image from an unreachable registry. Tests for environments with a registry that can fail pulls, e.g. a pull-through
+// proxy, can replace it.
+var failImagePulls = func(client *rancher.Client, clusterID, namespaceName string, deployment *appv1.Deployment, image string, failures int) error {
+	wranglerContext, err := getWranglerContext(client, clusterID)
+	if err != nil {
+		return err
+	}
+
+	return simulatePullFailures(wranglerContext.Apps.Deployment(), wranglerContext.Core.Pod(), namespaceName, deployment, unreachableRegistry+"/"+image, failures, rolloutPollInterval, defaults.FiveMinuteTimeout)
+}
+
+// validateRolloutWithRegistryFlakiness makes the first flakyPulls pulls of newImage fail and validates that the
+// upgrade of the deployment to newImage completes once the pulls succeed.
+func validateRolloutWithRegistryFlakiness(t *testing.T, client *rancher.Client, clusterID, namespaceName string, deployment *appv1.Deployment, newImage string, flakyPulls int) {
+	wranglerContext, err := getWranglerContext(client, clusterID)
+	require.NoError(t, err)
+
+	failPulls := func() error {
+		return failImagePulls(client, clusterID, namespaceName, deployment, newImage, flakyPulls)
+	}
+
+	err = rolloutWithRegistryFlakiness(wranglerContext.Apps.Deployment(), wranglerContext.Core.Pod(), namespaceName, deployment, newImage, failPulls, rolloutPollInterval, defaults.TenMinuteTimeout)
+	require.NoError(t, err)
+}
+
+func rolloutWithRegistryFlakiness(deployments deploymentClient, pods podLister, namespaceName string, deployment *appv1.Deployment, newImage string, failPulls func() error, interval, timeout time.Duration) error {
+	if err := failPulls(); err != nil {
+		return fmt.Errorf("failed to make image pulls of %s fail: %w", newImage, err)
+	}
+
+	if err := setDeploymentImage(deployments, namespaceName, deployment.Name, newImage); err != nil {
+		return err
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		return err
+	}
+
+	var waitErr error
+	err = kwait.PollUntilContextTimeout(context.TODO(), interval, timeout, true, func(ctx context.Context) (bool, error) {
+		current, err := deployments.Get(namespaceName, deployment.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+
+		podList, err := pods.List(namespaceName, metav1.ListOptions{LabelSelector: selector.String()})
+		if err != nil {
+			return false, err
+		}
+
+		for _, pod := range podList.Items {
+			if reason, message, failed := imagePullFailure(pod); failed {
+				waitErr = fmt.Errorf("pod %s is %s: %s", pod.Name, reason, message)
+				return false, nil
+			}
+		}
+
+		if !isRolloutComplete(current) || !allPodsReadyWithImage(podList.Items, newImage) {
+			waitErr = fmt.Errorf("rollout of deployment %s is not complete", deployment.Name)
+			return false, nil
+		}
+
+		return true, nil
+	})
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("timed out waiting for deployment %s to roll out %s after failing image pulls: %w", deployment.Name, newImage, waitErr)
+	}
+
+	return err
+}
+
+// simulatePullFailures rolls out the unpullable image and waits until pulls failed in failures pods.
+func simulatePullFailures(deployments deploymentClient, pods podLister, namespaceName string, deployment *appv1.Deployment, unpullableImage string, failures int, interval, timeout time.Duration) error {
+	if failures == 0 {
+		return nil
+	}
+
+	if err := setDeploymentImage(deployments, namespaceName, deployment.Name, unpullableImage); err != nil {
+		return err
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		return err
+	}
+
+	failed := map[string]bool{}
+	err = kwait.PollUntilContextTimeout(context.TODO(), interval, timeout, true, func(ctx context.Context) (bool, error) {
+		podList, err := pods.List(namespaceName, metav1.ListOptions{LabelSelector: selector.String()})
+		if err != nil {
+			return false, err
+		}
+
+		for _, pod := range podList.Items {
+			if _, _, pullFailed := imagePullFailure(pod); pullFailed {
+				failed[pod.Name] = true
+			}
+		}
+
+		return len(failed) >= failures, nil
+	})
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("timed out waiting for %d failed pulls of %s, observed %d", failures, unpullableImage, len(failed))
+	}
+
+	return err
+}
+
+// setDeploymentImage sets the image of every container of the deployment.
+func setDeploymentImage(deployments deploymentClient, namespaceName, deploymentName, image string) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		deployment, err := deployments.Get(namespaceName, deploymentName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		for i := range deployment.Spec.Template.Spec.Containers {
+			deployment.Spec.Template.Spec.Containers[i].Image = image
+		}
+		_, err = deployments.Update(deployment)
+		return err
+	})
+}
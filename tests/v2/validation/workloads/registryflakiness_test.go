@@ -0,0 +1,122 @@
+package workloads
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+)
+
+// fakeFlakyRegistry simulates a registry that fails the next failuresLeft pulls, one per listing of the pods, and
+// never serves images of the unreachable registry.
+type fakeFlakyRegistry struct {
+	*fakeDeployments
+	failuresLeft int
+}
+
+func newFakeFlakyRegistry() *fakeFlakyRegistry {
+	deployment := newTestDeploymentWithImage("web", nginxImageName)
+	deployment.Spec.Replicas = pointer.Int32(2)
+	deployment.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}
+
+	f := &fakeFlakyRegistry{fakeDeployments: newFakeDeployments(deployment)}
+	f.onGet = func(deployment *appv1.Deployment) {
+		deployment.Status = newRolledOutStatus(deployment)
+		if !f.pullable() {
+			deployment.Status.AvailableReplicas = 0
+		}
+	}
+
+	return f
+}
+
+func (f *fakeFlakyRegistry) image() string {
+	return f.deployment.Spec.Template.Spec.Containers[0].Image
+}
+
+func (f *fakeFlakyRegistry) pullable() bool {
+	return f.failuresLeft == 0 && !strings.HasPrefix(f.image(), unreachableRegistry+"/")
+}
+
+func (f *fakeFlakyRegistry) List(namespace string, opts metav1.ListOptions) (*corev1.PodList, error) {
+	failing := !f.pullable()
+	if f.failuresLeft > 0 {
+		f.failuresLeft--
+	}
+
+	var pods []corev1.Pod
+	for i := int32(0); i < *f.deployment.Spec.Replicas; i++ {
+		pod := newTestPod(fmt.Sprintf("web-%d", i), "node-1")
+		pod.Spec = *f.deployment.Spec.Template.Spec.DeepCopy()
+		if failing {
+			pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+				Name:  "app",
+				State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff", Message: "Back-off pulling image " + f.image()}},
+			}}
+		} else {
+			pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}
+		}
+		pods = append(pods, pod)
+	}
+
+	return &corev1.PodList{Items: pods}, nil
+}
+
+func TestRolloutWithRegistryFlakiness(t *testing.T) {
+	fake := newFakeFlakyRegistry()
+	failPulls := func() error {
+		fake.failuresLeft = 3
+		return nil
+	}
+
+	err := rolloutWithRegistryFlakiness(fake, fake, "default", fake.deployment, redisImageName, failPulls, time.Millisecond, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, redisImageName, fake.image())
+	assert.Zero(t, fake.failuresLeft)
+}
+
+func TestRolloutWithRegistryFlakinessNeverRecovers(t *testing.T) {
+	fake := newFakeFlakyRegistry()
+	failPulls := func() error {
+		fake.failuresLeft = 1 << 30
+		return nil
+	}
+
+	err := rolloutWithRegistryFlakiness(fake, fake, "default", fake.deployment, redisImageName, failPulls, time.Millisecond, 20*time.Millisecond)
+	assert.EqualError(t, err, "timed out waiting for deployment web to roll out redis after failing image pulls: pod web-0 is ImagePullBackOff: Back-off pulling image redis")
+}
+
+func TestRolloutWithRegistryFlakinessSimulatorError(t *testing.T) {
+	fake := newFakeFlakyRegistry()
+
+	err := rolloutWithRegistryFlakiness(fake, fake, "default", fake.deployment, redisImageName, func() error { return errors.New("no registry proxy") }, time.Millisecond, time.Second)
+	assert.EqualError(t, err, "failed to make image pulls of redis fail: no registry proxy")
+	assert.Equal(t, nginxImageName, fake.image(), "the deployment should not be upgraded")
+}
+
+func TestSimulatePullFailures(t *testing.T) {
+	fake := newFakeFlakyRegistry()
+	unpullable := unreachableRegistry + "/" + redisImageName
+
+	err := simulatePullFailures(fake, fake, "default", fake.deployment, unpullable, 2, time.Millisecond, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, unpullable, fake.image())
+
+	err = rolloutWithRegistryFlakiness(fake, fake, "default", fake.deployment, redisImageName, func() error { return nil }, time.Millisecond, time.Second)
+	require.NoError(t, err)
+}
+
+func TestSimulatePullFailuresNotObserved(t *testing.T) {
+	fake := newFakeFlakyRegistry()
+
+	err := simulatePullFailures(fake, fake, "default", fake.deployment, redisImageName, 2, time.Millisecond, 20*time.Millisecond)
+	assert.EqualError(t, err, "timed out waiting for 2 failed pulls of redis, observed 0")
+}
@@ -0,0 +1,86 @@
+package workloads
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// capturePodUIDs returns the UIDs of the deployment's pods by pod name, to be passed to verifyNoUnnecessaryRestart.
+func capturePodUIDs(client *rancher.Client, clusterID, namespaceName string, deployment *appv1.Deployment) (map[string]types.UID, error) {
+	wranglerContext, err := getWranglerContext(client, clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	deploymentPods, err := listDeploymentPods(wranglerContext, namespaceName, deployment)
+	if err != nil {
+		return nil, err
+	}
+
+	uids := map[string]types.UID{}
+	for _, pod := range deploymentPods {
+		uids[pod.Name] = pod.UID
+	}
+
+	return uids, nil
+}
+
+// verifyNoUnnecessaryRestart verifies that the pods captured in beforeUIDs were not recreated by a change to the
+// deployment, unless the change modified the pod template. deployment is the deployment as it was before the change.
+func verifyNoUnnecessaryRestart(client *rancher.Client, clusterID, namespaceName string, deployment *appv1.Deployment, beforeUIDs map[string]types.UID) error {
+	wranglerContext, err := getWranglerContext(client, clusterID)
+	if err != nil {
+		return err
+	}
+
+	latestDeployment, err := wranglerContext.Apps.Deployment().Get(namespaceName, deployment.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	deploymentPods, err := listDeploymentPods(wranglerContext, namespaceName, latestDeployment)
+	if err != nil {
+		return err
+	}
+
+	templateChanged := !equality.Semantic.DeepEqual(deployment.Spec.Template, latestDeployment.Spec.Template)
+
+	return checkNoUnnecessaryRestart(templateChanged, beforeUIDs, deploymentPods)
+}
+
+// checkNoUnnecessaryRestart returns an error if the pod template did not change but pods were replaced. Pods may only
+// be added by a scale up or removed by a scale down, so as many pods as there are in the smaller set must be kept.
+func checkNoUnnecessaryRestart(templateChanged bool, beforeUIDs map[string]types.UID, currentPods []corev1.Pod) error {
+	if templateChanged {
+		return nil
+	}
+
+	currentUIDs := map[types.UID]bool{}
+	for _, pod := range currentPods {
+		currentUIDs[pod.UID] = true
+	}
+
+	var replaced []string
+	for name, uid := range beforeUIDs {
+		if !currentUIDs[uid] {
+			replaced = append(replaced, name)
+		}
+	}
+
+	kept := len(beforeUIDs) - len(replaced)
+	wantKept := min(len(beforeUIDs), len(currentPods))
+	if kept < wantKept {
+		sort.Strings(replaced)
+		return fmt.Errorf("pods %s were recreated although the pod template did not change", strings.Join(replaced, ", "))
+	}
+
+	return nil
+}
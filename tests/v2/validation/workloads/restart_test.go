@@ -0,0 +1,63 @@
+package workloads
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func newTestPodWithUID(name string) corev1.Pod {
+	return corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, UID: types.UID(name + "-uid")}}
+}
+
+func TestCheckNoUnnecessaryRestart(t *testing.T) {
+	beforeUIDs := map[string]types.UID{
+		"pod-1": "pod-1-uid",
+		"pod-2": "pod-2-uid",
+	}
+
+	tests := []struct {
+		name            string
+		templateChanged bool
+		currentPods     []corev1.Pod
+		wantErr         bool
+	}{
+		{
+			name:            "template change recreates pods",
+			templateChanged: true,
+			currentPods:     []corev1.Pod{newTestPodWithUID("pod-3"), newTestPodWithUID("pod-4")},
+		},
+		{
+			name:        "scale up preserves pods",
+			currentPods: []corev1.Pod{newTestPodWithUID("pod-1"), newTestPodWithUID("pod-2"), newTestPodWithUID("pod-3")},
+		},
+		{
+			name:        "scale down removes pods",
+			currentPods: []corev1.Pod{newTestPodWithUID("pod-2")},
+		},
+		{
+			name:        "replica change recreates pods",
+			currentPods: []corev1.Pod{newTestPodWithUID("pod-1"), newTestPodWithUID("pod-3"), newTestPodWithUID("pod-4")},
+			wantErr:     true,
+		},
+		{
+			name:        "metadata change recreates pods",
+			currentPods: []corev1.Pod{newTestPodWithUID("pod-3"), newTestPodWithUID("pod-4")},
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkNoUnnecessaryRestart(tt.templateChanged, beforeUIDs, tt.currentPods)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
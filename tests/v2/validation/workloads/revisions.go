@@ -0,0 +1,68 @@
+package workloads
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kwait "k8s.io/apimachinery/pkg/util/wait"
+)
+
+// revisionPollInterval is kept short so that revisions which are only current for a moment are still observed.
+const revisionPollInterval = 100 * time.Millisecond
+
+// WaitForRevisionSequence waits until the deployment went through the given revisions in order, returning an error
+// as soon as a later revision is observed before an earlier one, e.g. because successive updates were coalesced.
+// Revisions that are not part of the sequence are ignored.
+func WaitForRevisionSequence(client *rancher.Client, clusterID, namespaceName, name string, revisions []string, timeout time.Duration) error {
+	wranglerContext, err := getWranglerContext(client, clusterID)
+	if err != nil {
+		return err
+	}
+
+	return waitForRevisionSequence(wranglerContext.Apps.Deployment(), namespaceName, name, revisions, revisionPollInterval, timeout)
+}
+
+func waitForRevisionSequence(deployments deploymentClient, namespaceName, name string, revisions []string, interval, timeout time.Duration) error {
+	next := 0
+	err := kwait.PollUntilContextTimeout(context.TODO(), interval, timeout, true, func(ctx context.Context) (bool, error) {
+		deployment, err := deployments.Get(namespaceName, name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+
+		next, err = observeRevision(revisions, next, deployment.Annotations[revisionAnnotation])
+		if err != nil {
+			return false, err
+		}
+
+		return next == len(revisions), nil
+	})
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("timed out waiting for deployment %s to reach revision %s, observed revisions %s", name, revisions[next], strings.Join(revisions[:next], ","))
+	}
+
+	return err
+}
+
+// observeRevision returns the index of the next expected revision after observing revision, given the index of the
+// currently expected one. It returns an error if revision comes later in the sequence than the expected revision.
+func observeRevision(revisions []string, next int, revision string) (int, error) {
+	for i := next; i < len(revisions); i++ {
+		if revisions[i] != revision {
+			continue
+		}
+
+		if i > next {
+			return next, fmt.Errorf("revision %s was observed but revisions %s were skipped", revision, strings.Join(revisions[next:i], ","))
+		}
+
+		return next + 1, nil
+	}
+
+	return next, nil
+}
@@ -0,0 +1,53 @@
+package workloads
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	appv1 "k8s.io/api/apps/v1"
+)
+
+func TestWaitForRevisionSequence(t *testing.T) {
+	tests := []struct {
+		name     string
+		observed []string
+		wantErr  string
+	}{
+		{
+			name:     "every revision observed",
+			observed: []string{"1", "2", "2", "3", "4"},
+		},
+		{
+			name:     "revision observed several times",
+			observed: []string{"1", "1", "2", "3", "3", "4"},
+		},
+		{
+			name:     "revision skipped",
+			observed: []string{"1", "2", "4"},
+			wantErr:  "revision 4 was observed but revisions 3 were skipped",
+		},
+		{
+			name:     "sequence not completed",
+			observed: []string{"1", "2", "3"},
+			wantErr:  "timed out waiting for deployment web to reach revision 4, observed revisions 2,3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Report the revisions in order, repeating the last one once exhausted.
+			deployments := newFakeDeployments(newTestDeploymentWithImage("web", nginxImageName))
+			deployments.onGet = func(deployment *appv1.Deployment) {
+				deployment.Annotations = map[string]string{revisionAnnotation: nthGet(deployments, tt.observed)}
+			}
+
+			err := waitForRevisionSequence(deployments, "default", "web", []string{"2", "3", "4"}, time.Millisecond, 100*time.Millisecond)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
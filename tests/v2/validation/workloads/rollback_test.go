@@ -0,0 +1,65 @@
+package workloads
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newTestReplicaSet(deploymentName, revision, image string) appv1.ReplicaSet {
+	return appv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            deploymentName + "-" + revision,
+			Annotations:     map[string]string{revisionAnnotation: revision},
+			OwnerReferences: []metav1.OwnerReference{{Kind: "Deployment", Name: deploymentName}},
+		},
+		Spec: appv1.ReplicaSetSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: image}}},
+			},
+		},
+	}
+}
+
+func newTestDeploymentWithImage(name, image string) *appv1.Deployment {
+	return &appv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: appv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: image}}},
+			},
+		},
+	}
+}
+
+func TestRollbackRestoresTargetRevisionImage(t *testing.T) {
+	replicaSets := []appv1.ReplicaSet{
+		newTestReplicaSet("web", "1", nginxImageName),
+		newTestReplicaSet("web", "2", redisImageName),
+		newTestReplicaSet("other", "1", ubuntuImageName),
+	}
+
+	targetImages, err := revisionImages(replicaSets, "web", "1")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"app": nginxImageName}, targetImages)
+
+	// The deployment still runs the current revision's image, so the rollback did not restore the image.
+	err = checkContainerImages(newTestDeploymentWithImage("web", redisImageName), targetImages)
+	assert.ErrorContains(t, err, "expected nginx")
+
+	err = checkContainerImages(newTestDeploymentWithImage("web", nginxImageName), targetImages)
+	assert.NoError(t, err)
+}
+
+func TestRevisionImagesNotFound(t *testing.T) {
+	replicaSets := []appv1.ReplicaSet{
+		newTestReplicaSet("other", "1", ubuntuImageName),
+	}
+
+	_, err := revisionImages(replicaSets, "web", "1")
+	assert.Error(t, err)
+}
@@ -0,0 +1,144 @@
+package workloads
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	"github.com/rancher/shepherd/extensions/defaults"
+	"github.com/rancher/shepherd/extensions/kubectl"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	kwait "k8s.io/apimachinery/pkg/util/wait"
+)
+
+// restartedAtAnnotation is the pod template annotation kubectl rollout restart sets to trigger a new rollout.
+const restartedAtAnnotation = "kubectl.kubernetes.io/restartedAt"
+
+// RestartDeployment runs kubectl rollout restart on the deployment and waits until the restart produced a new
+// revision whose pods all replaced the previous ones, while the container images stayed the same.
+func RestartDeployment(client *rancher.Client, clusterID, namespaceName, name string) error {
+	wranglerContext, err := getWranglerContext(client, clusterID)
+	if err != nil {
+		return err
+	}
+
+	restart := func() error {
+		execCmd := []string{"kubectl", "rollout", "restart", "-n", namespaceName, fmt.Sprintf("deployment.apps/%s", name)}
+		_, err := kubectl.Command(client, nil, clusterID, execCmd, "")
+		return err
+	}
+
+	return restartDeployment(wranglerContext.Apps.Deployment(), wranglerContext.Core.Pod(), namespaceName, name, restart, rolloutPollInterval, defaults.FiveMinuteTimeout)
+}
+
+func restartDeployment(deployments deploymentClient, pods podLister, namespaceName, name string, restart func() error, interval, timeout time.Duration) error {
+	before, err := deployments.Get(namespaceName, name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(before.Spec.Selector)
+	if err != nil {
+		return err
+	}
+
+	podList, err := pods.List(namespaceName, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return err
+	}
+
+	beforeUIDs := map[types.UID]bool{}
+	for _, pod := range podList.Items {
+		beforeUIDs[pod.UID] = true
+	}
+
+	beforeImages := map[string]string{}
+	for _, container := range before.Spec.Template.Spec.Containers {
+		beforeImages[container.Name] = container.Image
+	}
+
+	if err := restart(); err != nil {
+		return err
+	}
+
+	var waitErr error
+	err = kwait.PollUntilContextTimeout(context.TODO(), interval, timeout, true, func(ctx context.Context) (bool, error) {
+		current, err := deployments.Get(namespaceName, name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+
+		if err := checkContainerImages(current, beforeImages); err != nil {
+			return false, err
+		}
+
+		waitErr = checkRestarted(before, current)
+		if waitErr != nil {
+			return false, nil
+		}
+
+		podList, err := pods.List(namespaceName, metav1.ListOptions{LabelSelector: selector.String()})
+		if err != nil {
+			return false, err
+		}
+
+		replicas := int32(1)
+		if current.Spec.Replicas != nil {
+			replicas = *current.Spec.Replicas
+		}
+
+		waitErr = checkPodsRecreated(podList.Items, beforeUIDs, int(replicas))
+		return waitErr == nil, nil
+	})
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("timed out waiting for restart of deployment %s: %w", name, waitErr)
+	}
+
+	return err
+}
+
+// checkRestarted returns an error unless the restart annotation of the pod template changed, the deployment moved to
+// a newer revision and its rollout completed.
+func checkRestarted(before, current *appv1.Deployment) error {
+	restartedAt := current.Spec.Template.Annotations[restartedAtAnnotation]
+	if restartedAt == "" || restartedAt == before.Spec.Template.Annotations[restartedAtAnnotation] {
+		return fmt.Errorf("annotation %s of deployment %s was not updated", restartedAtAnnotation, current.Name)
+	}
+
+	beforeRevision, _ := strconv.Atoi(before.Annotations[revisionAnnotation])
+	currentRevision, err := strconv.Atoi(current.Annotations[revisionAnnotation])
+	if err != nil || currentRevision <= beforeRevision {
+		return fmt.Errorf("deployment %s is at revision %q, expected a revision newer than %d", current.Name, current.Annotations[revisionAnnotation], beforeRevision)
+	}
+
+	if !isRolloutComplete(current) {
+		return fmt.Errorf("rollout of deployment %s is not complete", current.Name)
+	}
+
+	return nil
+}
+
+// checkPodsRecreated returns an error unless there are expectedReplicas ready pods and none of them existed before the
+// restart.
+func checkPodsRecreated(pods []corev1.Pod, beforeUIDs map[types.UID]bool, expectedReplicas int) error {
+	if len(pods) != expectedReplicas {
+		return fmt.Errorf("found %d pods, expected %d", len(pods), expectedReplicas)
+	}
+
+	for _, pod := range pods {
+		if beforeUIDs[pod.UID] {
+			return fmt.Errorf("pod %s was not recreated", pod.Name)
+		}
+		if pod.DeletionTimestamp != nil || !isPodReady(pod) {
+			return fmt.Errorf("pod %s is not ready", pod.Name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,135 @@
+package workloads
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/pointer"
+)
+
+// fakeRolloutRestart simulates kubectl rollout restart: restarting stamps the restart annotation, bumps the revision
+// and, unless keepPods is set, replaces every pod with a new one.
+type fakeRolloutRestart struct {
+	*fakeDeployments
+	restarts int
+	keepPods bool
+}
+
+func newFakeRolloutRestart() *fakeRolloutRestart {
+	deployment := newTestDeploymentWithImage("web", nginxImageName)
+	deployment.Spec.Replicas = pointer.Int32(2)
+	deployment.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}
+	deployment.Annotations = map[string]string{revisionAnnotation: "1"}
+
+	return &fakeRolloutRestart{fakeDeployments: newFakeDeployments(deployment)}
+}
+
+func (f *fakeRolloutRestart) restart() error {
+	f.restarts++
+	f.deployment.Generation++
+	f.deployment.Annotations[revisionAnnotation] = fmt.Sprint(f.restarts + 1)
+	f.deployment.Spec.Template.Annotations = map[string]string{restartedAtAnnotation: fmt.Sprintf("2024-01-01T00:00:0%dZ", f.restarts)}
+
+	return nil
+}
+
+func (f *fakeRolloutRestart) List(namespace string, opts metav1.ListOptions) (*corev1.PodList, error) {
+	generation := f.restarts
+	if f.keepPods {
+		generation = 0
+	}
+
+	var pods []corev1.Pod
+	for i := int32(0); i < *f.deployment.Spec.Replicas; i++ {
+		name := fmt.Sprintf("web-%d-%d", generation, i)
+		pods = append(pods, corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, UID: types.UID(name)},
+			Spec:       f.deployment.Spec.Template.Spec,
+			Status: corev1.PodStatus{
+				Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+			},
+		})
+	}
+
+	return &corev1.PodList{Items: pods}, nil
+}
+
+func TestRestartDeployment(t *testing.T) {
+	fake := newFakeRolloutRestart()
+
+	err := restartDeployment(fake, fake, "default", "web", fake.restart, 10*time.Millisecond, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, 1, fake.restarts)
+	assert.Equal(t, "2", fake.deployment.Annotations[revisionAnnotation])
+	assert.Equal(t, nginxImageName, fake.deployment.Spec.Template.Spec.Containers[0].Image)
+}
+
+func TestRestartDeploymentPodsNotRecreated(t *testing.T) {
+	fake := newFakeRolloutRestart()
+	fake.keepPods = true
+
+	err := restartDeployment(fake, fake, "default", "web", fake.restart, 10*time.Millisecond, 100*time.Millisecond)
+	assert.EqualError(t, err, "timed out waiting for restart of deployment web: pod web-0-0 was not recreated")
+}
+
+func TestRestartDeploymentCommandError(t *testing.T) {
+	fake := newFakeRolloutRestart()
+
+	err := restartDeployment(fake, fake, "default", "web", func() error { return errors.New("forbidden") }, 10*time.Millisecond, time.Second)
+	assert.EqualError(t, err, "forbidden")
+}
+
+func TestCheckRestarted(t *testing.T) {
+	before := newTestDeploymentWithImage("web", nginxImageName)
+	before.Annotations = map[string]string{revisionAnnotation: "3"}
+	before.Status = appv1.DeploymentStatus{Replicas: 1, UpdatedReplicas: 1, AvailableReplicas: 1}
+
+	restarted := func(revision, restartedAt string) *appv1.Deployment {
+		deployment := before.DeepCopy()
+		deployment.Annotations[revisionAnnotation] = revision
+		if restartedAt != "" {
+			deployment.Spec.Template.Annotations = map[string]string{restartedAtAnnotation: restartedAt}
+		}
+		return deployment
+	}
+
+	tests := []struct {
+		name    string
+		current *appv1.Deployment
+		wantErr string
+	}{
+		{
+			name:    "restarted",
+			current: restarted("4", "2024-01-01T00:00:00Z"),
+		},
+		{
+			name:    "annotation not updated",
+			current: restarted("4", ""),
+			wantErr: "annotation kubectl.kubernetes.io/restartedAt of deployment web was not updated",
+		},
+		{
+			name:    "same revision",
+			current: restarted("3", "2024-01-01T00:00:00Z"),
+			wantErr: `deployment web is at revision "3", expected a revision newer than 3`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkRestarted(before, tt.current)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
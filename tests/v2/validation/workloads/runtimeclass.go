@@ -0,0 +1,32 @@
+package workloads
+
+import (
+	"fmt"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// verifyPodRuntimeClass verifies that the running pods of the deployment use the wanted runtime class, e.g. gvisor or
+// kata. An empty wantRuntimeClass stands for pods using the default runtime of the node, i.e. without a runtime class.
+func verifyPodRuntimeClass(client *rancher.Client, clusterID, namespaceName string, deployment *appv1.Deployment, wantRuntimeClass string) error {
+	return verifyDeploymentPods(client, clusterID, namespaceName, deployment, func(pods []corev1.Pod) error {
+		return checkRunningPods(pods, podRuntimeClassCheck(wantRuntimeClass))
+	})
+}
+
+// podRuntimeClassCheck returns a check that fails for a pod that does not use the wanted runtime class.
+func podRuntimeClassCheck(wantRuntimeClass string) func(corev1.Pod) error {
+	return func(pod corev1.Pod) error {
+		runtimeClass := ""
+		if pod.Spec.RuntimeClassName != nil {
+			runtimeClass = *pod.Spec.RuntimeClassName
+		}
+		if runtimeClass != wantRuntimeClass {
+			return fmt.Errorf("pod %s has runtime class %q, expected %q", pod.Name, runtimeClass, wantRuntimeClass)
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,56 @@
+package workloads
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/pointer"
+)
+
+func TestPodRuntimeClassCheck(t *testing.T) {
+	tests := []struct {
+		name             string
+		pod              corev1.Pod
+		wantRuntimeClass string
+		wantErr          string
+	}{
+		{
+			name:             "matching runtime class",
+			pod:              newRunningTestPod("pod-1", corev1.PodSpec{RuntimeClassName: pointer.String("gvisor")}),
+			wantRuntimeClass: "gvisor",
+		},
+		{
+			name: "default runtime",
+			pod:  newRunningTestPod("pod-1", corev1.PodSpec{}),
+		},
+		{
+			name:             "mismatched runtime class",
+			pod:              newRunningTestPod("pod-1", corev1.PodSpec{RuntimeClassName: pointer.String("kata")}),
+			wantRuntimeClass: "gvisor",
+			wantErr:          `pod pod-1 has runtime class "kata", expected "gvisor"`,
+		},
+		{
+			name:             "default runtime instead of runtime class",
+			pod:              newRunningTestPod("pod-1", corev1.PodSpec{}),
+			wantRuntimeClass: "kata",
+			wantErr:          `pod pod-1 has runtime class "", expected "kata"`,
+		},
+		{
+			name:    "runtime class instead of default runtime",
+			pod:     newRunningTestPod("pod-1", corev1.PodSpec{RuntimeClassName: pointer.String("kata")}),
+			wantErr: `pod pod-1 has runtime class "kata", expected ""`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := podRuntimeClassCheck(tt.wantRuntimeClass)(tt.pod)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package workloads
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	"github.com/rancher/shepherd/extensions/defaults"
+	appv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kwait "k8s.io/apimachinery/pkg/util/wait"
+)
+
+// WaitForZeroPods waits until a deployment that was scaled down to zero has no pods left and reports zero replicas
+// in its status.
+func WaitForZeroPods(client *rancher.Client, clusterID, namespaceName string, deployment *appv1.Deployment) error {
+	wranglerContext, err := getWranglerContext(client, clusterID)
+	if err != nil {
+		return err
+	}
+
+	return waitForZeroPods(wranglerContext.Apps.Deployment(), wranglerContext.Core.Pod(), namespaceName, deployment, rolloutPollInterval, defaults.FiveMinuteTimeout)
+}
+
+func waitForZeroPods(deployments deploymentClient, pods podLister, namespaceName string, deployment *appv1.Deployment, interval, timeout time.Duration) error {
+	selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		return err
+	}
+
+	var statusReplicas int32
+	remainingPods := 0
+	err = kwait.PollUntilContextTimeout(context.TODO(), interval, timeout, true, func(ctx context.Context) (bool, error) {
+		latestDeployment, err := deployments.Get(namespaceName, deployment.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		statusReplicas = latestDeployment.Status.Replicas
+
+		podList, err := pods.List(namespaceName, metav1.ListOptions{LabelSelector: selector.String()})
+		if err != nil {
+			return false, err
+		}
+		remainingPods = len(podList.Items)
+
+		return statusReplicas == 0 && remainingPods == 0, nil
+	})
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("timed out waiting for deployment %s to have no pods, observed %d pods and %d status replicas", deployment.Name, remainingPods, statusReplicas)
+	}
+
+	return err
+}
@@ -0,0 +1,58 @@
+package workloads
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// fakeDrain simulates a deployment scaled down to zero that terminates one pod per poll.
+type fakeDrain struct {
+	*fakeDeployments
+	pods int
+}
+
+func newFakeDrain(deployment *appv1.Deployment, pods int) *fakeDrain {
+	f := &fakeDrain{fakeDeployments: newFakeDeployments(deployment), pods: pods}
+	f.onGet = func(deployment *appv1.Deployment) {
+		deployment.Status.Replicas = int32(f.pods)
+	}
+
+	return f
+}
+
+func (f *fakeDrain) List(namespace string, opts metav1.ListOptions) (*corev1.PodList, error) {
+	var pods []corev1.Pod
+	for i := 0; i < f.pods; i++ {
+		pods = append(pods, corev1.Pod{})
+	}
+	if f.pods > 0 {
+		f.pods--
+	}
+
+	return &corev1.PodList{Items: pods}, nil
+}
+
+func TestWaitForZeroPods(t *testing.T) {
+	deployment := newTestDeploymentWithImage("web", nginxImageName)
+	deployment.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}
+
+	drain := newFakeDrain(deployment, 3)
+	err := waitForZeroPods(drain, drain, "default", deployment, time.Millisecond, 5*time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, drain.pods)
+}
+
+func TestWaitForZeroPodsTimeout(t *testing.T) {
+	deployment := newTestDeploymentWithImage("web", nginxImageName)
+	deployment.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}
+
+	// The pods are drained one per poll, so they can not all be gone within two polls.
+	drain := newFakeDrain(deployment, 100)
+	err := waitForZeroPods(drain, drain, "default", deployment, 10*time.Millisecond, 15*time.Millisecond)
+	assert.ErrorContains(t, err, "timed out waiting for deployment web to have no pods")
+}
@@ -0,0 +1,33 @@
+package workloads
+
+import (
+	"fmt"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// verifyPodScheduler verifies that the pods of the deployment carry the wanted scheduler name and were scheduled onto
+// a node. Pods assigned to a scheduler that does not run in the cluster stay Pending without a node, which is
+// reported as such instead of as a scheduler name mismatch, so pending pods are checked as well.
+func verifyPodScheduler(client *rancher.Client, clusterID, namespaceName string, deployment *appv1.Deployment, wantSchedulerName string) error {
+	return verifyDeploymentPods(client, clusterID, namespaceName, deployment, func(pods []corev1.Pod) error {
+		return checkPods(pods, podSchedulerCheck(wantSchedulerName))
+	})
+}
+
+// podSchedulerCheck returns a check that fails for a pod that does not use the wanted scheduler or was not scheduled
+// onto a node.
+func podSchedulerCheck(wantSchedulerName string) func(corev1.Pod) error {
+	return func(pod corev1.Pod) error {
+		if pod.Spec.SchedulerName != wantSchedulerName {
+			return fmt.Errorf("pod %s has scheduler %q, expected %q", pod.Name, pod.Spec.SchedulerName, wantSchedulerName)
+		}
+		if pod.Spec.NodeName == "" {
+			return fmt.Errorf("pod %s was not scheduled by %s and is %s", pod.Name, wantSchedulerName, pod.Status.Phase)
+		}
+
+		return nil
+	}
+}
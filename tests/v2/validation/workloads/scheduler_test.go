@@ -0,0 +1,49 @@
+package workloads
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestPodSchedulerCheck(t *testing.T) {
+	unscheduled := newPendingTestPod("pod-1")
+	unscheduled.Spec.SchedulerName = "missing-scheduler"
+
+	tests := []struct {
+		name              string
+		pod               corev1.Pod
+		wantSchedulerName string
+		wantErr           string
+	}{
+		{
+			name:              "scheduled by the custom scheduler",
+			pod:               newRunningTestPod("pod-1", corev1.PodSpec{SchedulerName: "my-scheduler"}),
+			wantSchedulerName: "my-scheduler",
+		},
+		{
+			name:              "mismatched scheduler",
+			pod:               newRunningTestPod("pod-1", corev1.PodSpec{SchedulerName: corev1.DefaultSchedulerName}),
+			wantSchedulerName: "my-scheduler",
+			wantErr:           `pod pod-1 has scheduler "default-scheduler", expected "my-scheduler"`,
+		},
+		{
+			name:              "scheduler absent",
+			pod:               unscheduled,
+			wantSchedulerName: "missing-scheduler",
+			wantErr:           "pod pod-1 was not scheduled by missing-scheduler and is Pending",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := podSchedulerCheck(tt.wantSchedulerName)(tt.pod)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
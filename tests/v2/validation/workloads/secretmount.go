@@ -0,0 +1,160 @@
+package workloads
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	"github.com/rancher/shepherd/extensions/defaults"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kwait "k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+)
+
+// secretCreator is the subset of the wrangler Secret client needed to create a secret.
+type secretCreator interface {
+	Create(secret *corev1.Secret) (*corev1.Secret, error)
+}
+
+// CreateSecretAndVerifyMount creates the secret in the downstream cluster, makes every container of the deployment
+// mount it at mountPath and waits until all running pods of the deployment mount it and are ready.
+func CreateSecretAndVerifyMount(client *rancher.Client, clusterID, namespaceName string, secret *corev1.Secret, deployment *appv1.Deployment, mountPath string) error {
+	wranglerContext, err := getWranglerContext(client, clusterID)
+	if err != nil {
+		return err
+	}
+
+	return createSecretAndVerifyMount(wranglerContext.Core.Secret(), wranglerContext.Apps.Deployment(), wranglerContext.Core.Pod(), namespaceName, secret, deployment, mountPath, rolloutPollInterval, defaults.FiveMinuteTimeout)
+}
+
+func createSecretAndVerifyMount(secrets secretCreator, deployments deploymentClient, pods podLister, namespaceName string, secret *corev1.Secret, deployment *appv1.Deployment, mountPath string, interval, timeout time.Duration) error {
+	secret = secret.DeepCopy()
+	secret.Namespace = namespaceName
+	createdSecret, err := secrets.Create(secret)
+	if err != nil {
+		return fmt.Errorf("error creating secret %s: %w", secret.Name, err)
+	}
+
+	var updatedDeployment *appv1.Deployment
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latestDeployment, err := deployments.Get(namespaceName, deployment.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		if mountsSecret(latestDeployment.Spec.Template.Spec, createdSecret.Name, mountPath) {
+			updatedDeployment = latestDeployment
+			return nil
+		}
+
+		addSecretMount(&latestDeployment.Spec.Template.Spec, createdSecret.Name, mountPath)
+		updatedDeployment, err = deployments.Update(latestDeployment)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(updatedDeployment.Spec.Selector)
+	if err != nil {
+		return err
+	}
+
+	var pending []string
+	err = kwait.PollUntilContextTimeout(context.TODO(), interval, timeout, true, func(ctx context.Context) (bool, error) {
+		current, err := deployments.Get(namespaceName, deployment.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		if !isRolloutComplete(current) {
+			return false, nil
+		}
+
+		podList, err := pods.List(namespaceName, metav1.ListOptions{LabelSelector: selector.String()})
+		if err != nil {
+			return false, err
+		}
+
+		pending = podsNotMountingSecret(podList.Items, createdSecret.Name, mountPath)
+		return len(podList.Items) > 0 && len(pending) == 0, nil
+	})
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("timed out waiting for pods of deployment %s to mount secret %s at %s, pods not mounting it: %v", deployment.Name, createdSecret.Name, mountPath, pending)
+	}
+
+	return err
+}
+
+// addSecretMount adds a volume for the secret to the pod spec and mounts it read only at mountPath in every container.
+func addSecretMount(podSpec *corev1.PodSpec, secretName, mountPath string) {
+	volumeName := secretVolumeName(*podSpec, secretName)
+	if volumeName == "" {
+		volumeName = secretName
+		podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+			Name:         volumeName,
+			VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: secretName}},
+		})
+	}
+
+	for i := range podSpec.Containers {
+		if !hasVolumeMount(podSpec.Containers[i], volumeName, mountPath) {
+			podSpec.Containers[i].VolumeMounts = append(podSpec.Containers[i].VolumeMounts, corev1.VolumeMount{
+				Name:      volumeName,
+				MountPath: mountPath,
+				ReadOnly:  true,
+			})
+		}
+	}
+}
+
+// mountsSecret returns true if every container of the pod spec mounts the secret at mountPath.
+func mountsSecret(podSpec corev1.PodSpec, secretName, mountPath string) bool {
+	volumeName := secretVolumeName(podSpec, secretName)
+	if volumeName == "" || len(podSpec.Containers) == 0 {
+		return false
+	}
+
+	for _, container := range podSpec.Containers {
+		if !hasVolumeMount(container, volumeName, mountPath) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// podsNotMountingSecret returns the names of the pods that are terminating, not ready or don't mount the secret.
+func podsNotMountingSecret(pods []corev1.Pod, secretName, mountPath string) []string {
+	var pending []string
+	for _, pod := range pods {
+		if pod.DeletionTimestamp != nil || !isPodReady(pod) || !mountsSecret(pod.Spec, secretName, mountPath) {
+			pending = append(pending, pod.Name)
+		}
+	}
+
+	return pending
+}
+
+func secretVolumeName(podSpec corev1.PodSpec, secretName string) string {
+	for _, volume := range podSpec.Volumes {
+		if volume.Secret != nil && volume.Secret.SecretName == secretName {
+			return volume.Name
+		}
+	}
+
+	return ""
+}
+
+func hasVolumeMount(container corev1.Container, volumeName, mountPath string) bool {
+	for _, mount := range container.VolumeMounts {
+		if mount.Name == volumeName && mount.MountPath == mountPath {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,121 @@
+package workloads
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+)
+
+// fakeSecretMount simulates a cluster whose pods run the current pod template of the deployment, unless stale is set
+// in which case they keep running the template the deployment was created with.
+type fakeSecretMount struct {
+	*fakeDeployments
+	secrets   map[string]*corev1.Secret
+	createErr error
+	original  corev1.PodSpec
+	stale     bool
+}
+
+func newFakeSecretMount() *fakeSecretMount {
+	deployment := newTestDeploymentWithImage("web", nginxImageName)
+	deployment.Spec.Replicas = pointer.Int32(2)
+	deployment.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}
+
+	return &fakeSecretMount{
+		fakeDeployments: newFakeDeployments(deployment),
+		secrets:         map[string]*corev1.Secret{},
+		original:        *deployment.Spec.Template.Spec.DeepCopy(),
+	}
+}
+
+func (f *fakeSecretMount) Create(secret *corev1.Secret) (*corev1.Secret, error) {
+	if f.createErr != nil {
+		return nil, f.createErr
+	}
+
+	f.secrets[secret.Namespace+"/"+secret.Name] = secret.DeepCopy()
+	return secret, nil
+}
+
+func (f *fakeSecretMount) List(namespace string, opts metav1.ListOptions) (*corev1.PodList, error) {
+	podSpec := f.deployment.Spec.Template.Spec
+	if f.stale {
+		podSpec = f.original
+	}
+
+	var pods []corev1.Pod
+	for i := int32(0); i < *f.deployment.Spec.Replicas; i++ {
+		pods = append(pods, corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-pod"},
+			Spec:       *podSpec.DeepCopy(),
+			Status: corev1.PodStatus{
+				Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+			},
+		})
+	}
+
+	return &corev1.PodList{Items: pods}, nil
+}
+
+func newTestSecret(name string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Data:       map[string][]byte{"password": []byte("secret")},
+	}
+}
+
+func TestCreateSecretAndVerifyMount(t *testing.T) {
+	fake := newFakeSecretMount()
+
+	err := createSecretAndVerifyMount(fake, fake, fake, "default", newTestSecret("creds"), fake.deployment, "/etc/creds", 10*time.Millisecond, time.Second)
+	require.NoError(t, err)
+
+	assert.Contains(t, fake.secrets, "default/creds")
+	assert.True(t, mountsSecret(fake.deployment.Spec.Template.Spec, "creds", "/etc/creds"))
+	assert.Equal(t, int64(1), fake.deployment.Generation)
+}
+
+func TestCreateSecretAndVerifyMountAlreadyReferenced(t *testing.T) {
+	fake := newFakeSecretMount()
+	addSecretMount(&fake.deployment.Spec.Template.Spec, "creds", "/etc/creds")
+
+	err := createSecretAndVerifyMount(fake, fake, fake, "default", newTestSecret("creds"), fake.deployment, "/etc/creds", 10*time.Millisecond, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), fake.deployment.Generation, "deployment already mounting the secret should not be updated")
+}
+
+func TestCreateSecretAndVerifyMountCreateError(t *testing.T) {
+	fake := newFakeSecretMount()
+	fake.createErr = errors.New("forbidden")
+
+	err := createSecretAndVerifyMount(fake, fake, fake, "default", newTestSecret("creds"), fake.deployment, "/etc/creds", 10*time.Millisecond, time.Second)
+	assert.EqualError(t, err, "error creating secret creds: forbidden")
+}
+
+func TestCreateSecretAndVerifyMountNotMounted(t *testing.T) {
+	fake := newFakeSecretMount()
+	fake.stale = true
+
+	err := createSecretAndVerifyMount(fake, fake, fake, "default", newTestSecret("creds"), fake.deployment, "/etc/creds", 10*time.Millisecond, 100*time.Millisecond)
+	assert.ErrorContains(t, err, "timed out waiting for pods of deployment web to mount secret creds at /etc/creds")
+}
+
+func TestMountsSecret(t *testing.T) {
+	podSpec := corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}, {Name: "sidecar"}}}
+	assert.False(t, mountsSecret(podSpec, "creds", "/etc/creds"))
+
+	addSecretMount(&podSpec, "creds", "/etc/creds")
+	assert.True(t, mountsSecret(podSpec, "creds", "/etc/creds"))
+	assert.False(t, mountsSecret(podSpec, "creds", "/etc/other"))
+	assert.False(t, mountsSecret(podSpec, "other", "/etc/creds"))
+
+	addSecretMount(&podSpec, "creds", "/etc/creds")
+	assert.Len(t, podSpec.Volumes, 1)
+	assert.Len(t, podSpec.Containers[1].VolumeMounts, 1)
+}
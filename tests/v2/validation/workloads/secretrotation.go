@@ -0,0 +1,184 @@
+package workloads
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	"github.com/rancher/shepherd/extensions/defaults"
+	"github.com/rancher/shepherd/extensions/kubectl"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	kwait "k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+)
+
+// secretChecksumAnnotation is the pod template annotation holding the checksum of the secret, which apps, e.g. deployed
+// by Helm charts, update to roll their pods when the secret changes.
+const secretChecksumAnnotation = "checksum/secret"
+
+// secretClient is the subset of the wrangler Secret client needed to update a secret.
+type secretClient interface {
+	Get(namespace, name string, opts metav1.GetOptions) (*corev1.Secret, error)
+	Update(secret *corev1.Secret) (*corev1.Secret, error)
+}
+
+// podEnvReader returns the value of the env var in the container of the pod.
+type podEnvReader func(pod corev1.Pod, containerName, envName string) (string, error)
+
+// verifyEnvFromSecretRotation sets the key of the secret to newValue and verifies that the pods of the deployment see
+// newValue in the env var the key is exposed as. Env vars are resolved when a container starts, so unlike a mounted
+// secret they are not updated in running pods, and the pods have to be replaced: if the deployment has the
+// checksum/secret annotation, its tooling is expected to update it, which rolls the pods, otherwise the deployment is
+// restarted the way kubectl rollout restart does.
+func verifyEnvFromSecretRotation(client *rancher.Client, clusterID, namespaceName string, deployment *appv1.Deployment, secretName, key, newValue string) error {
+	wranglerContext, err := getWranglerContext(client, clusterID)
+	if err != nil {
+		return err
+	}
+
+	readEnv := func(pod corev1.Pod, containerName, envName string) (string, error) {
+		execCmd := []string{"kubectl", "exec", "-n", namespaceName, pod.Name, "-c", containerName, "--", "printenv", envName}
+		output, err := kubectl.Command(client, nil, clusterID, execCmd, "")
+		return strings.TrimSpace(output), err
+	}
+
+	return envFromSecretRotation(wranglerContext.Core.Secret(), wranglerContext.Apps.Deployment(), wranglerContext.Core.Pod(), readEnv, namespaceName, deployment, secretName, key, newValue, rolloutPollInterval, defaults.FiveMinuteTimeout)
+}
+
+func envFromSecretRotation(secrets secretClient, deployments deploymentClient, pods podLister, readEnv podEnvReader, namespaceName string, deployment *appv1.Deployment, secretName, key, newValue string, interval, timeout time.Duration) error {
+	before, err := deployments.Get(namespaceName, deployment.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	containerName, envName, ok := secretKeyEnv(before.Spec.Template.Spec, secretName, key)
+	if !ok {
+		return fmt.Errorf("deployment %s does not expose key %s of secret %s as env var", deployment.Name, key, secretName)
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(before.Spec.Selector)
+	if err != nil {
+		return err
+	}
+
+	podList, err := pods.List(namespaceName, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return err
+	}
+
+	beforeUIDs := map[types.UID]bool{}
+	for _, pod := range podList.Items {
+		beforeUIDs[pod.UID] = true
+	}
+
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		secret, err := secrets.Get(namespaceName, secretName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		if secret.Data == nil {
+			secret.Data = map[string][]byte{}
+		}
+		secret.Data[key] = []byte(newValue)
+		_, err = secrets.Update(secret)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("error rotating secret %s: %w", secretName, err)
+	}
+
+	beforeChecksum, hasChecksum := before.Spec.Template.Annotations[secretChecksumAnnotation]
+	if !hasChecksum {
+		err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			current, err := deployments.Get(namespaceName, deployment.Name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+
+			if current.Spec.Template.Annotations == nil {
+				current.Spec.Template.Annotations = map[string]string{}
+			}
+			current.Spec.Template.Annotations[restartedAtAnnotation] = time.Now().Format(time.RFC3339)
+			_, err = deployments.Update(current)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("error restarting deployment %s: %w", deployment.Name, err)
+		}
+	}
+
+	var waitErr error
+	err = kwait.PollUntilContextTimeout(context.TODO(), interval, timeout, true, func(ctx context.Context) (bool, error) {
+		current, err := deployments.Get(namespaceName, deployment.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+
+		if hasChecksum && current.Spec.Template.Annotations[secretChecksumAnnotation] == beforeChecksum {
+			waitErr = fmt.Errorf("annotation %s of deployment %s was not updated", secretChecksumAnnotation, deployment.Name)
+			return false, nil
+		}
+		if !isRolloutComplete(current) {
+			waitErr = fmt.Errorf("rollout of deployment %s is not complete", deployment.Name)
+			return false, nil
+		}
+
+		podList, err := pods.List(namespaceName, metav1.ListOptions{LabelSelector: selector.String()})
+		if err != nil {
+			return false, err
+		}
+
+		replicas := int32(1)
+		if current.Spec.Replicas != nil {
+			replicas = *current.Spec.Replicas
+		}
+
+		if waitErr = checkPodsRecreated(podList.Items, beforeUIDs, int(replicas)); waitErr != nil {
+			return false, nil
+		}
+
+		for _, pod := range podList.Items {
+			value, err := readEnv(pod, containerName, envName)
+			if err != nil {
+				return false, err
+			}
+			if value != newValue {
+				waitErr = fmt.Errorf("env var %s of pod %s has value %q, expected %q", envName, pod.Name, value, newValue)
+				return false, nil
+			}
+		}
+
+		return true, nil
+	})
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("timed out waiting for pods of deployment %s to use the rotated secret %s: %w", deployment.Name, secretName, waitErr)
+	}
+
+	return err
+}
+
+// secretKeyEnv returns the container and the name of the env var the key of the secret is exposed as, either by an env
+// var referencing the key or by envFrom importing the whole secret.
+func secretKeyEnv(podSpec corev1.PodSpec, secretName, key string) (string, string, bool) {
+	for _, container := range podSpec.Containers {
+		for _, env := range container.Env {
+			if env.ValueFrom != nil && env.ValueFrom.SecretKeyRef != nil && env.ValueFrom.SecretKeyRef.Name == secretName && env.ValueFrom.SecretKeyRef.Key == key {
+				return container.Name, env.Name, true
+			}
+		}
+		for _, envFrom := range container.EnvFrom {
+			if envFrom.SecretRef != nil && envFrom.SecretRef.Name == secretName {
+				return container.Name, envFrom.Prefix + key, true
+			}
+		}
+	}
+
+	return "", "", false
+}
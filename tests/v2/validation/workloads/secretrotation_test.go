@@ -0,0 +1,158 @@
+package workloads
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/pointer"
+)
+
+// fakeSecretRotation simulates a deployment reading the password key of the creds secret from an env var. Its pods
+// only see the value the secret had when they were started, and are replaced when the pod template changes. With
+// checksumTooling set, rotating the secret updates the checksum annotation like Helm-based tooling does, and with
+// ignoreRestart set, pod template changes do not replace the pods.
+type fakeSecretRotation struct {
+	*fakeDeployments
+	secret          *corev1.Secret
+	generation      int
+	startedWith     string
+	checksumTooling bool
+	ignoreRestart   bool
+}
+
+func newFakeSecretRotation() *fakeSecretRotation {
+	deployment := newTestDeploymentWithImage("web", nginxImageName)
+	deployment.Spec.Replicas = pointer.Int32(2)
+	deployment.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}
+	deployment.Spec.Template.Spec.Containers[0].Env = []corev1.EnvVar{{
+		Name:      "PASSWORD",
+		ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "creds"}, Key: "password"}},
+	}}
+
+	f := &fakeSecretRotation{
+		fakeDeployments: newFakeDeployments(deployment),
+		secret:          &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "creds"}, Data: map[string][]byte{"password": []byte("old")}},
+		startedWith:     "old",
+	}
+	f.onUpdate = func(*appv1.Deployment) {
+		f.restartPods()
+	}
+
+	return f
+}
+
+// roll simulates a change of the pod template made by tooling rather than through Update.
+func (f *fakeSecretRotation) roll() {
+	f.deployment.Generation++
+	f.restartPods()
+}
+
+// restartPods replaces the pods after a change of the pod template, unless ignoreRestart is set.
+func (f *fakeSecretRotation) restartPods() {
+	if !f.ignoreRestart {
+		f.generation++
+		f.startedWith = string(f.secret.Data["password"])
+	}
+}
+
+func (f *fakeSecretRotation) List(namespace string, opts metav1.ListOptions) (*corev1.PodList, error) {
+	var pods []corev1.Pod
+	for i := int32(0); i < *f.deployment.Spec.Replicas; i++ {
+		name := fmt.Sprintf("web-%d-%d", f.generation, i)
+		pods = append(pods, corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, UID: types.UID(name)},
+			Status: corev1.PodStatus{
+				Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+			},
+		})
+	}
+
+	return &corev1.PodList{Items: pods}, nil
+}
+
+func (f *fakeSecretRotation) readEnv(pod corev1.Pod, containerName, envName string) (string, error) {
+	if envName != "PASSWORD" {
+		return "", nil
+	}
+
+	return f.startedWith, nil
+}
+
+// fakeSecrets updates the secret of a fakeSecretRotation.
+type fakeSecrets struct {
+	*fakeSecretRotation
+}
+
+func (f fakeSecrets) Get(namespace, name string, opts metav1.GetOptions) (*corev1.Secret, error) {
+	return f.secret.DeepCopy(), nil
+}
+
+func (f fakeSecrets) Update(secret *corev1.Secret) (*corev1.Secret, error) {
+	f.secret = secret.DeepCopy()
+	if f.checksumTooling {
+		f.deployment.Spec.Template.Annotations[secretChecksumAnnotation] = string(secret.Data["password"])
+		f.roll()
+	}
+
+	return secret, nil
+}
+
+func TestEnvFromSecretRotationRestart(t *testing.T) {
+	fake := newFakeSecretRotation()
+
+	err := envFromSecretRotation(fakeSecrets{fake}, fake, fake, fake.readEnv, "default", fake.deployment, "creds", "password", "new", time.Millisecond, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(fake.secret.Data["password"]))
+	assert.NotEmpty(t, fake.deployment.Spec.Template.Annotations[restartedAtAnnotation])
+}
+
+func TestEnvFromSecretRotationChecksum(t *testing.T) {
+	fake := newFakeSecretRotation()
+	fake.checksumTooling = true
+	fake.deployment.Spec.Template.Annotations = map[string]string{secretChecksumAnnotation: "old"}
+
+	err := envFromSecretRotation(fakeSecrets{fake}, fake, fake, fake.readEnv, "default", fake.deployment, "creds", "password", "new", time.Millisecond, time.Second)
+	require.NoError(t, err)
+	assert.Empty(t, fake.deployment.Spec.Template.Annotations[restartedAtAnnotation], "a deployment with a checksum annotation should not be restarted")
+}
+
+func TestEnvFromSecretRotationPodsNotRestarted(t *testing.T) {
+	fake := newFakeSecretRotation()
+	fake.ignoreRestart = true
+
+	err := envFromSecretRotation(fakeSecrets{fake}, fake, fake, fake.readEnv, "default", fake.deployment, "creds", "password", "new", time.Millisecond, 20*time.Millisecond)
+	assert.EqualError(t, err, "timed out waiting for pods of deployment web to use the rotated secret creds: pod web-0-0 was not recreated")
+}
+
+func TestEnvFromSecretRotationUnusedKey(t *testing.T) {
+	fake := newFakeSecretRotation()
+
+	err := envFromSecretRotation(fakeSecrets{fake}, fake, fake, fake.readEnv, "default", fake.deployment, "creds", "username", "new", time.Millisecond, time.Second)
+	assert.EqualError(t, err, "deployment web does not expose key username of secret creds as env var")
+	assert.Equal(t, "old", string(fake.secret.Data["password"]))
+}
+
+func TestSecretKeyEnv(t *testing.T) {
+	podSpec := corev1.PodSpec{Containers: []corev1.Container{
+		{Name: "app"},
+		{
+			Name:    "sidecar",
+			EnvFrom: []corev1.EnvFromSource{{Prefix: "DB_", SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "creds"}}}},
+		},
+	}}
+
+	containerName, envName, ok := secretKeyEnv(podSpec, "creds", "password")
+	assert.True(t, ok)
+	assert.Equal(t, "sidecar", containerName)
+	assert.Equal(t, "DB_password", envName)
+
+	_, _, ok = secretKeyEnv(podSpec, "other", "password")
+	assert.False(t, ok)
+}
@@ -0,0 +1,192 @@
+package workloads
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// verifyPodSecurityContext verifies that the running pods of the deployment have the wanted pod-level security context
+// and that their containers, by name, have the wanted container-level security context. Only the fields set in want
+// and containerWant are compared, and a container inherits runAsNonRoot, runAsUser and seccompProfile from the pod
+// unless it sets them itself, as the kubelet does.
+func verifyPodSecurityContext(client *rancher.Client, clusterID, namespaceName string, deployment *appv1.Deployment, want *corev1.PodSecurityContext, containerWant map[string]*corev1.SecurityContext) error {
+	return verifyDeploymentPods(client, clusterID, namespaceName, deployment, func(pods []corev1.Pod) error {
+		return checkPodSecurityContext(pods, want, containerWant)
+	})
+}
+
+// checkPodSecurityContext returns an error if a running pod or one of its containers does not have the wanted security
+// context.
+func checkPodSecurityContext(pods []corev1.Pod, want *corev1.PodSecurityContext, containerWant map[string]*corev1.SecurityContext) error {
+	containerNames := make([]string, 0, len(containerWant))
+	for name := range containerWant {
+		containerNames = append(containerNames, name)
+	}
+	sort.Strings(containerNames)
+
+	return checkRunningPods(pods, func(pod corev1.Pod) error {
+		podContext := pod.Spec.SecurityContext
+		if podContext == nil {
+			podContext = &corev1.PodSecurityContext{}
+		}
+
+		if want != nil {
+			if err := checkPodLevelSecurityContext(podContext, want); err != nil {
+				return fmt.Errorf("pod %s: %w", pod.Name, err)
+			}
+		}
+
+		for _, name := range containerNames {
+			container := findContainer(pod.Spec.Containers, name)
+			if container == nil {
+				return fmt.Errorf("pod %s has no container %s", pod.Name, name)
+			}
+
+			if err := checkContainerSecurityContext(effectiveSecurityContext(podContext, container.SecurityContext), containerWant[name]); err != nil {
+				return fmt.Errorf("container %s of pod %s: %w", name, pod.Name, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+func checkPodLevelSecurityContext(got, want *corev1.PodSecurityContext) error {
+	if err := checkOptional("runAsNonRoot", got.RunAsNonRoot, want.RunAsNonRoot); err != nil {
+		return err
+	}
+	if err := checkOptional("runAsUser", got.RunAsUser, want.RunAsUser); err != nil {
+		return err
+	}
+	if err := checkOptional("runAsGroup", got.RunAsGroup, want.RunAsGroup); err != nil {
+		return err
+	}
+	if err := checkOptional("fsGroup", got.FSGroup, want.FSGroup); err != nil {
+		return err
+	}
+
+	return checkSeccompProfile(got.SeccompProfile, want.SeccompProfile)
+}
+
+func checkContainerSecurityContext(got, want *corev1.SecurityContext) error {
+	if want == nil {
+		return nil
+	}
+
+	if err := checkOptional("runAsNonRoot", got.RunAsNonRoot, want.RunAsNonRoot); err != nil {
+		return err
+	}
+	if err := checkOptional("runAsUser", got.RunAsUser, want.RunAsUser); err != nil {
+		return err
+	}
+	if err := checkOptional("privileged", got.Privileged, want.Privileged); err != nil {
+		return err
+	}
+	if err := checkOptional("allowPrivilegeEscalation", got.AllowPrivilegeEscalation, want.AllowPrivilegeEscalation); err != nil {
+		return err
+	}
+	if err := checkOptional("readOnlyRootFilesystem", got.ReadOnlyRootFilesystem, want.ReadOnlyRootFilesystem); err != nil {
+		return err
+	}
+	if err := checkSeccompProfile(got.SeccompProfile, want.SeccompProfile); err != nil {
+		return err
+	}
+
+	if want.Capabilities != nil {
+		gotCapabilities := got.Capabilities
+		if gotCapabilities == nil {
+			gotCapabilities = &corev1.Capabilities{}
+		}
+		if !sameCapabilities(gotCapabilities.Add, want.Capabilities.Add) {
+			return fmt.Errorf("adds capabilities %v, expected %v", gotCapabilities.Add, want.Capabilities.Add)
+		}
+		if !sameCapabilities(gotCapabilities.Drop, want.Capabilities.Drop) {
+			return fmt.Errorf("drops capabilities %v, expected %v", gotCapabilities.Drop, want.Capabilities.Drop)
+		}
+	}
+
+	return nil
+}
+
+// effectiveSecurityContext returns the security context of the container with the fields it inherits from the pod
+// filled in.
+func effectiveSecurityContext(podContext *corev1.PodSecurityContext, containerContext *corev1.SecurityContext) *corev1.SecurityContext {
+	effective := &corev1.SecurityContext{}
+	if containerContext != nil {
+		effective = containerContext.DeepCopy()
+	}
+
+	if effective.RunAsNonRoot == nil {
+		effective.RunAsNonRoot = podContext.RunAsNonRoot
+	}
+	if effective.RunAsUser == nil {
+		effective.RunAsUser = podContext.RunAsUser
+	}
+	if effective.SeccompProfile == nil {
+		effective.SeccompProfile = podContext.SeccompProfile
+	}
+
+	return effective
+}
+
+// checkOptional returns an error if want is set and got is unset or differs from it.
+func checkOptional[T comparable](field string, got, want *T) error {
+	if want == nil || (got != nil && *got == *want) {
+		return nil
+	}
+
+	if got == nil {
+		return fmt.Errorf("%s is not set, expected %v", field, *want)
+	}
+
+	return fmt.Errorf("%s is %v, expected %v", field, *got, *want)
+}
+
+func checkSeccompProfile(got, want *corev1.SeccompProfile) error {
+	if want == nil {
+		return nil
+	}
+	if got == nil {
+		return fmt.Errorf("seccompProfile is not set, expected %s", want.Type)
+	}
+
+	if got.Type != want.Type || (want.LocalhostProfile != nil && (got.LocalhostProfile == nil || *got.LocalhostProfile != *want.LocalhostProfile)) {
+		return fmt.Errorf("seccompProfile is %s, expected %s", formatSeccompProfile(got), formatSeccompProfile(want))
+	}
+
+	return nil
+}
+
+func formatSeccompProfile(profile *corev1.SeccompProfile) string {
+	if profile.LocalhostProfile != nil {
+		return fmt.Sprintf("%s/%s", profile.Type, *profile.LocalhostProfile)
+	}
+
+	return string(profile.Type)
+}
+
+// sameCapabilities returns true if both lists hold the same capabilities, in any order.
+func sameCapabilities(got, want []corev1.Capability) bool {
+	if len(got) != len(want) {
+		return false
+	}
+
+	sorted := func(capabilities []corev1.Capability) []corev1.Capability {
+		sorted := append([]corev1.Capability{}, capabilities...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		return sorted
+	}
+
+	gotSorted, wantSorted := sorted(got), sorted(want)
+	for i := range gotSorted {
+		if gotSorted[i] != wantSorted[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,118 @@
+package workloads
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/pointer"
+)
+
+func newHardenedTestPod(name string) corev1.Pod {
+	return newRunningTestPod(name, corev1.PodSpec{
+		SecurityContext: &corev1.PodSecurityContext{
+			RunAsNonRoot:   pointer.Bool(true),
+			RunAsUser:      pointer.Int64(1000),
+			SeccompProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+		},
+		Containers: []corev1.Container{{
+			Name: "app",
+			SecurityContext: &corev1.SecurityContext{
+				AllowPrivilegeEscalation: pointer.Bool(false),
+				Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}, Add: []corev1.Capability{"NET_BIND_SERVICE"}},
+			},
+		}},
+	})
+}
+
+func TestCheckPodSecurityContext(t *testing.T) {
+	wantPod := &corev1.PodSecurityContext{
+		RunAsNonRoot:   pointer.Bool(true),
+		SeccompProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+	}
+	wantContainers := map[string]*corev1.SecurityContext{
+		"app": {
+			RunAsNonRoot:             pointer.Bool(true),
+			AllowPrivilegeEscalation: pointer.Bool(false),
+			Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}, Add: []corev1.Capability{"NET_BIND_SERVICE"}},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		pod     func() corev1.Pod
+		wantErr string
+	}{
+		{
+			name: "hardened",
+			pod:  func() corev1.Pod { return newHardenedTestPod("web-a") },
+		},
+		{
+			name: "mismatched capability drop",
+			pod: func() corev1.Pod {
+				pod := newHardenedTestPod("web-a")
+				pod.Spec.Containers[0].SecurityContext.Capabilities.Drop = []corev1.Capability{"NET_RAW"}
+				return pod
+			},
+			wantErr: "container app of pod web-a: drops capabilities [NET_RAW], expected [ALL]",
+		},
+		{
+			name: "container overrides runAsNonRoot",
+			pod: func() corev1.Pod {
+				pod := newHardenedTestPod("web-a")
+				pod.Spec.Containers[0].SecurityContext.RunAsNonRoot = pointer.Bool(false)
+				return pod
+			},
+			wantErr: "container app of pod web-a: runAsNonRoot is false, expected true",
+		},
+		{
+			name: "unconfined seccomp profile",
+			pod: func() corev1.Pod {
+				pod := newHardenedTestPod("web-a")
+				pod.Spec.SecurityContext.SeccompProfile = &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeUnconfined}
+				return pod
+			},
+			wantErr: "pod web-a: seccompProfile is Unconfined, expected RuntimeDefault",
+		},
+		{
+			name: "no pod security context",
+			pod: func() corev1.Pod {
+				pod := newHardenedTestPod("web-a")
+				pod.Spec.SecurityContext = nil
+				return pod
+			},
+			wantErr: "pod web-a: runAsNonRoot is not set, expected true",
+		},
+		{
+			name: "missing container",
+			pod: func() corev1.Pod {
+				pod := newHardenedTestPod("web-a")
+				pod.Spec.Containers[0].Name = "other"
+				return pod
+			},
+			wantErr: "pod web-a has no container app",
+		},
+		{
+			name:    "no running pods",
+			pod:     func() corev1.Pod { return newTestPod("web-a", "node-1") },
+			wantErr: "no running pods found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkPodSecurityContext([]corev1.Pod{tt.pod()}, wantPod, wantContainers)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestSameCapabilities(t *testing.T) {
+	assert.True(t, sameCapabilities([]corev1.Capability{"NET_RAW", "ALL"}, []corev1.Capability{"ALL", "NET_RAW"}))
+	assert.True(t, sameCapabilities(nil, []corev1.Capability{}))
+	assert.False(t, sameCapabilities([]corev1.Capability{"ALL"}, []corev1.Capability{"ALL", "NET_RAW"}))
+}
@@ -0,0 +1,48 @@
+package workloads
+
+import (
+	"fmt"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const defaultServiceAccount = "default"
+
+// verifyPodServiceAccount verifies that the running pods of the deployment use the wanted service account. An empty
+// wantSA stands for the default service account of the namespace.
+func verifyPodServiceAccount(client *rancher.Client, clusterID, namespaceName string, deployment *appv1.Deployment, wantSA string) error {
+	return verifyDeploymentPods(client, clusterID, namespaceName, deployment, func(pods []corev1.Pod) error {
+		return checkRunningPods(pods, podServiceAccountCheck(wantSA))
+	})
+}
+
+// podServiceAccountCheck returns a check that fails for a pod that does not run as the wanted service account. An
+// empty wantSA stands for the default service account.
+func podServiceAccountCheck(wantSA string) func(corev1.Pod) error {
+	if wantSA == "" {
+		wantSA = defaultServiceAccount
+	}
+
+	return func(pod corev1.Pod) error {
+		if sa := podServiceAccount(pod); sa != wantSA {
+			return fmt.Errorf("pod %s runs as service account %s, expected %s", pod.Name, sa, wantSA)
+		}
+
+		return nil
+	}
+}
+
+// podServiceAccount returns the service account the pod runs as. Pods that do not name a service account run as the
+// default service account of their namespace.
+func podServiceAccount(pod corev1.Pod) string {
+	if pod.Spec.ServiceAccountName != "" {
+		return pod.Spec.ServiceAccountName
+	}
+	if pod.Spec.DeprecatedServiceAccount != "" {
+		return pod.Spec.DeprecatedServiceAccount
+	}
+
+	return defaultServiceAccount
+}
@@ -0,0 +1,48 @@
+package workloads
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestPodServiceAccountCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		pod     corev1.Pod
+		wantSA  string
+		wantErr string
+	}{
+		{
+			name:   "matching service account",
+			pod:    newRunningTestPod("pod-1", corev1.PodSpec{ServiceAccountName: "app"}),
+			wantSA: "app",
+		},
+		{
+			name: "implicit default service account",
+			pod:  newRunningTestPod("pod-1", corev1.PodSpec{}),
+		},
+		{
+			name: "explicit default service account",
+			pod:  newRunningTestPod("pod-1", corev1.PodSpec{ServiceAccountName: "default"}),
+		},
+		{
+			name:    "mismatched service account",
+			pod:     newRunningTestPod("pod-1", corev1.PodSpec{}),
+			wantSA:  "app",
+			wantErr: "pod pod-1 runs as service account default, expected app",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := podServiceAccountCheck(tt.wantSA)(tt.pod)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package workloads
+
+import (
+	"fmt"
+
+	dashboardsettings "github.com/rancher/rancher/pkg/controllers/dashboardapi/settings"
+	"github.com/rancher/shepherd/clients/rancher"
+	appv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// verifySettingReflectedInWorkload verifies that the effective value of the setting is the value extract reads from
+// the deployment, e.g. from an env var or an argument of its containers. Settings are global, so the setting is read
+// from the local cluster while the deployment is read from the cluster it runs in.
+func verifySettingReflectedInWorkload(client *rancher.Client, clusterID, namespaceName string, settingName string, deployment *appv1.Deployment, extract func(*appv1.Deployment) string) error {
+	wranglerContext, err := getWranglerContext(client, clusterID)
+	if err != nil {
+		return err
+	}
+
+	return settingReflectedInWorkload(client.WranglerContext.Mgmt.Setting(), wranglerContext.Apps.Deployment(), namespaceName, settingName, deployment, extract)
+}
+
+func settingReflectedInWorkload(settings settingClient, deployments deploymentClient, namespaceName, settingName string, deployment *appv1.Deployment, extract func(*appv1.Deployment) string) error {
+	setting, err := settings.Get(settingName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	latestDeployment, err := deployments.Get(namespaceName, deployment.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	want := dashboardsettings.EffectiveValue(setting)
+	if got := extract(latestDeployment); got != want {
+		return fmt.Errorf("deployment %s uses %q, expected the effective value %q of setting %s", deployment.Name, got, want, settingName)
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package workloads
+
+import (
+	"testing"
+
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/stretchr/testify/assert"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSettingReflectedInWorkload(t *testing.T) {
+	const settingName = "agent-tls-mode"
+
+	extractEnv := func(deployment *appv1.Deployment) string {
+		for _, env := range deployment.Spec.Template.Spec.Containers[0].Env {
+			if env.Name == "STRICT_VERIFY" {
+				return env.Value
+			}
+		}
+		return ""
+	}
+
+	tests := []struct {
+		name     string
+		setting  v3.Setting
+		envValue string
+		wantErr  string
+	}{
+		{
+			name:     "value matches",
+			setting:  v3.Setting{Default: "system-store", Value: "strict"},
+			envValue: "strict",
+		},
+		{
+			name:     "default matches",
+			setting:  v3.Setting{Default: "system-store"},
+			envValue: "system-store",
+		},
+		{
+			name:     "value mismatches",
+			setting:  v3.Setting{Default: "system-store", Value: "strict"},
+			envValue: "system-store",
+			wantErr:  `deployment agent uses "system-store", expected the effective value "strict" of setting agent-tls-mode`,
+		},
+		{
+			name:    "missing in deployment",
+			setting: v3.Setting{Default: "system-store"},
+			wantErr: `deployment agent uses "", expected the effective value "system-store" of setting agent-tls-mode`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setting.ObjectMeta = metav1.ObjectMeta{Name: settingName}
+			settings := fakeSettings{settingName: tt.setting}
+
+			deployment := newTestDeploymentWithImage("agent", nginxImageName)
+			if tt.envValue != "" {
+				deployment.Spec.Template.Spec.Containers[0].Env = []corev1.EnvVar{{Name: "STRICT_VERIFY", Value: tt.envValue}}
+			}
+			err := settingReflectedInWorkload(settings, newFakeDeployments(deployment), "default", settingName, deployment, extractEnv)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestSettingReflectedInWorkloadMissingSetting(t *testing.T) {
+	deployment := newTestDeploymentWithImage("agent", nginxImageName)
+
+	err := settingReflectedInWorkload(fakeSettings{}, newFakeDeployments(deployment), "default", "missing", deployment, func(*appv1.Deployment) string { return "" })
+	assert.Error(t, err)
+}
@@ -0,0 +1,102 @@
+package workloads
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	appv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// FieldDiff is a difference of a single field between two deployment specs. Path is the dotted JSON path of the
+// field below the spec, e.g. "template.spec.containers[0].image". Before or After is nil if the field is unset.
+type FieldDiff struct {
+	Path   string
+	Before interface{}
+	After  interface{}
+}
+
+// SnapshotDeployment returns a copy of the deployment as currently stored in the cluster.
+func SnapshotDeployment(client *rancher.Client, clusterID, namespaceName, name string) (*appv1.Deployment, error) {
+	wranglerContext, err := getWranglerContext(client, clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	deployment, err := wranglerContext.Apps.Deployment().Get(namespaceName, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	return deployment.DeepCopy(), nil
+}
+
+// DiffDeployments returns the field level differences between the specs of the two deployments, sorted by path.
+func DiffDeployments(before, after *appv1.Deployment) []FieldDiff {
+	beforeSpec, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&before.Spec)
+	if err != nil {
+		return []FieldDiff{{Path: "", Before: before.Spec, After: after.Spec}}
+	}
+
+	afterSpec, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&after.Spec)
+	if err != nil {
+		return []FieldDiff{{Path: "", Before: before.Spec, After: after.Spec}}
+	}
+
+	diffs := diffValues("", beforeSpec, afterSpec)
+	sort.Slice(diffs, func(i, j int) bool {
+		return diffs[i].Path < diffs[j].Path
+	})
+
+	return diffs
+}
+
+// diffValues recursively compares two values of an unstructured object.
+func diffValues(path string, before, after interface{}) []FieldDiff {
+	beforeMap, beforeIsMap := before.(map[string]interface{})
+	afterMap, afterIsMap := after.(map[string]interface{})
+	if beforeIsMap && afterIsMap {
+		var diffs []FieldDiff
+		keys := map[string]bool{}
+		for key := range beforeMap {
+			keys[key] = true
+		}
+		for key := range afterMap {
+			keys[key] = true
+		}
+		for key := range keys {
+			fieldPath := key
+			if path != "" {
+				fieldPath = path + "." + key
+			}
+			diffs = append(diffs, diffValues(fieldPath, beforeMap[key], afterMap[key])...)
+		}
+		return diffs
+	}
+
+	beforeList, beforeIsList := before.([]interface{})
+	afterList, afterIsList := after.([]interface{})
+	if beforeIsList && afterIsList {
+		var diffs []FieldDiff
+		for i := 0; i < max(len(beforeList), len(afterList)); i++ {
+			var beforeItem, afterItem interface{}
+			if i < len(beforeList) {
+				beforeItem = beforeList[i]
+			}
+			if i < len(afterList) {
+				afterItem = afterList[i]
+			}
+			diffs = append(diffs, diffValues(fmt.Sprintf("%s[%d]", path, i), beforeItem, afterItem)...)
+		}
+		return diffs
+	}
+
+	if reflect.DeepEqual(before, after) {
+		return nil
+	}
+
+	return []FieldDiff{{Path: path, Before: before, After: after}}
+}
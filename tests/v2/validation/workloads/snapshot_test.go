@@ -0,0 +1,60 @@
+package workloads
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/pointer"
+)
+
+func TestDiffDeployments(t *testing.T) {
+	before := newTestDeploymentWithImage("web", nginxImageName)
+	before.Spec.Replicas = pointer.Int32(2)
+	before.Spec.Template.Spec.Containers[0].Env = []corev1.EnvVar{{Name: "MODE", Value: "debug"}}
+
+	tests := []struct {
+		name   string
+		mutate func(after *corev1.PodSpec, replicas **int32)
+		want   []FieldDiff
+	}{
+		{
+			name:   "no changes",
+			mutate: func(after *corev1.PodSpec, replicas **int32) {},
+		},
+		{
+			name: "image change",
+			mutate: func(after *corev1.PodSpec, replicas **int32) {
+				after.Containers[0].Image = redisImageName
+			},
+			want: []FieldDiff{{Path: "template.spec.containers[0].image", Before: nginxImageName, After: redisImageName}},
+		},
+		{
+			name: "replica change",
+			mutate: func(after *corev1.PodSpec, replicas **int32) {
+				*replicas = pointer.Int32(3)
+			},
+			want: []FieldDiff{{Path: "replicas", Before: int64(2), After: int64(3)}},
+		},
+		{
+			name: "env change",
+			mutate: func(after *corev1.PodSpec, replicas **int32) {
+				after.Containers[0].Env[0].Value = "release"
+				after.Containers[0].Env = append(after.Containers[0].Env, corev1.EnvVar{Name: "PORT", Value: "80"})
+			},
+			want: []FieldDiff{
+				{Path: "template.spec.containers[0].env[0].value", Before: "debug", After: "release"},
+				{Path: "template.spec.containers[0].env[1]", After: map[string]interface{}{"name": "PORT", "value": "80"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			after := before.DeepCopy()
+			tt.mutate(&after.Spec.Template.Spec, &after.Spec.Replicas)
+
+			assert.Equal(t, tt.want, DiffDeployments(before, after))
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package workloads
+
+import (
+	"fmt"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// verifyTopologySpread verifies that the deployment's pods are distributed across the topology domains of the
+// topology key such that the difference between the most and least populated domain does not exceed maxSkew.
+func verifyTopologySpread(client *rancher.Client, clusterID, namespaceName string, deployment *appv1.Deployment, topologyKey string, maxSkew int32) error {
+	wranglerContext, err := getWranglerContext(client, clusterID)
+	if err != nil {
+		return err
+	}
+
+	deploymentPods, err := listDeploymentPods(wranglerContext, namespaceName, deployment)
+	if err != nil {
+		return err
+	}
+
+	nodeList, err := wranglerContext.Core.Node().List(metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	return checkTopologySpread(deploymentPods, nodeList.Items, topologyKey, maxSkew)
+}
+
+// checkTopologySpread counts the scheduled pods per topology domain, including the domains without any pods, and
+// returns an error if the skew exceeds maxSkew.
+func checkTopologySpread(pods []corev1.Pod, nodes []corev1.Node, topologyKey string, maxSkew int32) error {
+	domainByNode := map[string]string{}
+	podsByDomain := map[string]int32{}
+	for _, node := range nodes {
+		domain, ok := node.Labels[topologyKey]
+		if !ok {
+			continue
+		}
+		domainByNode[node.Name] = domain
+		podsByDomain[domain] = 0
+	}
+
+	if len(podsByDomain) == 0 {
+		return fmt.Errorf("no nodes carry the topology key %s", topologyKey)
+	}
+
+	for _, pod := range pods {
+		domain, ok := domainByNode[pod.Spec.NodeName]
+		if !ok {
+			return fmt.Errorf("pod %s is scheduled to node %q which has no topology key %s", pod.Name, pod.Spec.NodeName, topologyKey)
+		}
+		podsByDomain[domain]++
+	}
+
+	var maxDomain, minDomain string
+	for domain, count := range podsByDomain {
+		if maxDomain == "" || count > podsByDomain[maxDomain] {
+			maxDomain = domain
+		}
+		if minDomain == "" || count < podsByDomain[minDomain] {
+			minDomain = domain
+		}
+	}
+
+	skew := podsByDomain[maxDomain] - podsByDomain[minDomain]
+	if skew > maxSkew {
+		return fmt.Errorf("pods are spread across %s with skew %d exceeding max skew %d: %s=%s has %d pods, %s=%s has %d pods",
+			topologyKey, skew, maxSkew, topologyKey, maxDomain, podsByDomain[maxDomain], topologyKey, minDomain, podsByDomain[minDomain])
+	}
+
+	return nil
+}
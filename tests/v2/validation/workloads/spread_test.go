@@ -0,0 +1,81 @@
+package workloads
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const zoneLabel = "topology.kubernetes.io/zone"
+
+func TestCheckTopologySpread(t *testing.T) {
+	nodes := []corev1.Node{
+		newTestNode("node-1", map[string]string{zoneLabel: "zone-a"}),
+		newTestNode("node-2", map[string]string{zoneLabel: "zone-a"}),
+		newTestNode("node-3", map[string]string{zoneLabel: "zone-b"}),
+		newTestNode("node-4", map[string]string{zoneLabel: "zone-c"}),
+	}
+
+	tests := []struct {
+		name    string
+		pods    []corev1.Pod
+		maxSkew int32
+		wantErr string
+	}{
+		{
+			name: "evenly spread",
+			pods: []corev1.Pod{
+				newTestPod("pod-1", "node-1"),
+				newTestPod("pod-2", "node-3"),
+				newTestPod("pod-3", "node-4"),
+			},
+			maxSkew: 1,
+		},
+		{
+			name: "skew within limit",
+			pods: []corev1.Pod{
+				newTestPod("pod-1", "node-1"),
+				newTestPod("pod-2", "node-2"),
+				newTestPod("pod-3", "node-3"),
+			},
+			maxSkew: 2,
+		},
+		{
+			name: "skew exceeded by an empty zone",
+			pods: []corev1.Pod{
+				newTestPod("pod-1", "node-1"),
+				newTestPod("pod-2", "node-2"),
+				newTestPod("pod-3", "node-3"),
+			},
+			maxSkew: 1,
+			wantErr: "skew 2 exceeding max skew 1",
+		},
+		{
+			name: "all pods in one zone",
+			pods: []corev1.Pod{
+				newTestPod("pod-1", "node-1"),
+				newTestPod("pod-2", "node-2"),
+			},
+			maxSkew: 1,
+			wantErr: "topology.kubernetes.io/zone=zone-a has 2 pods",
+		},
+		{
+			name:    "pod on node without the topology key",
+			pods:    []corev1.Pod{newTestPod("pod-1", "node-5")},
+			maxSkew: 1,
+			wantErr: "has no topology key",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkTopologySpread(tt.pods, nodes, zoneLabel, tt.maxSkew)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package workloads
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	"github.com/rancher/shepherd/extensions/defaults"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kwait "k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+)
+
+// statefulSetClient is the subset of the wrangler StatefulSet client needed to scale a statefulset.
+type statefulSetClient interface {
+	Get(namespace, name string, opts metav1.GetOptions) (*appv1.StatefulSet, error)
+	Update(statefulSet *appv1.StatefulSet) (*appv1.StatefulSet, error)
+}
+
+// verifyStatefulSetPodIdentity scales the statefulset to expectedReplicas and verifies that its pods end up named
+// sequentially from name-0 to name-(expectedReplicas-1) and that, while scaling down, pods are removed starting from
+// the highest ordinal.
+func verifyStatefulSetPodIdentity(client *rancher.Client, clusterID, namespaceName, name string, expectedReplicas int) error {
+	wranglerContext, err := getWranglerContext(client, clusterID)
+	if err != nil {
+		return err
+	}
+
+	return scaleStatefulSetPreservingIdentity(wranglerContext.Apps.StatefulSet(), wranglerContext.Core.Pod(), namespaceName, name, expectedReplicas, rolloutPollInterval, defaults.FiveMinuteTimeout)
+}
+
+func scaleStatefulSetPreservingIdentity(statefulSets statefulSetClient, pods podLister, namespaceName, name string, expectedReplicas int, interval, timeout time.Duration) error {
+	var statefulSet *appv1.StatefulSet
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latestStatefulSet, err := statefulSets.Get(namespaceName, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		replicas := int32(expectedReplicas)
+		latestStatefulSet.Spec.Replicas = &replicas
+		statefulSet, err = statefulSets.Update(latestStatefulSet)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(statefulSet.Spec.Selector)
+	if err != nil {
+		return err
+	}
+
+	var previous, current []int
+	err = kwait.PollUntilContextTimeout(context.TODO(), interval, timeout, true, func(ctx context.Context) (bool, error) {
+		podList, err := pods.List(namespaceName, metav1.ListOptions{LabelSelector: selector.String()})
+		if err != nil {
+			return false, err
+		}
+
+		current, err = podOrdinals(name, podList.Items)
+		if err != nil {
+			return false, err
+		}
+		if err := checkScaleDownOrder(name, previous, current); err != nil {
+			return false, err
+		}
+		previous = current
+
+		return checkStatefulSetOrdinals(name, podList.Items, expectedReplicas) == nil, nil
+	})
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("timed out waiting for statefulset %s to have %d sequentially named pods, observed ordinals %v", name, expectedReplicas, current)
+	}
+
+	return err
+}
+
+// checkStatefulSetOrdinals returns an error unless the pods are exactly the ready pods name-0 to name-(expectedReplicas-1).
+func checkStatefulSetOrdinals(name string, pods []corev1.Pod, expectedReplicas int) error {
+	if len(pods) != expectedReplicas {
+		return fmt.Errorf("statefulset %s has %d pods, expected %d", name, len(pods), expectedReplicas)
+	}
+
+	ordinals, err := podOrdinals(name, pods)
+	if err != nil {
+		return err
+	}
+	for i, ordinal := range ordinals {
+		if ordinal != i {
+			return fmt.Errorf("statefulset %s is missing pod %s-%d", name, name, i)
+		}
+	}
+
+	for _, pod := range pods {
+		if pod.DeletionTimestamp != nil || !isPodReady(pod) {
+			return fmt.Errorf("pod %s is not ready", pod.Name)
+		}
+	}
+
+	return nil
+}
+
+// checkScaleDownOrder returns an error if a pod that was removed between two observations had a lower ordinal than a
+// pod that is still present, i.e. the statefulset did not scale down starting from the highest ordinal.
+func checkScaleDownOrder(name string, previous, current []int) error {
+	if len(current) == 0 {
+		return nil
+	}
+
+	present := map[int]bool{}
+	for _, ordinal := range current {
+		present[ordinal] = true
+	}
+	highest := current[len(current)-1]
+
+	for _, ordinal := range previous {
+		if !present[ordinal] && ordinal < highest {
+			return fmt.Errorf("pod %s-%d was removed before pod %s-%d", name, ordinal, name, highest)
+		}
+	}
+
+	return nil
+}
+
+// podOrdinals returns the sorted ordinals of the statefulset's pods, which are named <name>-<ordinal>.
+func podOrdinals(name string, pods []corev1.Pod) ([]int, error) {
+	ordinals := make([]int, 0, len(pods))
+	for _, pod := range pods {
+		ordinal, err := strconv.Atoi(strings.TrimPrefix(pod.Name, name+"-"))
+		if !strings.HasPrefix(pod.Name, name+"-") || err != nil || ordinal < 0 {
+			return nil, fmt.Errorf("pod %s is not named after statefulset %s", pod.Name, name)
+		}
+		ordinals = append(ordinals, ordinal)
+	}
+	sort.Ints(ordinals)
+
+	return ordinals, nil
+}
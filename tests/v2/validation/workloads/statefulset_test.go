@@ -0,0 +1,135 @@
+package workloads
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+)
+
+// fakeStatefulSetScale simulates a statefulset that removes one pod per listing until it reaches its replicas, either
+// from the highest ordinal like the statefulset controller or from the lowest one.
+type fakeStatefulSetScale struct {
+	statefulSet  *appv1.StatefulSet
+	ordinals     []int
+	lowestFirst  bool
+	observations [][]int
+}
+
+func newFakeStatefulSetScale(name string, replicas int, lowestFirst bool) *fakeStatefulSetScale {
+	statefulSet := &appv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: appv1.StatefulSetSpec{
+			Replicas: pointer.Int32(int32(replicas)),
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "test"}},
+		},
+	}
+
+	fake := &fakeStatefulSetScale{statefulSet: statefulSet, lowestFirst: lowestFirst}
+	for i := 0; i < replicas; i++ {
+		fake.ordinals = append(fake.ordinals, i)
+	}
+
+	return fake
+}
+
+func (f *fakeStatefulSetScale) Get(namespace, name string, opts metav1.GetOptions) (*appv1.StatefulSet, error) {
+	return f.statefulSet.DeepCopy(), nil
+}
+
+func (f *fakeStatefulSetScale) Update(statefulSet *appv1.StatefulSet) (*appv1.StatefulSet, error) {
+	f.statefulSet = statefulSet.DeepCopy()
+	return statefulSet, nil
+}
+
+func (f *fakeStatefulSetScale) List(namespace string, opts metav1.ListOptions) (*corev1.PodList, error) {
+	var pods []corev1.Pod
+	for _, ordinal := range f.ordinals {
+		pod := newTestPod(fmt.Sprintf("%s-%d", f.statefulSet.Name, ordinal), "node-1")
+		pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}
+		pods = append(pods, pod)
+	}
+	f.observations = append(f.observations, append([]int{}, f.ordinals...))
+
+	if len(f.ordinals) > int(*f.statefulSet.Spec.Replicas) {
+		if f.lowestFirst {
+			f.ordinals = f.ordinals[1:]
+		} else {
+			f.ordinals = f.ordinals[:len(f.ordinals)-1]
+		}
+	}
+
+	return &corev1.PodList{Items: pods}, nil
+}
+
+func TestScaleStatefulSetPreservingIdentity(t *testing.T) {
+	fake := newFakeStatefulSetScale("db", 4, false)
+
+	err := scaleStatefulSetPreservingIdentity(fake, fake, "default", "db", 2, 10*time.Millisecond, 5*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, [][]int{{0, 1, 2, 3}, {0, 1, 2}, {0, 1}}, fake.observations)
+	assert.Equal(t, int32(2), *fake.statefulSet.Spec.Replicas)
+}
+
+func TestScaleStatefulSetLowestOrdinalRemoved(t *testing.T) {
+	fake := newFakeStatefulSetScale("db", 4, true)
+
+	err := scaleStatefulSetPreservingIdentity(fake, fake, "default", "db", 2, 10*time.Millisecond, 5*time.Second)
+	assert.EqualError(t, err, "pod db-0 was removed before pod db-3")
+}
+
+func TestCheckStatefulSetOrdinals(t *testing.T) {
+	readyPod := func(name string) corev1.Pod {
+		pod := newTestPod(name, "node-1")
+		pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}
+		return pod
+	}
+
+	tests := []struct {
+		name    string
+		pods    []corev1.Pod
+		wantErr string
+	}{
+		{
+			name: "sequential",
+			pods: []corev1.Pod{readyPod("db-1"), readyPod("db-0"), readyPod("db-2")},
+		},
+		{
+			name:    "gap in ordinals",
+			pods:    []corev1.Pod{readyPod("db-0"), readyPod("db-2"), readyPod("db-3")},
+			wantErr: "statefulset db is missing pod db-1",
+		},
+		{
+			name:    "too few pods",
+			pods:    []corev1.Pod{readyPod("db-0"), readyPod("db-1")},
+			wantErr: "statefulset db has 2 pods, expected 3",
+		},
+		{
+			name:    "foreign pod",
+			pods:    []corev1.Pod{readyPod("db-0"), readyPod("db-1"), readyPod("web-2")},
+			wantErr: "pod web-2 is not named after statefulset db",
+		},
+		{
+			name:    "pod not ready",
+			pods:    []corev1.Pod{readyPod("db-0"), readyPod("db-1"), newTestPod("db-2", "node-1")},
+			wantErr: "pod db-2 is not ready",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkStatefulSetOrdinals("db", tt.pods, 3)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
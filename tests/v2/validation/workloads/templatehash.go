@@ -0,0 +1,78 @@
+package workloads
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// verifyConsistentPodTemplateHash verifies that every pod of the deployment carries the pod-template-hash of the
+// ReplicaSet of the deployment's current revision, which catches stuck rollouts where pods with mixed hashes persist.
+func verifyConsistentPodTemplateHash(client *rancher.Client, clusterID, namespaceName string, deployment *appv1.Deployment) error {
+	wranglerContext, err := getWranglerContext(client, clusterID)
+	if err != nil {
+		return err
+	}
+
+	latestDeployment, err := wranglerContext.Apps.Deployment().Get(namespaceName, deployment.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	replicaSets, err := wranglerContext.Apps.ReplicaSet().List(namespaceName, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	currentHash, err := currentPodTemplateHash(replicaSets.Items, latestDeployment)
+	if err != nil {
+		return err
+	}
+
+	deploymentPods, err := listDeploymentPods(wranglerContext, namespaceName, latestDeployment)
+	if err != nil {
+		return err
+	}
+
+	return checkPodTemplateHashes(deploymentPods, currentHash)
+}
+
+// currentPodTemplateHash returns the pod-template-hash of the ReplicaSet owned by the deployment for its current revision.
+func currentPodTemplateHash(replicaSets []appv1.ReplicaSet, deployment *appv1.Deployment) (string, error) {
+	revision := deployment.Annotations[revisionAnnotation]
+	for _, replicaSet := range replicaSets {
+		if replicaSet.Annotations[revisionAnnotation] == revision && isOwnedByDeployment(replicaSet, deployment.Name) {
+			return replicaSet.Labels[appv1.DefaultDeploymentUniqueLabelKey], nil
+		}
+	}
+
+	return "", fmt.Errorf("replicaset for revision %s of deployment %s not found", revision, deployment.Name)
+}
+
+// checkPodTemplateHashes returns an error listing the pods whose pod-template-hash differs from the current hash.
+func checkPodTemplateHashes(deploymentPods []corev1.Pod, currentHash string) error {
+	staleHashes := map[string][]string{}
+	for _, pod := range deploymentPods {
+		hash := pod.Labels[appv1.DefaultDeploymentUniqueLabelKey]
+		if hash != currentHash {
+			staleHashes[hash] = append(staleHashes[hash], pod.Name)
+		}
+	}
+
+	if len(staleHashes) == 0 {
+		return nil
+	}
+
+	var stale []string
+	for hash, podNames := range staleHashes {
+		stale = append(stale, fmt.Sprintf("%s (%s)", hash, strings.Join(podNames, ",")))
+	}
+	sort.Strings(stale)
+
+	return fmt.Errorf("pods with stale pod-template-hash found, expected %s: %s", currentHash, strings.Join(stale, "; "))
+}
@@ -0,0 +1,53 @@
+package workloads
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newTestPodWithHash(name, hash string) corev1.Pod {
+	return corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{appv1.DefaultDeploymentUniqueLabelKey: hash},
+		},
+	}
+}
+
+func TestCurrentPodTemplateHash(t *testing.T) {
+	oldReplicaSet := newTestReplicaSet("web", "1", nginxImageName)
+	oldReplicaSet.Labels = map[string]string{appv1.DefaultDeploymentUniqueLabelKey: "old"}
+	newReplicaSet := newTestReplicaSet("web", "2", redisImageName)
+	newReplicaSet.Labels = map[string]string{appv1.DefaultDeploymentUniqueLabelKey: "new"}
+
+	deployment := newTestDeploymentWithImage("web", redisImageName)
+	deployment.Annotations = map[string]string{revisionAnnotation: "2"}
+
+	hash, err := currentPodTemplateHash([]appv1.ReplicaSet{oldReplicaSet, newReplicaSet}, deployment)
+	require.NoError(t, err)
+	assert.Equal(t, "new", hash)
+
+	deployment.Annotations[revisionAnnotation] = "3"
+	_, err = currentPodTemplateHash([]appv1.ReplicaSet{oldReplicaSet, newReplicaSet}, deployment)
+	assert.Error(t, err)
+}
+
+func TestCheckPodTemplateHashes(t *testing.T) {
+	err := checkPodTemplateHashes([]corev1.Pod{
+		newTestPodWithHash("pod-1", "new"),
+		newTestPodWithHash("pod-2", "new"),
+	}, "new")
+	assert.NoError(t, err)
+
+	err = checkPodTemplateHashes([]corev1.Pod{
+		newTestPodWithHash("pod-1", "new"),
+		newTestPodWithHash("pod-2", "old"),
+		newTestPodWithHash("pod-3", "old"),
+	}, "new")
+	assert.EqualError(t, err, "pods with stale pod-template-hash found, expected new: old (pod-2,pod-3)")
+}
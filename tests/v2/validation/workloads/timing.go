@@ -0,0 +1,116 @@
+package workloads
+
+import (
+	"context"
+	"time"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	"github.com/rancher/shepherd/extensions/defaults"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kwait "k8s.io/apimachinery/pkg/util/wait"
+)
+
+const rolloutPollInterval = time.Second
+
+// deploymentClient is the subset of the wrangler Deployment client needed to read and update deployments.
+type deploymentClient interface {
+	Get(namespace, name string, opts metav1.GetOptions) (*appv1.Deployment, error)
+	Update(deployment *appv1.Deployment) (*appv1.Deployment, error)
+}
+
+// podLister is the subset of the wrangler Pod client needed to list pods.
+type podLister interface {
+	List(namespace string, opts metav1.ListOptions) (*corev1.PodList, error)
+}
+
+// TimedDeploymentUpgrade updates the image of every container of the deployment to newImage and returns how long it
+// took until the rollout completed, i.e. all pods run the new image and are ready and no old pods are left.
+func TimedDeploymentUpgrade(client *rancher.Client, clusterID, namespaceName string, deployment *appv1.Deployment, newImage string) (time.Duration, error) {
+	wranglerContext, err := getWranglerContext(client, clusterID)
+	if err != nil {
+		return 0, err
+	}
+
+	return timedDeploymentUpgrade(wranglerContext.Apps.Deployment(), wranglerContext.Core.Pod(), namespaceName, deployment, newImage, rolloutPollInterval, defaults.TenMinuteTimeout)
+}
+
+func timedDeploymentUpgrade(deployments deploymentClient, pods podLister, namespaceName string, deployment *appv1.Deployment, newImage string, interval, timeout time.Duration) (time.Duration, error) {
+	latestDeployment, err := deployments.Get(namespaceName, deployment.Name, metav1.GetOptions{})
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range latestDeployment.Spec.Template.Spec.Containers {
+		latestDeployment.Spec.Template.Spec.Containers[i].Image = newImage
+	}
+
+	start := time.Now()
+	updatedDeployment, err := deployments.Update(latestDeployment)
+	if err != nil {
+		return 0, err
+	}
+
+	err = waitForRolloutWithImage(deployments, pods, namespaceName, updatedDeployment, newImage, interval, timeout)
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Since(start), nil
+}
+
+// waitForRolloutWithImage waits until the rollout of the deployment completed and all its pods are ready and run image.
+func waitForRolloutWithImage(deployments deploymentClient, pods podLister, namespaceName string, deployment *appv1.Deployment, image string, interval, timeout time.Duration) error {
+	selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		return err
+	}
+
+	return kwait.PollUntilContextTimeout(context.TODO(), interval, timeout, true, func(ctx context.Context) (bool, error) {
+		current, err := deployments.Get(namespaceName, deployment.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		if !isRolloutComplete(current) {
+			return false, nil
+		}
+
+		podList, err := pods.List(namespaceName, metav1.ListOptions{LabelSelector: selector.String()})
+		if err != nil {
+			return false, err
+		}
+
+		return allPodsReadyWithImage(podList.Items, image), nil
+	})
+}
+
+// allPodsReadyWithImage returns true if there is at least one pod and every pod is ready and only runs the image.
+func allPodsReadyWithImage(pods []corev1.Pod, image string) bool {
+	if len(pods) == 0 {
+		return false
+	}
+
+	for _, pod := range pods {
+		if pod.DeletionTimestamp != nil || !isPodReady(pod) {
+			return false
+		}
+		for _, container := range pod.Spec.Containers {
+			if container.Image != image {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func isPodReady(pod corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,82 @@
+package workloads
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+)
+
+// fakeRollout simulates a deployment whose rollout completes a fixed delay after it was updated.
+type fakeRollout struct {
+	*fakeDeployments
+	updatedAt time.Time
+	delay     time.Duration
+}
+
+func newFakeRollout(deployment *appv1.Deployment, delay time.Duration) *fakeRollout {
+	f := &fakeRollout{fakeDeployments: newFakeDeployments(deployment), delay: delay}
+	f.onGet = func(deployment *appv1.Deployment) {
+		if f.converged() {
+			deployment.Status = newRolledOutStatus(deployment)
+		}
+	}
+	f.onUpdate = func(*appv1.Deployment) {
+		f.updatedAt = time.Now()
+	}
+
+	return f
+}
+
+func (f *fakeRollout) converged() bool {
+	return !f.updatedAt.IsZero() && time.Since(f.updatedAt) >= f.delay
+}
+
+func (f *fakeRollout) List(namespace string, opts metav1.ListOptions) (*corev1.PodList, error) {
+	image := nginxImageName
+	if f.converged() {
+		image = redisImageName
+	}
+
+	var pods []corev1.Pod
+	for i := int32(0); i < *f.deployment.Spec.Replicas; i++ {
+		pods = append(pods, corev1.Pod{
+			Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: image}}},
+			Status: corev1.PodStatus{
+				Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+			},
+		})
+	}
+
+	return &corev1.PodList{Items: pods}, nil
+}
+
+func TestTimedDeploymentUpgrade(t *testing.T) {
+	deployment := newTestDeploymentWithImage("web", nginxImageName)
+	deployment.Spec.Replicas = pointer.Int32(2)
+	deployment.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}
+
+	const delay = 200 * time.Millisecond
+	rollout := newFakeRollout(deployment, delay)
+
+	duration, err := timedDeploymentUpgrade(rollout, rollout, "default", deployment, redisImageName, 10*time.Millisecond, 5*time.Second)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, duration, delay)
+	assert.Less(t, duration, delay+time.Second)
+	assert.Equal(t, redisImageName, rollout.deployment.Spec.Template.Spec.Containers[0].Image)
+}
+
+func TestTimedDeploymentUpgradeTimeout(t *testing.T) {
+	deployment := newTestDeploymentWithImage("web", nginxImageName)
+	deployment.Spec.Replicas = pointer.Int32(2)
+
+	rollout := newFakeRollout(deployment, time.Hour)
+
+	_, err := timedDeploymentUpgrade(rollout, rollout, "default", deployment, redisImageName, 10*time.Millisecond, 100*time.Millisecond)
+	assert.Error(t, err)
+}
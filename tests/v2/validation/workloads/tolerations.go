@@ -0,0 +1,105 @@
+package workloads
+
+import (
+	"fmt"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// verifyPodTolerations verifies that the pods of the deployment have the wanted tolerations, were scheduled, and
+// tolerate the NoExecute taints of the nodes they run on.
+func verifyPodTolerations(client *rancher.Client, clusterID, namespaceName string, deployment *appv1.Deployment, wantTolerations []corev1.Toleration) error {
+	wranglerContext, err := getWranglerContext(client, clusterID)
+	if err != nil {
+		return err
+	}
+
+	pods, err := listDeploymentPods(wranglerContext, namespaceName, deployment)
+	if err != nil {
+		return err
+	}
+
+	nodeList, err := wranglerContext.Core.Node().List(metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	return checkPodTolerations(pods, nodeList.Items, wantTolerations)
+}
+
+// checkPodTolerations returns an error if a pod is missing one of the wanted tolerations, is still pending, or runs on
+// a node with a NoExecute taint it does not tolerate. NoSchedule taints are not checked, as a pod that was running
+// before such a taint was added keeps running on the node.
+func checkPodTolerations(pods []corev1.Pod, nodes []corev1.Node, wantTolerations []corev1.Toleration) error {
+	if len(pods) == 0 {
+		return fmt.Errorf("no pods found")
+	}
+
+	nodesByName := map[string]corev1.Node{}
+	for _, node := range nodes {
+		nodesByName[node.Name] = node
+	}
+
+	for _, pod := range pods {
+		for i := range wantTolerations {
+			if !hasToleration(pod.Spec.Tolerations, &wantTolerations[i]) {
+				return fmt.Errorf("pod %s is missing toleration %s", pod.Name, wantTolerations[i].String())
+			}
+		}
+
+		if pod.Spec.NodeName == "" {
+			return fmt.Errorf("pod %s is %s and not scheduled: %s", pod.Name, pod.Status.Phase, schedulingMessage(pod))
+		}
+
+		node, ok := nodesByName[pod.Spec.NodeName]
+		if !ok {
+			return fmt.Errorf("pod %s is scheduled to unknown node %s", pod.Name, pod.Spec.NodeName)
+		}
+
+		for i := range node.Spec.Taints {
+			taint := &node.Spec.Taints[i]
+			if taint.Effect != corev1.TaintEffectNoExecute {
+				continue
+			}
+			if !toleratesTaint(pod.Spec.Tolerations, taint) {
+				return fmt.Errorf("pod %s runs on node %s without tolerating taint %s", pod.Name, node.Name, taint.ToString())
+			}
+		}
+	}
+
+	return nil
+}
+
+func hasToleration(tolerations []corev1.Toleration, want *corev1.Toleration) bool {
+	for i := range tolerations {
+		if tolerations[i].MatchToleration(want) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func toleratesTaint(tolerations []corev1.Toleration, taint *corev1.Taint) bool {
+	for i := range tolerations {
+		if tolerations[i].ToleratesTaint(taint) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// schedulingMessage returns why the scheduler could not schedule the pod, as reported by its PodScheduled condition.
+func schedulingMessage(pod corev1.Pod) string {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodScheduled && condition.Status == corev1.ConditionFalse {
+			return condition.Message
+		}
+	}
+
+	return "no scheduling condition reported"
+}
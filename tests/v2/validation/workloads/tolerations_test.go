@@ -0,0 +1,80 @@
+package workloads
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestCheckPodTolerations(t *testing.T) {
+	gpuTaint := corev1.Taint{Key: "gpu", Value: "true", Effect: corev1.TaintEffectNoSchedule}
+	gpuToleration := corev1.Toleration{Key: "gpu", Operator: corev1.TolerationOpEqual, Value: "true", Effect: corev1.TaintEffectNoSchedule}
+
+	evictingTaint := corev1.Taint{Key: "maintenance", Value: "true", Effect: corev1.TaintEffectNoExecute}
+
+	taintedNode := newTestNode("node-1", nil)
+	taintedNode.Spec.Taints = []corev1.Taint{gpuTaint}
+	evictingNode := newTestNode("node-3", nil)
+	evictingNode.Spec.Taints = []corev1.Taint{evictingTaint}
+	nodes := []corev1.Node{taintedNode, newTestNode("node-2", nil), evictingNode}
+
+	tolerating := func(name, nodeName string) corev1.Pod {
+		pod := newTestPod(name, nodeName)
+		pod.Spec.Tolerations = []corev1.Toleration{gpuToleration}
+		return pod
+	}
+
+	pending := newTestPod("pod-pending", "")
+	pending.Status.Phase = corev1.PodPending
+	pending.Status.Conditions = []corev1.PodCondition{{
+		Type:    corev1.PodScheduled,
+		Status:  corev1.ConditionFalse,
+		Reason:  corev1.PodReasonUnschedulable,
+		Message: "0/1 nodes are available: 1 node(s) had untolerated taint {gpu: true}.",
+	}}
+
+	tests := []struct {
+		name            string
+		pods            []corev1.Pod
+		wantTolerations []corev1.Toleration
+		wantErr         string
+	}{
+		{
+			name:            "pods tolerate the taint and are scheduled",
+			pods:            []corev1.Pod{tolerating("pod-1", "node-1"), tolerating("pod-2", "node-2")},
+			wantTolerations: []corev1.Toleration{gpuToleration},
+		},
+		{
+			name:            "missing toleration",
+			pods:            []corev1.Pod{tolerating("pod-1", "node-1"), newTestPod("pod-2", "node-2")},
+			wantTolerations: []corev1.Toleration{gpuToleration},
+			wantErr:         "pod pod-2 is missing toleration",
+		},
+		{
+			name:    "pods stay pending",
+			pods:    []corev1.Pod{pending},
+			wantErr: "pod pod-pending is Pending and not scheduled: 0/1 nodes are available: 1 node(s) had untolerated taint {gpu: true}.",
+		},
+		{
+			name: "pod running before a NoSchedule taint was added",
+			pods: []corev1.Pod{newTestPod("pod-1", "node-1")},
+		},
+		{
+			name:    "pod on node with NoExecute taint without toleration",
+			pods:    []corev1.Pod{newTestPod("pod-1", "node-3")},
+			wantErr: "pod pod-1 runs on node node-3 without tolerating taint maintenance=true:NoExecute",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkPodTolerations(tt.pods, nodes, tt.wantTolerations)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package workloads
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/rancher/shepherd/clients/rancher"
+	"github.com/rancher/shepherd/extensions/defaults"
+	"github.com/stretchr/testify/require"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kwait "k8s.io/apimachinery/pkg/util/wait"
+)
+
+// validateUpgradeExpectFailure upgrades the deployment to the bad image and validates that the rollout gets stuck with
+// a container of a new pod waiting for the wanted reason, e.g. ErrImagePull or ImagePullBackOff. Unlike
+// verifyAutoRollbackOnFailure, it returns as soon as the reason is reported instead of waiting for the progress
+// deadline. The deployment is left on the bad image.
+func validateUpgradeExpectFailure(t *testing.T, client *rancher.Client, clusterID, namespaceName string, deployment *appv1.Deployment, badImage, wantReason string) {
+	wranglerContext, err := getWranglerContext(client, clusterID)
+	require.NoError(t, err)
+
+	err = upgradeExpectFailure(wranglerContext.Apps.Deployment(), wranglerContext.Core.Pod(), namespaceName, deployment, badImage, wantReason, rolloutPollInterval, defaults.FiveMinuteTimeout)
+	require.NoError(t, err)
+}
+
+func upgradeExpectFailure(deployments deploymentClient, pods podLister, namespaceName string, deployment *appv1.Deployment, badImage, wantReason string, interval, timeout time.Duration) error {
+	if err := setDeploymentImage(deployments, namespaceName, deployment.Name, badImage); err != nil {
+		return err
+	}
+
+	var waitErr error
+	err := kwait.PollUntilContextTimeout(context.TODO(), interval, timeout, true, func(ctx context.Context) (bool, error) {
+		current, err := deployments.Get(namespaceName, deployment.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+
+		if isRolloutComplete(current) {
+			return false, fmt.Errorf("rollout of deployment %s to bad image %s completed", deployment.Name, badImage)
+		}
+
+		selector, err := metav1.LabelSelectorAsSelector(current.Spec.Selector)
+		if err != nil {
+			return false, err
+		}
+
+		podList, err := pods.List(namespaceName, metav1.ListOptions{LabelSelector: selector.String()})
+		if err != nil {
+			return false, err
+		}
+
+		var found bool
+		found, waitErr = checkWaitingReason(podList.Items, badImage, wantReason)
+		if waitErr != nil && found {
+			return false, waitErr
+		}
+
+		return found, nil
+	})
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("timed out waiting for a pod of deployment %s with image %s to report %s: %w", deployment.Name, badImage, wantReason, waitErr)
+	}
+
+	return err
+}
+
+// checkWaitingReason returns true if a container of a pod running the bad image is waiting for the wanted reason. It
+// returns true with an error if such a pod is ready, as the image was expected to fail. Otherwise, the error reports
+// the waiting reasons observed so far.
+func checkWaitingReason(pods []corev1.Pod, badImage, wantReason string) (bool, error) {
+	observed := map[string]bool{}
+	for _, pod := range pods {
+		if pod.DeletionTimestamp != nil || !podRunsImage(pod, badImage) {
+			continue
+		}
+
+		if isPodReady(pod) {
+			return true, fmt.Errorf("pod %s with bad image %s is ready", pod.Name, badImage)
+		}
+
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.State.Waiting == nil || status.State.Waiting.Reason == "" {
+				continue
+			}
+
+			if status.State.Waiting.Reason == wantReason {
+				return true, nil
+			}
+			observed[status.State.Waiting.Reason] = true
+		}
+	}
+
+	if len(observed) == 0 {
+		return false, fmt.Errorf("no container with image %s is waiting", badImage)
+	}
+
+	reasons := make([]string, 0, len(observed))
+	for reason := range observed {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+
+	return false, fmt.Errorf("containers with image %s are waiting for %v", badImage, reasons)
+}
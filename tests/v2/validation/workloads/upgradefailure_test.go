@@ -0,0 +1,151 @@
+package workloads
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+)
+
+const badTestImage = unreachableRegistry + "/nginx:latest"
+
+func newWaitingTestPod(name, image, reason string) corev1.Pod {
+	pod := newTestPod(name, "node-1")
+	pod.Spec.Containers = []corev1.Container{{Name: "app", Image: image}}
+	pod.Status.Phase = corev1.PodPending
+	pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+		Name:  "app",
+		State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: reason}},
+	}}
+	return pod
+}
+
+// fakeFailingUpgrade simulates a deployment whose new pods report the waiting reasons in order, one per listing of the
+// pods, repeating the last one once exhausted. With pullable set, the new image is pulled and the rollout completes.
+type fakeFailingUpgrade struct {
+	*fakeDeployments
+	reasons  []string
+	listings int
+	pullable bool
+}
+
+func newFakeFailingUpgrade(reasons ...string) *fakeFailingUpgrade {
+	deployment := newTestDeploymentWithImage("web", nginxImageName)
+	deployment.Spec.Replicas = pointer.Int32(2)
+	deployment.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "test"}}
+
+	f := &fakeFailingUpgrade{fakeDeployments: newFakeDeployments(deployment), reasons: reasons}
+	f.onGet = func(deployment *appv1.Deployment) {
+		deployment.Status = newRolledOutStatus(deployment)
+		if !f.pullable {
+			// The new pod is stuck next to the old ones.
+			deployment.Status.Replicas++
+			deployment.Status.UpdatedReplicas = 1
+		}
+	}
+
+	return f
+}
+
+func (f *fakeFailingUpgrade) List(namespace string, opts metav1.ListOptions) (*corev1.PodList, error) {
+	index := f.listings
+	if index >= len(f.reasons) {
+		index = len(f.reasons) - 1
+	}
+	f.listings++
+
+	image := f.deployment.Spec.Template.Spec.Containers[0].Image
+	pods := []corev1.Pod{newWaitingTestPod("web-new", image, f.reasons[index])}
+	for i := 0; i < 2; i++ {
+		pod := newReadyTestPod(fmt.Sprintf("web-old-%d", i), "node-1")
+		pod.Spec.Containers = []corev1.Container{{Name: "app", Image: nginxImageName}}
+		pods = append(pods, pod)
+	}
+
+	return &corev1.PodList{Items: pods}, nil
+}
+
+func TestUpgradeExpectFailure(t *testing.T) {
+	fake := newFakeFailingUpgrade("ContainerCreating", "ErrImagePull", "ImagePullBackOff")
+
+	err := upgradeExpectFailure(fake, fake, "default", fake.deployment, badTestImage, "ImagePullBackOff", time.Millisecond, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, badTestImage, fake.deployment.Spec.Template.Spec.Containers[0].Image)
+	assert.Equal(t, 3, fake.listings, "the validation should return as soon as the reason is reported")
+}
+
+func TestUpgradeExpectFailureUnexpectedReason(t *testing.T) {
+	fake := newFakeFailingUpgrade("InvalidImageName")
+
+	err := upgradeExpectFailure(fake, fake, "default", fake.deployment, badTestImage, "ErrImagePull", time.Millisecond, 20*time.Millisecond)
+	assert.EqualError(t, err, "timed out waiting for a pod of deployment web with image "+badTestImage+" to report ErrImagePull: containers with image "+badTestImage+" are waiting for [InvalidImageName]")
+}
+
+func TestUpgradeExpectFailureRolloutCompletes(t *testing.T) {
+	fake := newFakeFailingUpgrade("ErrImagePull")
+	fake.pullable = true
+
+	err := upgradeExpectFailure(fake, fake, "default", fake.deployment, badTestImage, "ErrImagePull", time.Millisecond, time.Second)
+	assert.EqualError(t, err, "rollout of deployment web to bad image "+badTestImage+" completed")
+}
+
+func TestCheckWaitingReason(t *testing.T) {
+	readyBadPod := newReadyTestPod("web-new", "node-1")
+	readyBadPod.Spec.Containers = []corev1.Container{{Name: "app", Image: badTestImage}}
+
+	terminatingPod := newWaitingTestPod("web-terminating", badTestImage, "ErrImagePull")
+	terminatingPod.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+
+	tests := []struct {
+		name      string
+		pods      []corev1.Pod
+		wantFound bool
+		wantErr   string
+	}{
+		{
+			name:      "wanted reason",
+			pods:      []corev1.Pod{newWaitingTestPod("web-old", nginxImageName, "ContainerCreating"), newWaitingTestPod("web-new", badTestImage, "ErrImagePull")},
+			wantFound: true,
+		},
+		{
+			name:    "other reasons",
+			pods:    []corev1.Pod{newWaitingTestPod("web-a", badTestImage, "ImagePullBackOff"), newWaitingTestPod("web-b", badTestImage, "ContainerCreating")},
+			wantErr: "containers with image " + badTestImage + " are waiting for [ContainerCreating ImagePullBackOff]",
+		},
+		{
+			name:    "wanted reason only on other image",
+			pods:    []corev1.Pod{newWaitingTestPod("web-old", nginxImageName, "ErrImagePull")},
+			wantErr: "no container with image " + badTestImage + " is waiting",
+		},
+		{
+			name:    "terminating pod ignored",
+			pods:    []corev1.Pod{terminatingPod},
+			wantErr: "no container with image " + badTestImage + " is waiting",
+		},
+		{
+			name:      "bad image ready",
+			pods:      []corev1.Pod{readyBadPod},
+			wantFound: true,
+			wantErr:   "pod web-new with bad image " + badTestImage + " is ready",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			found, err := checkWaitingReason(tt.pods, badTestImage, "ErrImagePull")
+			assert.Equal(t, tt.wantFound, found)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
@@ -16,6 +16,7 @@
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/require"
 	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/rancher/shepherd/pkg/wrangler"
@@ -52,20 +53,62 @@ func validateDeploymentUpgrade(t *testing.T, client *rancher.Client, clusterName
 	require.Equal(t, expectedReplicas, countPods)
 }
 
+func validateMultiContainerUpgrade(t *testing.T, client *rancher.Client, clusterName string, namespaceName string, appv1Deployment *appv1.Deployment, containerImages map[string]string, expectedReplicas int) {
+	log.Info("Waiting deployment comes up active")
+	err := charts.WatchAndWaitDeployments(client, clusterName, namespaceName, metav1.ListOptions{
+		FieldSelector:  "metadata.name=" + appv1Deployment.Name,
+		TimeoutSeconds: &defaults.WatchTimeoutSeconds,
+	})
+	require.NoError(t, err)
+
+	log.Info("Waiting for all pods to be running")
+	err = pods.WatchAndWaitPodContainerRunning(client, clusterName, namespaceName, appv1Deployment)
+	require.NoError(t, err)
+
+	wranglerContext, err := getWranglerContext(client, clusterName)
+	require.NoError(t, err)
+
+	deploymentPods, err := listDeploymentPods(wranglerContext, namespaceName, appv1Deployment)
+	require.NoError(t, err)
+
+	log.Infof("Verifying the images of containers %v", containerImages)
+	err = checkContainersRunningImages(deploymentPods, containerImages, expectedReplicas)
+	require.NoError(t, err)
+}
+
 func validateDeploymentScale(t *testing.T, client *rancher.Client, clusterName string, namespaceName string, scaleDeployment *appv1.Deployment, image string, expectedReplicas int) {
+	if expectedReplicas == 0 {
+		log.Info("Waiting for all pods of the deployment to be removed")
+		err := WaitForZeroPods(client, clusterName, namespaceName, scaleDeployment)
+		if err != nil {
+			logDeploymentEvents(client, clusterName, namespaceName, scaleDeployment)
+		}
+		require.NoError(t, err)
+		return
+	}
+
 	log.Info("Waiting deployment comes up active")
 	err := charts.WatchAndWaitDeployments(client, clusterName, namespaceName, metav1.ListOptions{
 		FieldSelector: "metadata.name=" + scaleDeployment.Name,
 	})
+	if err != nil {
+		reportDeploymentFailure(client, clusterName, namespaceName, scaleDeployment)
+	}
 	require.NoError(t, err)
 
 	log.Info("Waiting for all pods to be running")
 	err = pods.WatchAndWaitPodContainerRunning(client, clusterName, namespaceName, scaleDeployment)
+	if err != nil {
+		reportDeploymentFailure(client, clusterName, namespaceName, scaleDeployment)
+	}
 	require.NoError(t, err)
 
 	log.Infof("Counting all pods running by image %s", image)
 	countPods, err := pods.CountPodContainerRunningByImage(client, clusterName, namespaceName, image)
 	require.NoError(t, err)
+	if countPods != expectedReplicas {
+		reportDeploymentFailure(client, clusterName, namespaceName, scaleDeployment)
+	}
 	require.Equal(t, expectedReplicas, countPods)
 }
 
@@ -81,6 +124,22 @@ func rollbackDeployment(client *rancher.Client, clusterID, namespaceName string,
 		return "", err
 	}
 
+	wranglerContext, err := getWranglerContext(client, clusterID)
+	if err != nil {
+		return "", err
+	}
+
+	//Collect the images of the target revision before its ReplicaSet is relabeled by the rollback
+	replicaSets, err := wranglerContext.Apps.ReplicaSet().List(namespaceName, metav1.ListOptions{})
+	if err != nil {
+		return "", err
+	}
+
+	targetImages, err := revisionImages(replicaSets.Items, deploymentName, strconv.Itoa(revision))
+	if err != nil {
+		return "", err
+	}
+
 	//Collect the pod IDs that are expected to be deleted after the rollback
 	expectBeDeletedIds := []string{}
 	for _, podResp := range podsResp.Data {
@@ -120,10 +179,93 @@ func rollbackDeployment(client *rancher.Client, clusterID, namespaceName string,
 		}
 		return true, nil
 	})
+	if err != nil {
+		return "", err
+	}
+
+	rolledBackDeployment, err := wranglerContext.Apps.Deployment().Get(namespaceName, deploymentName, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+
+	err = checkContainerImages(rolledBackDeployment, targetImages)
 
 	return logCmd, err
 }
 
+// checkContainersRunningImages verifies that each named container runs its expected image in exactly expectedReplicas
+// running pods.
+func checkContainersRunningImages(deploymentPods []corev1.Pod, containerImages map[string]string, expectedReplicas int) error {
+	for containerName, image := range containerImages {
+		count := 0
+		for _, pod := range deploymentPods {
+			for _, containerStatus := range pod.Status.ContainerStatuses {
+				if containerStatus.Name != containerName || containerStatus.State.Running == nil {
+					continue
+				}
+				if !strings.Contains(containerStatus.Image, image) {
+					return fmt.Errorf("container %s of pod %s runs image %s, expected %s", containerName, pod.Name, containerStatus.Image, image)
+				}
+				count++
+			}
+		}
+
+		if count != expectedReplicas {
+			return fmt.Errorf("container %s runs image %s in %d pods, expected %d", containerName, image, count, expectedReplicas)
+		}
+	}
+
+	return nil
+}
+
+// revisionImages returns the container images by container name of the ReplicaSet owned by the deployment that
+// carries the given revision.
+func revisionImages(replicaSets []appv1.ReplicaSet, deploymentName, revision string) (map[string]string, error) {
+	for _, replicaSet := range replicaSets {
+		if replicaSet.Annotations[revisionAnnotation] != revision || !isOwnedByDeployment(replicaSet, deploymentName) {
+			continue
+		}
+
+		images := map[string]string{}
+		for _, container := range replicaSet.Spec.Template.Spec.Containers {
+			images[container.Name] = container.Image
+		}
+		return images, nil
+	}
+
+	return nil, fmt.Errorf("replicaset for revision %s of deployment %s not found", revision, deploymentName)
+}
+
+func isOwnedByDeployment(replicaSet appv1.ReplicaSet, deploymentName string) bool {
+	for _, owner := range replicaSet.OwnerReferences {
+		if owner.Kind == "Deployment" && owner.Name == deploymentName {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkContainerImages returns an error if the deployment's container images differ from the expected images.
+func checkContainerImages(deployment *appv1.Deployment, expectedImages map[string]string) error {
+	containers := deployment.Spec.Template.Spec.Containers
+	if len(containers) != len(expectedImages) {
+		return fmt.Errorf("deployment %s has %d containers, expected %d", deployment.Name, len(containers), len(expectedImages))
+	}
+
+	for _, container := range containers {
+		expected, ok := expectedImages[container.Name]
+		if !ok {
+			return fmt.Errorf("deployment %s has unexpected container %s", deployment.Name, container.Name)
+		}
+		if container.Image != expected {
+			return fmt.Errorf("container %s of deployment %s runs image %s, expected %s", container.Name, deployment.Name, container.Image, expected)
+		}
+	}
+
+	return nil
+}
+
 func verifyDeploymentAgainstRolloutHistory(client *rancher.Client, clusterID, namespaceName string, deploymentName string, expectedRevision string) error {
 	var wranglerContext *wrangler.Context
 	var err error
@@ -195,3 +337,17 @@ func verifyOrchestrationStatus(client *rancher.Client, clusterID, namespaceName
 
 	return nil
 }
+
+// isRolloutComplete returns true if the deployment controller has observed the latest spec and all replicas are
+// updated and available.
+func isRolloutComplete(deployment *appv1.Deployment) bool {
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+
+	return deployment.Status.ObservedGeneration >= deployment.Generation &&
+		deployment.Status.UpdatedReplicas == replicas &&
+		deployment.Status.Replicas == replicas &&
+		deployment.Status.AvailableReplicas == replicas
+}
@@ -22,7 +22,7 @@ func getDownstreamClientset(clients *clients.Clients, c *v1.Cluster) (*kubernete
 		return nil, err
 	}
 	// Register settings so that the provider is set and we can retrieve the internal server URL + CA for the kubeconfig manager below.
-	err = settings.Register(wContext.Mgmt.Setting())
+	err = settings.Register(wContext.Mgmt.Setting(), settings.Options{})
 	if err != nil {
 		return nil, err
 	}